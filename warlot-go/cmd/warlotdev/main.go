@@ -51,6 +51,34 @@ func main() {
 		if err := commands.RunCommit(args); err != nil {
 			fail(err)
 		}
+	case "migrate":
+		if err := commands.RunMigrate(args); err != nil {
+			fail(err)
+		}
+	case "gen":
+		if err := commands.RunGen(args); err != nil {
+			fail(err)
+		}
+	case "schema":
+		if err := commands.RunSchema(args); err != nil {
+			fail(err)
+		}
+	case "export":
+		if err := commands.RunExport(args); err != nil {
+			fail(err)
+		}
+	case "import":
+		if err := commands.RunImport(args); err != nil {
+			fail(err)
+		}
+	case "backup":
+		if err := commands.RunBackup(args); err != nil {
+			fail(err)
+		}
+	case "restore":
+		if err := commands.RunRestore(args); err != nil {
+			fail(err)
+		}
 
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", cmd)
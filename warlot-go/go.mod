@@ -1,3 +1,5 @@
 module github.com/steven3002/warlot-golang-sdk/warlot-go
 
 go 1.22
+
+require github.com/mattn/go-sqlite3 v1.14.33
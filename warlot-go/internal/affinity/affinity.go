@@ -0,0 +1,39 @@
+// Package affinity maps declared SQLite column types to Go types by
+// SQLite's type affinity rules. It is shared by code generation, export
+// and ingest.
+package affinity
+
+import "strings"
+
+// GoType maps a declared SQLite column type to a Go type using SQLite's
+// affinity rules. Nullable columns map to pointers, except []byte and any
+// which already represent NULL as nil. Date and time types map to string
+// because the API returns them as text, and BOOLEAN maps to int64 because
+// SQLite stores and returns booleans as 0 and 1.
+func GoType(declType string, nullable bool) string {
+	t := strings.ToUpper(declType)
+	var g string
+	switch {
+	case strings.Contains(t, "INT"), IsBool(declType):
+		g = "int64"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"),
+		strings.Contains(t, "DATE"), strings.Contains(t, "TIME"), strings.Contains(t, "UUID"), strings.Contains(t, "JSON"):
+		g = "string"
+	case strings.Contains(t, "BLOB"):
+		return "[]byte"
+	case t == "":
+		return "any"
+	default: // REAL, FLOAT, DOUBLE, NUMERIC, DECIMAL
+		g = "float64"
+	}
+	if nullable {
+		return "*" + g
+	}
+	return g
+}
+
+// IsBool reports whether a declared column type names a boolean, which
+// SQLite stores as the integers 0 and 1.
+func IsBool(declType string) bool {
+	return strings.Contains(strings.ToUpper(declType), "BOOL")
+}
@@ -0,0 +1,22 @@
+package affinity
+
+import "testing"
+
+func TestGoType(t *testing.T) {
+	types := map[[2]string]string{
+		{"INTEGER", ""}:       "int64",
+		{"BIGINT", "null"}:    "*int64",
+		{"VARCHAR(20)", ""}:   "string",
+		{"DATETIME", "null"}:  "*string",
+		{"REAL", ""}:          "float64",
+		{"DECIMAL(10,2)", ""}: "float64",
+		{"BOOLEAN", "null"}:   "*int64",
+		{"BLOB", "null"}:      "[]byte",
+		{"", "null"}:          "any",
+	}
+	for in, want := range types {
+		if got := GoType(in[0], in[1] == "null"); got != want {
+			t.Errorf("GoType(%q, %v) = %s, want %s", in[0], in[1] == "null", got, want)
+		}
+	}
+}
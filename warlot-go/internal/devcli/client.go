@@ -5,19 +5,25 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
 )
 
-// NewClient constructs an SDK client using global flags.
+// NewClient constructs an SDK client using global flags. -timeout bounds
+// each request until its response headers arrive; response bodies are
+// read without a deadline so long pages and streams are not cut off.
 func NewClient(g GlobalFlags) *warlot.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.ResponseHeaderTimeout = g.Timeout
 	opts := []warlot.Option{
 		warlot.WithBaseURL(g.BaseURL),
 		warlot.WithHolderID(g.HolderID),
 		warlot.WithProjectName(g.ProjectName),
 		warlot.WithRetries(g.Retries),
 		warlot.WithBackoff(g.BackoffInit, g.BackoffMax),
-		warlot.WithHTTPClient(&http.Client{Timeout: g.Timeout}),
+		warlot.WithHTTPClient(&http.Client{Transport: tr}),
 	}
 	if g.APIKey != "" {
 		opts = append(opts, warlot.WithAPIKey(g.APIKey))
@@ -35,3 +41,10 @@ func NewClient(g GlobalFlags) *warlot.Client {
 func Ctx(g GlobalFlags) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), g.Timeout)
 }
+
+// LongCtx returns a context for commands that may run for longer than
+// -timeout, such as export, import and migrations. It has no deadline and is
+// cancelled by an interrupt or SIGTERM.
+func LongCtx() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
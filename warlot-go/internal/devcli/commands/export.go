@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/devcli"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/export"
+)
+
+// RunExport dumps a table to CSV, JSONL or Parquet. With -out the
+// manifest is written next to the file and printed; without it rows go
+// to stdout.
+func RunExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	projectID := fs.String("project", "", "Project ID")
+	table := fs.String("table", "", "Table to export")
+	format := fs.String("format", "", "csv|jsonl|parquet (default from -out extension, else csv)")
+	out := fs.String("out", "", "Output file (default stdout)")
+	columns := fs.String("columns", "", "Comma-separated columns to export (default all)")
+	key := fs.String("key", "", "Column to page and split by (default single-column primary key)")
+	workers := fs.Int("workers", 1, "Concurrent workers; each writes a part file (requires -out and an integer key)")
+	pageSize := fs.Int("page-size", 1000, "Rows per SELECT when paging by key")
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*projectID, g)
+	devcli.MustNonEmpty(*table, "-table")
+
+	opts := export.Options{
+		Table:    *table,
+		Format:   export.Format(*format),
+		Columns:  splitList(*columns),
+		Key:      *key,
+		Workers:  *workers,
+		PageSize: *pageSize,
+	}
+	if opts.Format == "" {
+		opts.Format = export.FormatFromPath(*out)
+	}
+
+	proj := devcli.NewClient(g).Project(*projectID)
+	ctx, cancel := devcli.LongCtx()
+	defer cancel()
+
+	if *out == "" {
+		if *workers > 1 {
+			return errors.New("-workers needs -out")
+		}
+		n, err := export.ToWriter(ctx, proj, os.Stdout, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %d rows\n", n)
+		return nil
+	}
+	m, err := export.ToFile(ctx, proj, *out, opts)
+	if err != nil {
+		return err
+	}
+	devcli.PrintJSON(m)
+	return nil
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/devcli"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/structgen"
+)
+
+// RunGen dispatches to the structs subcommand.
+func RunGen(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: warlotdev gen <structs> [flags]")
+	}
+	switch args[0] {
+	case "structs":
+		return runGenStructs(args[1:])
+	default:
+		return errors.New("unknown gen subcommand; use structs")
+	}
+}
+
+func runGenStructs(args []string) error {
+	fs := flag.NewFlagSet("gen structs", flag.ContinueOnError)
+	projectID := fs.String("project", "", "Project ID")
+	out := fs.String("out", "", "Output file (default stdout)")
+	pkg := fs.String("pkg", "models", "Package name of the generated file")
+	tables := fs.String("tables", "", "Comma-separated tables to include (default all)")
+	crud := fs.Bool("crud", false, "Also generate typed List/Get/Insert/Update/Upsert/Delete helpers")
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*projectID, g)
+
+	cl := devcli.NewClient(g)
+	ctx, cancel := devcli.Ctx(g)
+	defer cancel()
+
+	names := splitList(*tables)
+	if len(names) == 0 {
+		lt, err := cl.ListTables(ctx, *projectID)
+		if err != nil {
+			return err
+		}
+		for _, t := range lt.Tables {
+			if !internalTable(t) {
+				names = append(names, t)
+			}
+		}
+	}
+
+	var defs []structgen.Table
+	for _, name := range names {
+		schema, err := cl.GetTableSchema(ctx, *projectID, name)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+		t, err := structgen.TableFromSchema(name, schema)
+		if err != nil {
+			return err
+		}
+		defs = append(defs, t)
+	}
+
+	src, err := structgen.Generate(defs, structgen.Options{Package: *pkg, CRUD: *crud})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d tables to %s\n", len(defs), *out)
+	return nil
+}
+
+// internalTable reports tables owned by SQLite or the migrator.
+func internalTable(name string) bool {
+	return strings.HasPrefix(name, "sqlite_") || name == "_migrations" || name == "_migrations_lock"
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
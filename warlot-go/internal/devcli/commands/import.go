@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/devcli"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/ingest"
+)
+
+// RunImport bulk-loads a CSV or JSONL file into a table. Progress is
+// checkpointed next to the file so a failed import can be rerun to
+// resume.
+func RunImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	projectID := fs.String("project", "", "Project ID")
+	table := fs.String("table", "", "Target table")
+	file := fs.String("file", "", "Input file (.csv, .jsonl)")
+	format := fs.String("format", "", "csv|jsonl (default from -file extension)")
+	mapping := fs.String("map", "", "Field to column mapping, e.g. title:name,amount:price (default match by name)")
+	batchRows := fs.Int("batch", 0, "Rows per INSERT (default as many as fit 999 parameters)")
+	workers := fs.Int("workers", 1, "Concurrent batches")
+	rate := fs.Float64("rate", 0, "Max batches per second (0 = unlimited)")
+	checkpoint := fs.String("checkpoint", "", "Checkpoint file (default <file>.checkpoint.json)")
+	noCheckpoint := fs.Bool("no-checkpoint", false, "Do not write a checkpoint")
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*projectID, g)
+	devcli.MustNonEmpty(*table, "-table")
+	devcli.MustNonEmpty(*file, "-file")
+
+	opts := ingest.Options{
+		Table:      *table,
+		Format:     ingest.Format(*format),
+		BatchRows:  *batchRows,
+		Workers:    *workers,
+		Rate:       *rate,
+		Checkpoint: *checkpoint,
+	}
+	if *mapping != "" {
+		opts.Columns = map[string]string{}
+		for _, pair := range splitList(*mapping) {
+			src, col, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("-map entry %q: want field:column", pair)
+			}
+			opts.Columns[strings.TrimSpace(src)] = strings.TrimSpace(col)
+		}
+	}
+	switch {
+	case *noCheckpoint:
+		opts.Checkpoint = ""
+	case opts.Checkpoint == "":
+		opts.Checkpoint = *file + ".checkpoint.json"
+	}
+
+	ctx, cancel := devcli.LongCtx()
+	defer cancel()
+
+	res, err := ingest.FromFile(ctx, devcli.NewClient(g).Project(*projectID), *file, opts)
+	if res != nil {
+		devcli.PrintJSON(res)
+	}
+	if err != nil && opts.Checkpoint != "" {
+		return fmt.Errorf("%w (rerun to resume from %s)", err, opts.Checkpoint)
+	}
+	return err
+}
@@ -0,0 +1,340 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/devcli"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// RunMigrate dispatches to up|down|status|create subcommands.
+func RunMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: warlotdev migrate <up|down|status|create> [flags]")
+	}
+	switch args[0] {
+	case "up":
+		return runMigrateUp(args[1:])
+	case "down":
+		return runMigrateDown(args[1:])
+	case "status":
+		return runMigrateStatus(args[1:])
+	case "create":
+		return runMigrateCreate(args[1:])
+	default:
+		return errors.New("unknown migrate subcommand; use up|down|status|create")
+	}
+}
+
+// migrateFlags are shared by the subcommands that talk to a project.
+type migrateFlags struct {
+	projectID  *string
+	dir        *string
+	asJSON     *bool
+	dryRun     *bool
+	to         *string
+	allowDrift *bool
+}
+
+func bindMigrateFlags(fs *flag.FlagSet, withDryRun bool) migrateFlags {
+	mf := migrateFlags{
+		projectID:  fs.String("project", "", "Project ID"),
+		dir:        fs.String("dir", "migrations", "Migrations directory"),
+		asJSON:     fs.Bool("json", false, "Print JSON instead of a table"),
+		allowDrift: fs.Bool("allow-drift", false, "Warn instead of failing when applied files changed"),
+	}
+	if withDryRun {
+		mf.dryRun = fs.Bool("dry-run", false, "Print the SQL that would run without executing it")
+		mf.to = fs.String("to", "", "Target version")
+	}
+	return mf
+}
+
+func runMigrateUp(args []string) error {
+	fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	mf := bindMigrateFlags(fs, true)
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*mf.projectID, g)
+
+	cl := devcli.NewClient(g)
+	ctx, cancel := devcli.LongCtx()
+	defer cancel()
+
+	proj := cl.Project(*mf.projectID)
+	fsys := os.DirFS(*mf.dir)
+	m := warlot.Migrator{AllowDrift: *mf.allowDrift}
+
+	if *mf.dryRun {
+		apply, rollback, err := m.Plan(ctx, proj, fsys, ".", *mf.to)
+		if err != nil {
+			return err
+		}
+		return printPlan(*mf.dir, apply, rollback)
+	}
+
+	var applied, rolledBack []string
+	var err error
+	if *mf.to != "" {
+		applied, rolledBack, err = m.To(ctx, proj, fsys, ".", *mf.to)
+	} else {
+		applied, err = m.Up(ctx, proj, fsys, ".")
+	}
+	printMigrateResult(*mf.asJSON, applied, rolledBack)
+	return err
+}
+
+func runMigrateDown(args []string) error {
+	fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+	mf := bindMigrateFlags(fs, true)
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*mf.projectID, g)
+
+	cl := devcli.NewClient(g)
+	ctx, cancel := devcli.LongCtx()
+	defer cancel()
+
+	proj := cl.Project(*mf.projectID)
+	fsys := os.DirFS(*mf.dir)
+	m := warlot.Migrator{AllowDrift: *mf.allowDrift}
+
+	if *mf.dryRun {
+		if *mf.to != "" {
+			apply, rollback, err := m.Plan(ctx, proj, fsys, ".", *mf.to)
+			if err != nil {
+				return err
+			}
+			return printPlan(*mf.dir, apply, rollback)
+		}
+		st, err := m.Status(ctx, proj, fsys, ".")
+		if err != nil {
+			return err
+		}
+		if n := len(st.Applied); n > 0 {
+			return printPlan(*mf.dir, nil, st.Applied[n-1:])
+		}
+		return printPlan(*mf.dir, nil, nil)
+	}
+
+	var rolledBack []string
+	var err error
+	if *mf.to != "" {
+		_, rolledBack, err = m.To(ctx, proj, fsys, ".", *mf.to)
+	} else {
+		rolledBack, err = m.Down(ctx, proj, fsys, ".")
+	}
+	printMigrateResult(*mf.asJSON, nil, rolledBack)
+	return err
+}
+
+func runMigrateStatus(args []string) error {
+	fs := flag.NewFlagSet("migrate status", flag.ContinueOnError)
+	mf := bindMigrateFlags(fs, false)
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*mf.projectID, g)
+
+	cl := devcli.NewClient(g)
+	ctx, cancel := devcli.LongCtx()
+	defer cancel()
+
+	out, err := warlot.Migrate.Status(ctx, cl.Project(*mf.projectID), os.DirFS(*mf.dir), ".")
+	if err != nil {
+		return err
+	}
+	if *mf.asJSON {
+		devcli.PrintJSON(out)
+		return nil
+	}
+
+	drifted := map[string]bool{}
+	for _, mi := range out.Drifted {
+		drifted[mi.ID] = true
+	}
+	var rows [][]string
+	for _, mi := range out.Applied {
+		state := "applied"
+		switch {
+		case drifted[mi.ID]:
+			state = "drifted"
+		case mi.Missing:
+			state = "missing"
+		}
+		rows = append(rows, []string{mi.ID, state, mi.AppliedAt, yesNo(mi.Reversible)})
+	}
+	for _, mi := range out.Pending {
+		rows = append(rows, []string{mi.ID, "pending", "", yesNo(mi.Reversible)})
+	}
+	devcli.PrintTable([]string{"MIGRATION", "STATE", "APPLIED AT", "REVERSIBLE"}, rows)
+	return nil
+}
+
+func runMigrateCreate(args []string) error {
+	fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := fs.String("dir", "migrations", "Migrations directory")
+	forwardOnly := fs.Bool("forward-only", false, "Create a single .sql file without a down script")
+
+	// Accept the name before or after the flags.
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if name == "" && fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	name = sanitizeMigrationName(name)
+	if name == "" {
+		return errors.New("usage: warlotdev migrate create <name> [-dir migrations] [-forward-only]")
+	}
+
+	base := migrationBase(name)
+	files := [][2]string{
+		{base + ".up.sql", "-- " + base + ": forward migration.\n"},
+		{base + ".down.sql", "-- " + base + ": reverts the forward migration.\n"},
+	}
+	if *forwardOnly {
+		files = [][2]string{{base + ".sql", "-- " + base + ": forward-only migration.\n"}}
+	}
+	return createMigrationFiles(*dir, files)
+}
+
+// migrationBase returns a timestamped migration file base name.
+func migrationBase(name string) string {
+	return time.Now().UTC().Format("20060102150405") + "_" + name
+}
+
+// createMigrationFiles writes {name, body} pairs into dir, refusing to
+// overwrite existing files, and prints each path.
+func createMigrationFiles(dir string, files [][2]string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		path, body := filepath.Join(dir, file[0]), file[1]
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		_, werr := f.WriteString(body)
+		if cerr := f.Close(); werr == nil {
+			werr = cerr
+		}
+		if werr != nil {
+			return werr
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func requireProjectFlags(projectID string, g devcli.GlobalFlags) {
+	devcli.MustNonEmpty(projectID, "-project")
+	devcli.MustNonEmpty(g.HolderID, "-holder")
+	devcli.MustNonEmpty(g.ProjectName, "-pname")
+	devcli.MustNonEmpty(g.APIKey, "-apikey")
+}
+
+func printMigrateResult(asJSON bool, applied, rolledBack []string) {
+	if asJSON {
+		devcli.PrintJSON(map[string][]string{"applied": nonNil(applied), "rolled_back": nonNil(rolledBack)})
+		return
+	}
+	var rows [][]string
+	for _, id := range rolledBack {
+		rows = append(rows, []string{id, "rolled back"})
+	}
+	for _, id := range applied {
+		rows = append(rows, []string{id, "applied"})
+	}
+	if len(rows) == 0 {
+		fmt.Println("no migrations to run")
+		return
+	}
+	devcli.PrintTable([]string{"MIGRATION", "RESULT"}, rows)
+}
+
+// printPlan prints the SQL a run would execute: rollbacks newest first
+// with the down script the ledger would use, then up files from disk.
+func printPlan(dir string, apply, rollback []warlot.MigrationInfo) error {
+	if len(apply) == 0 && len(rollback) == 0 {
+		fmt.Println("no migrations to run")
+		return nil
+	}
+	for _, mi := range rollback {
+		switch {
+		case mi.DownSQL != "":
+			fmt.Printf("-- down %s\n%s\n\n", mi.ID, strings.TrimRight(mi.DownSQL, "\n"))
+		case mi.Reversible:
+			fmt.Printf("-- down %s: Go migration\n\n", mi.ID)
+		default:
+			fmt.Printf("-- down %s: irreversible, rollback will fail\n\n", mi.ID)
+		}
+	}
+	for _, mi := range apply {
+		if mi.FileSum == "" {
+			fmt.Printf("-- up %s: Go migration\n\n", mi.ID)
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, mi.ID))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("-- up %s\n%s\n\n", mi.ID, strings.TrimRight(string(b), "\n"))
+	}
+	return nil
+}
+
+func sanitizeMigrationName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/devcli"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/schemadiff"
+)
+
+// RunSchema dispatches to the diff subcommand.
+func RunSchema(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: warlotdev schema <diff> [flags]")
+	}
+	switch args[0] {
+	case "diff":
+		return runSchemaDiff(args[1:])
+	default:
+		return errors.New("unknown schema subcommand; use diff")
+	}
+}
+
+// runSchemaDiff compares -project (the schema to migrate) with either a
+// target project or a DDL file (the desired schema).
+func runSchemaDiff(args []string) error {
+	fs := flag.NewFlagSet("schema diff", flag.ContinueOnError)
+	projectID := fs.String("project", "", "Project ID whose schema is migrated")
+	target := fs.String("target", "", "Target project ID holding the desired schema")
+	targetKey := fs.String("target-apikey", "", "API key for -target (default -apikey)")
+	targetName := fs.String("target-pname", "", "Project name for -target (default -pname)")
+	file := fs.String("file", "", "DDL file holding the desired schema")
+	asJSON := fs.Bool("json", false, "Print the diff as JSON")
+	migration := fs.String("migration", "", "Write the diff as a migration pair with this name")
+	dir := fs.String("dir", "migrations", "Migrations directory for -migration")
+	g := devcli.ParseGlobalFlagsArgs(fs, args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			devcli.Panicf("missing required flag: %v", r)
+		}
+	}()
+
+	requireProjectFlags(*projectID, g)
+	if (*target == "") == (*file == "") {
+		return errors.New("schema diff needs exactly one of -target or -file")
+	}
+
+	ctx, cancel := devcli.Ctx(g)
+	defer cancel()
+
+	from, err := schemadiff.Load(ctx, devcli.NewClient(g).Project(*projectID))
+	if err != nil {
+		return fmt.Errorf("load %s: %w", *projectID, err)
+	}
+
+	var to schemadiff.Schema
+	if *file != "" {
+		to, err = schemadiff.LoadFile(*file)
+	} else {
+		tg := g
+		if *targetKey != "" {
+			tg.APIKey = *targetKey
+		}
+		if *targetName != "" {
+			tg.ProjectName = *targetName
+		}
+		to, err = schemadiff.Load(ctx, devcli.NewClient(tg).Project(*target))
+		if err != nil {
+			err = fmt.Errorf("load %s: %w", *target, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	d := schemadiff.Compare(from, to)
+	switch {
+	case *asJSON:
+		devcli.PrintJSON(d)
+	case d.Empty():
+		fmt.Println("schemas are identical")
+	default:
+		fmt.Print(d)
+	}
+
+	if *migration == "" || d.Empty() {
+		return nil
+	}
+	name := sanitizeMigrationName(*migration)
+	if name == "" {
+		return errors.New("-migration needs a name")
+	}
+	up, down := d.Migration()
+	base := migrationBase(name)
+	return createMigrationFiles(*dir, [][2]string{
+		{base + ".up.sql", "-- " + base + ": generated by warlotdev schema diff.\n" + up},
+		{base + ".down.sql", "-- " + base + ": reverts the schema diff; dropped data is not restored.\n" + down},
+	})
+}
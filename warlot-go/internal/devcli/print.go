@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
 )
 
 // PrintJSON prints a value as pretty-printed JSON.
@@ -12,6 +14,16 @@ func PrintJSON(v any) {
 	fmt.Println(string(b))
 }
 
+// PrintTable prints rows as aligned columns under a header line.
+func PrintTable(header []string, rows [][]string) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, r := range rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	tw.Flush()
+}
+
 // PrintGlobalUsage renders the top-level usage text.
 func PrintGlobalUsage(bin string) {
 	// Environment defaults echoed inline for transparency.
@@ -43,6 +55,16 @@ COMMANDS:
   tables count  	-project <id>
   status      		-project <id>
   commit      		-project <id>
+  migrate up    	-project <id> [-dir migrations -to <version> -dry-run -json]
+  migrate down  	-project <id> [-dir migrations -to <version> -dry-run -json]
+  migrate status	-project <id> [-dir migrations -json]
+  migrate create	<name> [-dir migrations -forward-only]
+  gen structs   	-project <id> [-out models.go -pkg models -tables a,b -crud]
+  schema diff   	-project <id> (-target <id> | -file schema.sql) [-target-apikey k -migration <name> -dir migrations -json]
+  export        	-project <id> -table t [-format csv|jsonl|parquet -out file -columns a,b -key id -workers 4 -page-size 1000]
+  import        	-project <id> -table t -file data.csv [-format csv|jsonl -map src:col -batch 200 -workers 4 -rate 10 -checkpoint f -no-checkpoint]
+  backup        	-project <id> -out dir [-tables a,b -workers 4]
+  restore       	-project <id> -in dir [-workers 4 -rate 20] | -in dir -verify
 
 EXAMPLES:
   ` + bin + ` resolve -holder 0xH -pname myproj
@@ -51,6 +73,14 @@ EXAMPLES:
   ` + bin + ` sql -project <id> -q 'SELECT * FROM products ORDER BY id DESC LIMIT 5'
   ` + bin + ` sql -project <id> -q 'INSERT INTO t (name) VALUES (?)' -params '["alice"]' -idempotency one
   ` + bin + ` tables browse -project <id> -table products -limit 10
+  ` + bin + ` migrate create add_users -dir migrations
+  ` + bin + ` migrate up -project <id> -dir migrations -dry-run
+  ` + bin + ` gen structs -project <id> -out internal/models/models.go -pkg models -crud
+  ` + bin + ` schema diff -project <prod> -target <staging> -target-apikey $STAGING_KEY -migration promote
+  ` + bin + ` export -project <id> -table orders -out orders.parquet -workers 4
+  ` + bin + ` import -project <id> -table orders -file orders.csv -workers 4 -rate 20
+  ` + bin + ` backup -project <prod> -out backups/2024-05-01
+  ` + bin + ` restore -project <fresh> -in backups/2024-05-01 -workers 4
 `)
 }
 
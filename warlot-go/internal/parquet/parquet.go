@@ -0,0 +1,355 @@
+// Package parquet writes flat Apache Parquet files: optional columns of
+// boolean, int64, double or UTF-8 string type, PLAIN encoded and
+// uncompressed. Rows are buffered into row groups and flushed as each
+// group fills, so memory use is bounded by the row group size.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Type is a column's physical type.
+type Type int
+
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	String
+)
+
+func (t Type) String() string {
+	switch t {
+	case Boolean:
+		return "BOOLEAN"
+	case Int64:
+		return "INT64"
+	case Double:
+		return "DOUBLE"
+	case String:
+		return "STRING"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Parquet physical types and encodings used here.
+const (
+	physBoolean   = 0
+	physInt64     = 2
+	physDouble    = 5
+	physByteArray = 6
+
+	encPlain = 0
+	encRLE   = 3
+
+	convertedUTF8 = 0
+	repOptional   = 1
+	pageData      = 0
+	codecNone     = 0
+)
+
+func (t Type) physical() int32 {
+	switch t {
+	case Boolean:
+		return physBoolean
+	case Int64:
+		return physInt64
+	case Double:
+		return physDouble
+	}
+	return physByteArray
+}
+
+// Column describes one column of the file.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// DefaultRowGroupSize is the number of rows buffered per row group.
+const DefaultRowGroupSize = 64 * 1024
+
+var magic = []byte("PAR1")
+
+// Writer writes rows to a Parquet file. Close must be called to write the
+// footer.
+type Writer struct {
+	// RowGroupSize is the number of rows per row group; it may be changed
+	// before the first Write.
+	RowGroupSize int
+	// CreatedBy is recorded in the file metadata.
+	CreatedBy string
+
+	w      io.Writer
+	cols   []Column
+	off    int64
+	chunks []*chunk
+	rows   int64 // rows in the current group
+	total  int64
+	groups []rowGroup
+	err    error
+	closed bool
+}
+
+// chunk buffers one column of the current row group.
+type chunk struct {
+	defs   []bool // true when the value is present
+	values bytes.Buffer
+	bits   uint8 // pending boolean bits
+	nbits  int
+}
+
+type rowGroup struct {
+	rows    int64
+	size    int64
+	columns []columnChunk
+}
+
+type columnChunk struct {
+	offset int64 // data page offset
+	values int64
+	size   int64
+}
+
+// NewWriter returns a Writer for cols.
+func NewWriter(w io.Writer, cols []Column) *Writer {
+	pw := &Writer{RowGroupSize: DefaultRowGroupSize, CreatedBy: "warlot-go", w: w, cols: cols}
+	pw.chunks = make([]*chunk, len(cols))
+	for i := range pw.chunks {
+		pw.chunks[i] = &chunk{}
+	}
+	return pw
+}
+
+// Write appends a row. Values must be nil or match the column type: bool,
+// int64, float64, or string/[]byte. A value of the wrong type fails the
+// writer.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return errors.New("parquet: write after close")
+	}
+	if len(row) != len(w.cols) {
+		return fmt.Errorf("parquet: row has %d values for %d columns", len(row), len(w.cols))
+	}
+	if w.off == 0 {
+		if w.err = w.write(magic); w.err != nil {
+			return w.err
+		}
+	}
+	for i, v := range row {
+		// Earlier columns of this row are already buffered, so the
+		// writer cannot continue after a bad value.
+		if w.err = w.chunks[i].add(w.cols[i], v); w.err != nil {
+			return w.err
+		}
+	}
+	w.rows++
+	if w.rows >= int64(w.RowGroupSize) {
+		w.err = w.flush()
+	}
+	return w.err
+}
+
+func (c *chunk) add(col Column, v any) error {
+	if v == nil {
+		c.defs = append(c.defs, false)
+		return nil
+	}
+	var b [8]byte
+	switch col.Type {
+	case Boolean:
+		x, ok := v.(bool)
+		if !ok {
+			return typeErr(col, v)
+		}
+		if x {
+			c.bits |= 1 << c.nbits
+		}
+		if c.nbits++; c.nbits == 8 {
+			c.values.WriteByte(c.bits)
+			c.bits, c.nbits = 0, 0
+		}
+	case Int64:
+		x, ok := v.(int64)
+		if !ok {
+			return typeErr(col, v)
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(x))
+		c.values.Write(b[:])
+	case Double:
+		x, ok := v.(float64)
+		if !ok {
+			return typeErr(col, v)
+		}
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(x))
+		c.values.Write(b[:])
+	case String:
+		var s []byte
+		switch x := v.(type) {
+		case string:
+			s = []byte(x)
+		case []byte:
+			s = x
+		default:
+			return typeErr(col, v)
+		}
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+		c.values.Write(b[:4])
+		c.values.Write(s)
+	}
+	c.defs = append(c.defs, true)
+	return nil
+}
+
+func typeErr(col Column, v any) error {
+	return fmt.Errorf("parquet: column %s (%s): unsupported value %T", col.Name, col.Type, v)
+}
+
+// flush writes the buffered row group, one data page per column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	g := rowGroup{rows: w.rows}
+	for i, c := range w.chunks {
+		if c.nbits > 0 {
+			c.values.WriteByte(c.bits)
+		}
+		page := append(encodeLevels(c.defs), c.values.Bytes()...)
+		var h compact
+		h.begin()
+		h.fieldI32(1, pageData)
+		h.fieldI32(2, int32(len(page)))
+		h.fieldI32(3, int32(len(page)))
+		h.fieldStruct(5, func() {
+			h.fieldI32(1, int32(len(c.defs)))
+			h.fieldI32(2, encPlain)
+			h.fieldI32(3, encRLE)
+			h.fieldI32(4, encRLE)
+		})
+		h.end()
+
+		cc := columnChunk{offset: w.off, values: int64(len(c.defs)), size: int64(h.buf.Len() + len(page))}
+		if err := w.write(h.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		g.columns = append(g.columns, cc)
+		g.size += cc.size
+		w.chunks[i] = &chunk{}
+	}
+	w.groups = append(w.groups, g)
+	w.total += w.rows
+	w.rows = 0
+	return nil
+}
+
+// encodeLevels encodes definition levels (bit width 1) as a single
+// bit-packed run of the RLE/bit-packing hybrid, prefixed by its length.
+func encodeLevels(defs []bool) []byte {
+	groups := (len(defs) + 7) / 8
+	var body []byte
+	body = binary.AppendUvarint(body, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, d := range defs {
+		if d {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	body = append(body, packed...)
+	out := binary.LittleEndian.AppendUint32(nil, uint32(len(body)))
+	return append(out, body...)
+}
+
+// Close flushes buffered rows and writes the footer. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if w.off == 0 {
+		if w.err = w.write(magic); w.err != nil {
+			return w.err
+		}
+	}
+	if w.err = w.flush(); w.err != nil {
+		return w.err
+	}
+	meta := w.footer()
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(meta)))
+	for _, b := range [][]byte{meta, n[:], magic} {
+		if w.err = w.write(b); w.err != nil {
+			return w.err
+		}
+	}
+	return nil
+}
+
+// Rows returns the number of rows written so far.
+func (w *Writer) Rows() int64 { return w.total + w.rows }
+
+func (w *Writer) footer() []byte {
+	var c compact
+	c.begin()
+	c.fieldI32(1, 1)
+	c.fieldStructList(2, len(w.cols)+1, func(i int) {
+		if i == 0 {
+			c.fieldStr(4, "schema")
+			c.fieldI32(5, int32(len(w.cols)))
+			return
+		}
+		col := w.cols[i-1]
+		c.fieldI32(1, col.Type.physical())
+		c.fieldI32(3, repOptional)
+		c.fieldStr(4, col.Name)
+		if col.Type == String {
+			c.fieldI32(6, convertedUTF8)
+		}
+	})
+	c.fieldI64(3, w.total)
+	c.fieldStructList(4, len(w.groups), func(i int) {
+		g := w.groups[i]
+		c.fieldStructList(1, len(g.columns), func(j int) {
+			cc := g.columns[j]
+			c.fieldI64(2, cc.offset)
+			c.fieldStruct(3, func() {
+				c.fieldI32(1, w.cols[j].Type.physical())
+				c.fieldI32List(2, encPlain, encRLE)
+				c.fieldStrList(3, w.cols[j].Name)
+				c.fieldI32(4, codecNone)
+				c.fieldI64(5, cc.values)
+				c.fieldI64(6, cc.size)
+				c.fieldI64(7, cc.size)
+				c.fieldI64(9, cc.offset)
+			})
+		})
+		c.fieldI64(2, g.size)
+		c.fieldI64(3, g.rows)
+	})
+	if w.CreatedBy != "" {
+		c.fieldStr(6, w.CreatedBy)
+	}
+	c.end()
+	return c.buf.Bytes()
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.off += int64(n)
+	return err
+}
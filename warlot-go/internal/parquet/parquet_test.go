@@ -0,0 +1,197 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// reader decodes just enough Thrift compact protocol to check the files
+// written by Writer. Structs decode to field id → value.
+type reader struct {
+	b []byte
+	i int
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.i:])
+	r.i += n
+	return v
+}
+
+func (r *reader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *reader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		r.i++
+		return int64(int8(r.b[r.i-1]))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		r.i += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.i-8:]))
+	case 8:
+		n := int(r.uvarint())
+		r.i += n
+		return string(r.b[r.i-n : r.i])
+	case 9, 10:
+		h := r.b[r.i]
+		r.i++
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		out := make([]any, n)
+		for k := range out {
+			out[k] = r.value(h & 0x0F)
+		}
+		return out
+	case 12:
+		return r.structure()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+func (r *reader) structure() map[int16]any {
+	out := map[int16]any{}
+	var last int16
+	for {
+		h := r.b[r.i]
+		r.i++
+		if h == 0 {
+			return out
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		out[id] = r.value(h & 0x0F)
+		last = id
+	}
+}
+
+// readFile decodes every column of a file written by Writer.
+func readFile(t *testing.T, data []byte) (map[int16]any, [][]any) {
+	t.Helper()
+	if !bytes.HasPrefix(data, magic) || !bytes.HasSuffix(data, magic) {
+		t.Fatalf("missing magic")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := (&reader{b: data[len(data)-8-n : len(data)-8]}).structure()
+
+	schema := meta[2].([]any)[1:]
+	cols := make([][]any, len(schema))
+	for _, g := range meta[4].([]any) {
+		for j, cc := range g.(map[int16]any)[1].([]any) {
+			md := cc.(map[int16]any)[3].(map[int16]any)
+			r := &reader{b: data, i: int(md[9].(int64))}
+			hdr := r.structure()
+			dp := hdr[5].(map[int16]any)
+			count := int(dp[1].(int64))
+			page := data[r.i : r.i+int(hdr[3].(int64))]
+
+			lv := &reader{b: page[4:]}
+			run := lv.uvarint()
+			if run&1 != 1 {
+				t.Fatalf("expected bit-packed levels, header %d", run)
+			}
+			defs := page[4+lv.i:]
+			vals := page[4+int(binary.LittleEndian.Uint32(page)):]
+			bit := 0
+			for k := 0; k < count; k++ {
+				if defs[k/8]&(1<<(k%8)) == 0 {
+					cols[j] = append(cols[j], nil)
+					continue
+				}
+				var v any
+				switch md[1].(int64) {
+				case physBoolean:
+					v = vals[bit/8]&(1<<(bit%8)) != 0
+					bit++
+				case physInt64:
+					v, vals = int64(binary.LittleEndian.Uint64(vals)), vals[8:]
+				case physDouble:
+					v, vals = math.Float64frombits(binary.LittleEndian.Uint64(vals)), vals[8:]
+				case physByteArray:
+					l := int(binary.LittleEndian.Uint32(vals))
+					v, vals = string(vals[4:4+l]), vals[4+l:]
+				}
+				cols[j] = append(cols[j], v)
+			}
+		}
+	}
+	return meta, cols
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	schema := []Column{{"id", Int64}, {"name", String}, {"price", Double}, {"active", Boolean}}
+	var buf bytes.Buffer
+	w := NewWriter(&buf, schema)
+	w.RowGroupSize = 7
+
+	want := make([][]any, len(schema))
+	for i := 0; i < 20; i++ {
+		row := []any{int64(i), fmt.Sprintf("n%d", i), float64(i) / 4, i%3 == 0}
+		if i%5 == 4 {
+			row[1], row[2], row[3] = nil, nil, nil
+		}
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+		for j, v := range row {
+			want[j] = append(want[j], v)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Rows() != 20 {
+		t.Fatalf("Rows = %d", w.Rows())
+	}
+
+	meta, got := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 20 || len(meta[4].([]any)) != 3 {
+		t.Fatalf("num_rows=%v row_groups=%d", meta[3], len(meta[4].([]any)))
+	}
+	for j, el := range meta[2].([]any)[1:] {
+		if name := el.(map[int16]any)[4]; name != schema[j].Name {
+			t.Fatalf("schema[%d] = %v", j, name)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("columns:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, []Column{{"id", Int64}})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	meta, cols := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 0 || cols[0] != nil {
+		t.Fatalf("meta=%v cols=%v", meta, cols)
+	}
+}
+
+func TestWriterTypeMismatch(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, []Column{{"id", Int64}})
+	if err := w.Write([]any{"x"}); err == nil {
+		t.Fatal("expected type error")
+	}
+	if err := w.Write([]any{int64(1), int64(2)}); err == nil {
+		t.Fatal("expected column count error")
+	}
+}
@@ -0,0 +1,100 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type codes.
+const (
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+// compact writes Thrift compact protocol structs. Only the parts used by
+// Parquet metadata are implemented.
+type compact struct {
+	buf  bytes.Buffer
+	last []int16 // last field id per open struct
+}
+
+func (c *compact) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	c.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func zigzag(v int64) uint64 { return uint64((v << 1) ^ (v >> 63)) }
+
+func (c *compact) field(id int16, typ byte) {
+	top := &c.last[len(c.last)-1]
+	if d := id - *top; d > 0 && d <= 15 {
+		c.buf.WriteByte(byte(d)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(zigzag(int64(id)))
+	}
+	*top = id
+}
+
+func (c *compact) begin()      { c.last = append(c.last, 0) }
+func (c *compact) end()        { c.buf.WriteByte(0); c.last = c.last[:len(c.last)-1] }
+func (c *compact) i32(v int32) { c.varint(zigzag(int64(v))) }
+func (c *compact) i64(v int64) { c.varint(zigzag(v)) }
+
+func (c *compact) str(s string) {
+	c.varint(uint64(len(s)))
+	c.buf.WriteString(s)
+}
+
+func (c *compact) fieldI32(id int16, v int32) { c.field(id, ctI32); c.i32(v) }
+func (c *compact) fieldI64(id int16, v int64) { c.field(id, ctI64); c.i64(v) }
+func (c *compact) fieldStr(id int16, s string) {
+	c.field(id, ctBinary)
+	c.str(s)
+}
+
+// fieldStruct writes a nested struct whose fields are written by body.
+func (c *compact) fieldStruct(id int16, body func()) {
+	c.field(id, ctStruct)
+	c.begin()
+	body()
+	c.end()
+}
+
+func (c *compact) listHeader(id int16, elem byte, n int) {
+	c.field(id, ctList)
+	if n < 15 {
+		c.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		c.buf.WriteByte(0xF0 | elem)
+		c.varint(uint64(n))
+	}
+}
+
+// fieldStructList writes a list of n structs; body writes the fields of
+// element i.
+func (c *compact) fieldStructList(id int16, n int, body func(i int)) {
+	c.listHeader(id, ctStruct, n)
+	for i := 0; i < n; i++ {
+		c.begin()
+		body(i)
+		c.end()
+	}
+}
+
+func (c *compact) fieldI32List(id int16, vs ...int32) {
+	c.listHeader(id, ctI32, len(vs))
+	for _, v := range vs {
+		c.i32(v)
+	}
+}
+
+func (c *compact) fieldStrList(id int16, vs ...string) {
+	c.listHeader(id, ctBinary, len(vs))
+	for _, v := range vs {
+		c.str(v)
+	}
+}
@@ -0,0 +1,167 @@
+package sqlparse
+
+// Statement is a parsed SQL statement.
+type Statement interface{ stmt() }
+
+// Expr is a parsed SQL expression.
+type Expr interface{ expr() }
+
+// CreateTable is CREATE TABLE. Raw holds the statement's source text.
+type CreateTable struct {
+	Name        string
+	IfNotExists bool
+	Columns     []ColumnDef
+	PrimaryKey  []string   // table-level PRIMARY KEY (...)
+	Unique      [][]string // table-level UNIQUE (...)
+	Checks      []Expr
+	ForeignKeys []ForeignKey
+	Raw         string
+}
+
+// ColumnDef is a column definition inside CREATE TABLE or ALTER TABLE.
+type ColumnDef struct {
+	Name          string
+	Type          string // declared type as written, e.g. "VARCHAR(20)"
+	NotNull       bool
+	PrimaryKey    bool
+	Desc          bool // PRIMARY KEY DESC
+	AutoIncrement bool
+	Unique        bool
+	Default       Expr
+	DefaultText   string // source text of the DEFAULT value
+	Check         Expr
+	References    *ForeignKey
+	Collate       string
+	Raw           string
+}
+
+// ForeignKey is a REFERENCES clause. Columns is empty for column-level
+// references.
+type ForeignKey struct {
+	Columns    []string
+	Table      string
+	RefColumns []string
+}
+
+// DropTable is DROP TABLE.
+type DropTable struct {
+	Name     string
+	IfExists bool
+}
+
+// AlterTable is ALTER TABLE with exactly one of its actions set.
+type AlterTable struct {
+	Name         string
+	AddColumn    *ColumnDef
+	DropColumn   string
+	RenameTo     string
+	RenameColumn [2]string // old, new
+}
+
+// CreateIndex is CREATE [UNIQUE] INDEX.
+type CreateIndex struct {
+	Name        string
+	Table       string
+	Columns     []string
+	Unique      bool
+	IfNotExists bool
+	Where       Expr
+	Raw         string
+}
+
+// DropIndex is DROP INDEX.
+type DropIndex struct {
+	Name     string
+	IfExists bool
+}
+
+func (*CreateTable) stmt() {}
+func (*DropTable) stmt()   {}
+func (*AlterTable) stmt()  {}
+func (*CreateIndex) stmt() {}
+func (*DropIndex) stmt()   {}
+
+// Literal is a constant: nil, int64, float64, string, []byte or bool.
+type Literal struct{ Value any }
+
+// Param is a positional parameter. Index is zero-based across the whole
+// script passed to ParseScript.
+type Param struct{ Index int }
+
+// ColumnRef names a column, optionally qualified by table or alias.
+type ColumnRef struct{ Table, Name string }
+
+// Unary is a prefix operator: "-", "+", "~" or "NOT".
+type Unary struct {
+	Op string
+	X  Expr
+}
+
+// Binary is an infix operator. Op is upper-case for keywords ("AND", "OR",
+// "IS", "IS NOT") and one of "=", "!=", "<", "<=", ">", ">=", "+", "-",
+// "*", "/", "%", "||", "&", "|", "<<", ">>" otherwise.
+type Binary struct {
+	Op   string
+	L, R Expr
+}
+
+// Like is [NOT] LIKE or [NOT] GLOB.
+type Like struct {
+	X, Pattern Expr
+	Escape     Expr
+	Not        bool
+	Glob       bool
+}
+
+// In is [NOT] IN with a list.
+type In struct {
+	X    Expr
+	List []Expr
+	Not  bool
+}
+
+// Between is [NOT] BETWEEN.
+type Between struct {
+	X, Lo, Hi Expr
+	Not       bool
+}
+
+// Func is a function call. Star is set for COUNT(*).
+type Func struct {
+	Name     string // upper-case
+	Args     []Expr
+	Star     bool
+	Distinct bool
+}
+
+// Tuple is a row value such as (a, b).
+type Tuple struct{ Items []Expr }
+
+// Cast is CAST(x AS type).
+type Cast struct {
+	X    Expr
+	Type string
+}
+
+// Case is CASE [operand] WHEN ... THEN ... [ELSE ...] END.
+type Case struct {
+	Operand Expr
+	Whens   []When
+	Else    Expr
+}
+
+// When is one WHEN/THEN arm of a CASE.
+type When struct{ Cond, Result Expr }
+
+func (*Literal) expr()   {}
+func (*Param) expr()     {}
+func (*ColumnRef) expr() {}
+func (*Unary) expr()     {}
+func (*Binary) expr()    {}
+func (*Like) expr()      {}
+func (*In) expr()        {}
+func (*Between) expr()   {}
+func (*Func) expr()      {}
+func (*Tuple) expr()     {}
+func (*Cast) expr()      {}
+func (*Case) expr()      {}
@@ -0,0 +1,273 @@
+// Package sqlparse is a small parser for the SQLite dialect spoken by the
+// Warlot SQL API. It splits scripts into statements, counts their
+// parameters, and parses the DDL the SDK's tools inspect (CREATE TABLE,
+// CREATE INDEX, ALTER TABLE and DROP); it is not a general-purpose SQL
+// parser.
+package sqlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenKind classifies a lexical token.
+type TokenKind int
+
+const (
+	TokEOF TokenKind = iota
+	TokIdent
+	TokQuotedIdent
+	TokNumber
+	TokString
+	TokBlob
+	TokParam
+	TokOp
+)
+
+// Token is a lexical token. Pos and End are byte offsets into the input.
+type Token struct {
+	Kind TokenKind
+	Text string // identifier/string contents with quotes removed, or the raw text
+	Pos  int
+	End  int
+}
+
+// Error is a syntax error with the byte offset where it was detected.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sql syntax error at offset %d: %s", e.Pos, e.Msg)
+}
+
+// Tokenize splits src into tokens, dropping whitespace and comments. The
+// final token is always TokEOF.
+func Tokenize(src string) ([]Token, error) {
+	var toks []Token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, &Error{i, "unterminated comment"}
+			}
+			i += end + 4
+		case c == '\'':
+			s, n, err := scanQuoted(src, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, Token{TokString, s, i, i + n})
+			i += n
+		case c == '"' || c == '`':
+			s, n, err := scanQuoted(src, i, c)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, Token{TokQuotedIdent, s, i, i + n})
+			i += n
+		case c == '[':
+			end := strings.IndexByte(src[i:], ']')
+			if end < 0 {
+				return nil, &Error{i, "unterminated identifier"}
+			}
+			toks = append(toks, Token{TokQuotedIdent, src[i+1 : i+end], i, i + end + 1})
+			i += end + 1
+		case (c == 'x' || c == 'X') && i+1 < len(src) && src[i+1] == '\'':
+			s, n, err := scanQuoted(src, i+1, '\'')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, Token{TokBlob, s, i, i + 1 + n})
+			i += 1 + n
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			n := scanNumber(src[i:])
+			toks = append(toks, Token{TokNumber, src[i : i+n], i, i + n})
+			i += n
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			toks = append(toks, Token{TokIdent, src[i:j], i, j})
+			i = j
+		case c == '?':
+			j := i + 1
+			for j < len(src) && isDigit(src[j]) {
+				j++
+			}
+			toks = append(toks, Token{TokParam, src[i:j], i, j})
+			i = j
+		default:
+			n := scanOp(src[i:])
+			if n == 0 {
+				return nil, &Error{i, fmt.Sprintf("unexpected character %q", c)}
+			}
+			toks = append(toks, Token{TokOp, src[i : i+n], i, i + n})
+			i += n
+		}
+	}
+	toks = append(toks, Token{Kind: TokEOF, Pos: len(src), End: len(src)})
+	return toks, nil
+}
+
+// Split returns the non-empty statements of a script separated by
+// top-level semicolons, with surrounding whitespace trimmed. Semicolons
+// inside a CREATE TRIGGER body, between its BEGIN and END, do not split.
+func Split(src string) ([]string, error) {
+	toks, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	start, first := -1, 0
+	depth := 0 // open BEGIN/CASE blocks inside a trigger body
+	for i, t := range toks {
+		if t.Kind == TokEOF || (t.Kind == TokOp && t.Text == ";" && depth == 0) {
+			if start >= 0 {
+				out = append(out, strings.TrimSpace(src[start:t.Pos]))
+			}
+			start = -1
+			continue
+		}
+		if start < 0 {
+			start, first = t.Pos, i
+		}
+		if t.Kind == TokIdent && isTrigger(toks[first:i]) {
+			switch strings.ToUpper(t.Text) {
+			case "BEGIN", "CASE":
+				depth++
+			case "END":
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// isTrigger reports whether the tokens open a CREATE TRIGGER statement.
+func isTrigger(toks []Token) bool {
+	if len(toks) < 2 || toks[0].Kind != TokIdent || !strings.EqualFold(toks[0].Text, "CREATE") {
+		return false
+	}
+	kw := toks[1]
+	if len(toks) > 2 && (strings.EqualFold(kw.Text, "TEMP") || strings.EqualFold(kw.Text, "TEMPORARY")) {
+		kw = toks[2]
+	}
+	return kw.Kind == TokIdent && strings.EqualFold(kw.Text, "TRIGGER")
+}
+
+// CountParams returns the number of positional parameters a statement
+// consumes: the count of bare "?" markers, or the highest "?NNN" index.
+func CountParams(src string) (int, error) {
+	toks, err := Tokenize(src)
+	if err != nil {
+		return 0, err
+	}
+	n, max := 0, 0
+	for _, t := range toks {
+		if t.Kind != TokParam {
+			continue
+		}
+		if len(t.Text) == 1 {
+			n++
+			continue
+		}
+		var idx int
+		fmt.Sscanf(t.Text[1:], "%d", &idx)
+		if idx > max {
+			max = idx
+		}
+	}
+	if max > n {
+		return max, nil
+	}
+	return n, nil
+}
+
+// scanQuoted reads a quoted token starting at src[i] and returns its
+// unescaped contents and total length. Doubled quotes escape themselves.
+func scanQuoted(src string, i int, q byte) (string, int, error) {
+	var b strings.Builder
+	j := i + 1
+	for j < len(src) {
+		if src[j] == q {
+			if j+1 < len(src) && src[j+1] == q {
+				b.WriteByte(q)
+				j += 2
+				continue
+			}
+			return b.String(), j + 1 - i, nil
+		}
+		b.WriteByte(src[j])
+		j++
+	}
+	return "", 0, &Error{i, "unterminated quoted literal"}
+}
+
+func scanNumber(s string) int {
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		j := 2
+		for j < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[j]) >= 0 {
+			j++
+		}
+		return j
+	}
+	j := 0
+	for j < len(s) && isDigit(s[j]) {
+		j++
+	}
+	if j < len(s) && s[j] == '.' {
+		j++
+		for j < len(s) && isDigit(s[j]) {
+			j++
+		}
+	}
+	if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+		k := j + 1
+		if k < len(s) && (s[k] == '+' || s[k] == '-') {
+			k++
+		}
+		if k < len(s) && isDigit(s[k]) {
+			for k < len(s) && isDigit(s[k]) {
+				k++
+			}
+			j = k
+		}
+	}
+	return j
+}
+
+var twoCharOps = []string{"||", "<=", ">=", "<>", "!=", "==", "<<", ">>"}
+
+func scanOp(s string) int {
+	for _, op := range twoCharOps {
+		if strings.HasPrefix(s, op) {
+			return 2
+		}
+	}
+	if strings.IndexByte("(),;.=<>+-*/%&|~", s[0]) >= 0 {
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool { return isIdentStart(c) || isDigit(c) || c == '$' }
@@ -0,0 +1,39 @@
+package sqlparse
+
+import "testing"
+
+func TestSplit_CountParams(t *testing.T) {
+	stmts, err := Split("BEGIN; INSERT INTO t (a) VALUES ('x;y', ?) -- c;\n; /* ; */ COMMIT;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("stmts = %q", stmts)
+	}
+	n, err := CountParams(stmts[1])
+	if err != nil || n != 1 {
+		t.Fatalf("params = %d err=%v", n, err)
+	}
+	stmts, err = Split(`CREATE TRIGGER tr AFTER INSERT ON t BEGIN
+		UPDATE t SET n = CASE WHEN n > 0 THEN 1 ELSE 0 END; DELETE FROM u;
+	END; SELECT 1`)
+	if err != nil || len(stmts) != 2 || stmts[1] != "SELECT 1" {
+		t.Fatalf("trigger stmts = %q err=%v", stmts, err)
+	}
+	if _, err := Split("SELECT 'unterminated"); err == nil {
+		t.Fatal("expected error for unterminated string")
+	}
+}
+
+func TestParseCreateTable(t *testing.T) {
+	ct, err := ParseCreateTable(`CREATE TABLE IF NOT EXISTS "users" (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, n INT DEFAULT 0)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct.Name != "users" || len(ct.Columns) != 3 {
+		t.Fatalf("create = %+v", ct)
+	}
+	if _, err := Parse("SELEC 1"); err == nil {
+		t.Fatal("expected syntax error")
+	}
+}
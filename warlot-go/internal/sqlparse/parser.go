@@ -0,0 +1,898 @@
+package sqlparse
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses exactly one statement; a trailing semicolon is allowed.
+func Parse(src string) (Statement, error) {
+	stmts, err := ParseScript(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) != 1 {
+		return nil, fmt.Errorf("expected one statement, got %d", len(stmts))
+	}
+	return stmts[0], nil
+}
+
+// ParseScript parses a semicolon-separated script. Positional parameters
+// are numbered across the whole script, so one flat argument list binds
+// all statements in order; "?NNN" is relative to its own statement.
+func ParseScript(src string) ([]Statement, error) {
+	toks, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, toks: toks}
+	var out []Statement
+	for {
+		for p.acceptOp(";") {
+		}
+		if p.peek().Kind == TokEOF {
+			return out, nil
+		}
+		p.stmtBase, p.stmtMax = p.nparam, 0
+		st, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		if p.stmtBase+p.stmtMax > p.nparam {
+			p.nparam = p.stmtBase + p.stmtMax
+		}
+		out = append(out, st)
+		if t := p.peek(); t.Kind != TokEOF && !p.isOp(";") {
+			return nil, p.errorf("unexpected %q", t.Text)
+		}
+	}
+}
+
+// ParseCreateTable parses a single CREATE TABLE statement.
+func ParseCreateTable(src string) (*CreateTable, error) {
+	st, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	ct, ok := st.(*CreateTable)
+	if !ok {
+		return nil, fmt.Errorf("not a CREATE TABLE statement")
+	}
+	return ct, nil
+}
+
+type parser struct {
+	src      string
+	toks     []Token
+	pos      int
+	nparam   int // next bare "?" index
+	stmtBase int // first parameter index of the current statement
+	stmtMax  int // highest "?NNN" seen in the current statement
+}
+
+// bail carries a syntax error through recursive descent via panic.
+type bail struct{ err error }
+
+func (p *parser) statement() (st Statement, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bail)
+			if !ok {
+				panic(r)
+			}
+			err = b.err
+		}
+	}()
+	start := p.peek().Pos
+	t := p.peek()
+	if t.Kind != TokIdent {
+		return nil, p.errorf("unexpected %q", t.Text)
+	}
+	switch strings.ToUpper(t.Text) {
+	case "CREATE":
+		p.next()
+		p.acceptKw("TEMP", "TEMPORARY")
+		switch {
+		case p.acceptKw("TABLE"):
+			ct := p.createTable()
+			ct.Raw = strings.TrimSpace(p.src[start:p.prevEnd()])
+			return ct, nil
+		case p.acceptKw("UNIQUE"):
+			p.expectKw("INDEX")
+			ci := p.createIndex(true)
+			ci.Raw = strings.TrimSpace(p.src[start:p.prevEnd()])
+			return ci, nil
+		case p.acceptKw("INDEX"):
+			ci := p.createIndex(false)
+			ci.Raw = strings.TrimSpace(p.src[start:p.prevEnd()])
+			return ci, nil
+		}
+		return nil, p.errorf("unsupported CREATE statement")
+	case "DROP":
+		p.next()
+		switch {
+		case p.acceptKw("TABLE"):
+			d := &DropTable{}
+			d.IfExists = p.ifExists()
+			d.Name = p.qualifiedName()
+			return d, nil
+		case p.acceptKw("INDEX"):
+			d := &DropIndex{}
+			d.IfExists = p.ifExists()
+			d.Name = p.qualifiedName()
+			return d, nil
+		}
+		return nil, p.errorf("unsupported DROP statement")
+	case "ALTER":
+		p.next()
+		p.expectKw("TABLE")
+		return p.alterTable(), nil
+	}
+	return nil, p.errorf("unsupported statement %q", t.Text)
+}
+
+// ---- DDL ----
+
+func (p *parser) createTable() *CreateTable {
+	ct := &CreateTable{}
+	ct.IfNotExists = p.ifNotExists()
+	ct.Name = p.qualifiedName()
+	if p.isKw("AS") {
+		p.fail("CREATE TABLE ... AS SELECT is not supported")
+	}
+	p.expectOp("(")
+	for {
+		if p.isTableConstraint() {
+			p.tableConstraint(ct)
+		} else {
+			ct.Columns = append(ct.Columns, p.columnDef())
+		}
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	p.expectOp(")")
+	// Table options: WITHOUT ROWID, STRICT.
+	for {
+		if p.acceptKw("WITHOUT") {
+			p.expectKw("ROWID")
+		} else if !p.acceptKw("STRICT") {
+			break
+		}
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	return ct
+}
+
+func (p *parser) isTableConstraint() bool {
+	t := p.peek()
+	if t.Kind != TokIdent {
+		return false
+	}
+	switch strings.ToUpper(t.Text) {
+	case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+		return true
+	}
+	return false
+}
+
+func (p *parser) tableConstraint(ct *CreateTable) {
+	if p.acceptKw("CONSTRAINT") {
+		p.ident()
+	}
+	switch {
+	case p.acceptKw("PRIMARY"):
+		p.expectKw("KEY")
+		ct.PrimaryKey = p.indexedColumns()
+		p.conflictClause()
+	case p.acceptKw("UNIQUE"):
+		ct.Unique = append(ct.Unique, p.indexedColumns())
+		p.conflictClause()
+	case p.acceptKw("CHECK"):
+		p.expectOp("(")
+		ct.Checks = append(ct.Checks, p.expr())
+		p.expectOp(")")
+	case p.acceptKw("FOREIGN"):
+		p.expectKw("KEY")
+		fk := ForeignKey{Columns: p.indexedColumns()}
+		p.expectKw("REFERENCES")
+		p.references(&fk)
+		ct.ForeignKeys = append(ct.ForeignKeys, fk)
+	default:
+		p.fail("expected table constraint")
+	}
+}
+
+var columnConstraintKw = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "NOT": true, "NULL": true, "UNIQUE": true,
+	"CHECK": true, "DEFAULT": true, "COLLATE": true, "REFERENCES": true,
+	"GENERATED": true, "AS": true,
+}
+
+func (p *parser) columnDef() ColumnDef {
+	start := p.peek().Pos
+	cd := ColumnDef{Name: p.ident()}
+
+	// Declared type: identifiers up to the first constraint keyword, with an
+	// optional "(n[, m])" size.
+	typeStart, typeEnd := -1, -1
+	for t := p.peek(); t.Kind == TokIdent && !columnConstraintKw[strings.ToUpper(t.Text)]; t = p.peek() {
+		if typeStart < 0 {
+			typeStart = t.Pos
+		}
+		p.next()
+		typeEnd = t.End
+	}
+	if typeStart >= 0 && p.acceptOp("(") {
+		for !p.isOp(")") {
+			if p.peek().Kind == TokEOF {
+				p.fail("unterminated type size")
+			}
+			p.next()
+		}
+		typeEnd = p.next().End
+	}
+	if typeStart >= 0 {
+		cd.Type = p.src[typeStart:typeEnd]
+	}
+
+	for {
+		if p.acceptKw("CONSTRAINT") {
+			p.ident()
+		}
+		switch {
+		case p.acceptKw("PRIMARY"):
+			p.expectKw("KEY")
+			cd.PrimaryKey = true
+			if p.acceptKw("DESC") {
+				cd.Desc = true
+			} else {
+				p.acceptKw("ASC")
+			}
+			p.conflictClause()
+			cd.AutoIncrement = p.acceptKw("AUTOINCREMENT")
+		case p.isKw("NOT") && p.peekKwAt(1, "NULL"):
+			p.next()
+			p.next()
+			cd.NotNull = true
+			p.conflictClause()
+		case p.acceptKw("NULL"):
+		case p.acceptKw("UNIQUE"):
+			cd.Unique = true
+			p.conflictClause()
+		case p.acceptKw("CHECK"):
+			p.expectOp("(")
+			cd.Check = p.expr()
+			p.expectOp(")")
+		case p.acceptKw("DEFAULT"):
+			ds := p.peek().Pos
+			if p.acceptOp("(") {
+				cd.Default = p.expr()
+				p.expectOp(")")
+			} else {
+				cd.Default = p.unary()
+			}
+			cd.DefaultText = p.src[ds:p.prevEnd()]
+		case p.acceptKw("COLLATE"):
+			cd.Collate = p.ident()
+		case p.acceptKw("REFERENCES"):
+			fk := &ForeignKey{}
+			p.references(fk)
+			cd.References = fk
+		case p.acceptKw("GENERATED"):
+			p.expectKw("ALWAYS")
+			p.expectKw("AS")
+			p.generated()
+		case p.acceptKw("AS"):
+			p.generated()
+		default:
+			cd.Raw = strings.TrimSpace(p.src[start:p.prevEnd()])
+			return cd
+		}
+	}
+}
+
+func (p *parser) generated() {
+	p.expectOp("(")
+	p.expr()
+	p.expectOp(")")
+	p.acceptKw("STORED", "VIRTUAL")
+	p.fail("generated columns are not supported")
+}
+
+func (p *parser) references(fk *ForeignKey) {
+	fk.Table = p.qualifiedName()
+	if p.isOp("(") {
+		fk.RefColumns = p.indexedColumns()
+	}
+	for {
+		switch {
+		case p.acceptKw("ON"):
+			p.expectKw("DELETE", "UPDATE")
+			switch {
+			case p.acceptKw("SET"):
+				p.expectKw("NULL", "DEFAULT")
+			case p.acceptKw("NO"):
+				p.expectKw("ACTION")
+			default:
+				p.expectKw("CASCADE", "RESTRICT")
+			}
+		case p.acceptKw("MATCH"):
+			p.ident()
+		case p.isKw("NOT") && p.peekKwAt(1, "DEFERRABLE"):
+			p.next()
+			p.next()
+			p.deferrable()
+		case p.acceptKw("DEFERRABLE"):
+			p.deferrable()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) deferrable() {
+	if p.acceptKw("INITIALLY") {
+		p.expectKw("DEFERRED", "IMMEDIATE")
+	}
+}
+
+func (p *parser) conflictClause() {
+	if p.isKw("ON") && p.peekKwAt(1, "CONFLICT") {
+		p.next()
+		p.next()
+		p.expectKw("ROLLBACK", "ABORT", "FAIL", "IGNORE", "REPLACE")
+	}
+}
+
+// indexedColumns parses "(col [COLLATE x] [ASC|DESC], ...)".
+func (p *parser) indexedColumns() []string {
+	p.expectOp("(")
+	var cols []string
+	for {
+		cols = append(cols, p.ident())
+		if p.acceptKw("COLLATE") {
+			p.ident()
+		}
+		p.acceptKw("ASC", "DESC")
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	p.expectOp(")")
+	return cols
+}
+
+func (p *parser) createIndex(unique bool) *CreateIndex {
+	ci := &CreateIndex{Unique: unique}
+	ci.IfNotExists = p.ifNotExists()
+	ci.Name = p.qualifiedName()
+	p.expectKw("ON")
+	ci.Table = p.ident()
+	ci.Columns = p.indexedColumns()
+	if p.acceptKw("WHERE") {
+		ci.Where = p.expr()
+	}
+	return ci
+}
+
+func (p *parser) alterTable() *AlterTable {
+	at := &AlterTable{Name: p.qualifiedName()}
+	switch {
+	case p.acceptKw("ADD"):
+		p.acceptKw("COLUMN")
+		cd := p.columnDef()
+		at.AddColumn = &cd
+	case p.acceptKw("DROP"):
+		p.acceptKw("COLUMN")
+		at.DropColumn = p.ident()
+	case p.acceptKw("RENAME"):
+		if p.acceptKw("TO") {
+			at.RenameTo = p.ident()
+			break
+		}
+		p.acceptKw("COLUMN")
+		at.RenameColumn[0] = p.ident()
+		p.expectKw("TO")
+		at.RenameColumn[1] = p.ident()
+	default:
+		p.fail("unsupported ALTER TABLE action")
+	}
+	return at
+}
+
+// ---- expressions ----
+
+func (p *parser) expr() Expr { return p.or() }
+
+func (p *parser) or() Expr {
+	l := p.and()
+	for p.acceptKw("OR") {
+		l = &Binary{Op: "OR", L: l, R: p.and()}
+	}
+	return l
+}
+
+func (p *parser) and() Expr {
+	l := p.not()
+	for p.acceptKw("AND") {
+		l = &Binary{Op: "AND", L: l, R: p.not()}
+	}
+	return l
+}
+
+func (p *parser) not() Expr {
+	if p.isKw("NOT") && !p.peekKwAt(1, "EXISTS") {
+		p.next()
+		return &Unary{Op: "NOT", X: p.not()}
+	}
+	return p.equality()
+}
+
+func (p *parser) equality() Expr {
+	l := p.comparison()
+	for {
+		switch {
+		case p.acceptOp("=", "=="):
+			l = &Binary{Op: "=", L: l, R: p.comparison()}
+		case p.acceptOp("!=", "<>"):
+			l = &Binary{Op: "!=", L: l, R: p.comparison()}
+		case p.acceptKw("IS"):
+			op := "IS"
+			if p.acceptKw("NOT") {
+				op = "IS NOT"
+			}
+			if p.acceptKw("DISTINCT") {
+				p.expectKw("FROM")
+				if op == "IS" {
+					op = "IS NOT"
+				} else {
+					op = "IS"
+				}
+			}
+			l = &Binary{Op: op, L: l, R: p.comparison()}
+		case p.acceptKw("ISNULL"):
+			l = &Binary{Op: "IS", L: l, R: &Literal{}}
+		case p.acceptKw("NOTNULL"):
+			l = &Binary{Op: "IS NOT", L: l, R: &Literal{}}
+		case p.isKw("NOT") && p.peekKwAt(1, "NULL"):
+			p.next()
+			p.next()
+			l = &Binary{Op: "IS NOT", L: l, R: &Literal{}}
+		default:
+			not := false
+			if p.isKw("NOT") && p.peekKwAt(1, "IN", "LIKE", "GLOB", "BETWEEN") {
+				p.next()
+				not = true
+			}
+			switch {
+			case p.acceptKw("IN"):
+				in := &In{X: l, Not: not}
+				p.expectOp("(")
+				if !p.isOp(")") {
+					in.List = p.exprList()
+				}
+				p.expectOp(")")
+				l = in
+			case p.acceptKw("LIKE"), p.isKw("GLOB"):
+				glob := p.acceptKw("GLOB")
+				lk := &Like{X: l, Pattern: p.comparison(), Not: not, Glob: glob}
+				if p.acceptKw("ESCAPE") {
+					lk.Escape = p.comparison()
+				}
+				l = lk
+			case p.acceptKw("BETWEEN"):
+				lo := p.comparison()
+				p.expectKw("AND")
+				l = &Between{X: l, Lo: lo, Hi: p.comparison(), Not: not}
+			default:
+				return l
+			}
+		}
+	}
+}
+
+func (p *parser) comparison() Expr {
+	l := p.bitwise()
+	for {
+		t := p.peek()
+		if t.Kind == TokOp && (t.Text == "<" || t.Text == "<=" || t.Text == ">" || t.Text == ">=") {
+			p.next()
+			l = &Binary{Op: t.Text, L: l, R: p.bitwise()}
+			continue
+		}
+		return l
+	}
+}
+
+func (p *parser) bitwise() Expr {
+	l := p.additive()
+	for {
+		t := p.peek()
+		if t.Kind == TokOp && (t.Text == "&" || t.Text == "|" || t.Text == "<<" || t.Text == ">>") {
+			p.next()
+			l = &Binary{Op: t.Text, L: l, R: p.additive()}
+			continue
+		}
+		return l
+	}
+}
+
+func (p *parser) additive() Expr {
+	l := p.multiplicative()
+	for {
+		t := p.peek()
+		if t.Kind == TokOp && (t.Text == "+" || t.Text == "-") {
+			p.next()
+			l = &Binary{Op: t.Text, L: l, R: p.multiplicative()}
+			continue
+		}
+		return l
+	}
+}
+
+func (p *parser) multiplicative() Expr {
+	l := p.concat()
+	for {
+		t := p.peek()
+		if t.Kind == TokOp && (t.Text == "*" || t.Text == "/" || t.Text == "%") {
+			p.next()
+			l = &Binary{Op: t.Text, L: l, R: p.concat()}
+			continue
+		}
+		return l
+	}
+}
+
+func (p *parser) concat() Expr {
+	l := p.unary()
+	for p.acceptOp("||") {
+		l = &Binary{Op: "||", L: l, R: p.unary()}
+	}
+	return l
+}
+
+func (p *parser) unary() Expr {
+	t := p.peek()
+	if t.Kind == TokOp && (t.Text == "-" || t.Text == "+" || t.Text == "~") {
+		p.next()
+		x := p.unary()
+		if lit, ok := x.(*Literal); ok && t.Text != "~" {
+			switch v := lit.Value.(type) {
+			case int64:
+				if t.Text == "-" {
+					return &Literal{Value: -v}
+				}
+				return lit
+			case float64:
+				if t.Text == "-" {
+					return &Literal{Value: -v}
+				}
+				return lit
+			}
+		}
+		return &Unary{Op: t.Text, X: x}
+	}
+	x := p.primary()
+	for p.acceptKw("COLLATE") {
+		p.ident()
+	}
+	return x
+}
+
+func (p *parser) primary() Expr {
+	t := p.peek()
+	switch t.Kind {
+	case TokNumber:
+		p.next()
+		return &Literal{Value: parseNumber(t.Text)}
+	case TokString:
+		p.next()
+		return &Literal{Value: t.Text}
+	case TokBlob:
+		p.next()
+		b, err := hex.DecodeString(t.Text)
+		if err != nil {
+			p.fail("malformed blob literal")
+		}
+		return &Literal{Value: b}
+	case TokParam:
+		p.next()
+		if len(t.Text) == 1 {
+			idx := p.nparam
+			p.nparam++
+			return &Param{Index: idx}
+		}
+		n, err := strconv.Atoi(t.Text[1:])
+		if err != nil || n < 1 {
+			p.fail("bad parameter " + t.Text)
+		}
+		if n > p.stmtMax {
+			p.stmtMax = n
+		}
+		return &Param{Index: p.stmtBase + n - 1}
+	case TokOp:
+		if t.Text == "(" {
+			p.next()
+			items := p.exprList()
+			p.expectOp(")")
+			if len(items) == 1 {
+				return items[0]
+			}
+			return &Tuple{Items: items}
+		}
+	case TokQuotedIdent:
+		return p.columnRef()
+	case TokIdent:
+		switch strings.ToUpper(t.Text) {
+		case "NULL":
+			p.next()
+			return &Literal{}
+		case "TRUE":
+			p.next()
+			return &Literal{Value: int64(1)}
+		case "FALSE":
+			p.next()
+			return &Literal{Value: int64(0)}
+		case "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME":
+			p.next()
+			return &Func{Name: strings.ToUpper(t.Text)}
+		case "CASE":
+			p.next()
+			return p.caseExpr()
+		case "CAST":
+			p.next()
+			p.expectOp("(")
+			x := p.expr()
+			p.expectKw("AS")
+			ts := p.peek().Pos
+			for p.peek().Kind == TokIdent {
+				p.next()
+			}
+			if p.acceptOp("(") {
+				for !p.acceptOp(")") {
+					p.next()
+				}
+			}
+			typ := strings.TrimSpace(p.src[ts:p.prevEnd()])
+			p.expectOp(")")
+			return &Cast{X: x, Type: typ}
+		}
+		if p.peekOpAt(1, "(") {
+			return p.function()
+		}
+		return p.columnRef()
+	}
+	p.fail(fmt.Sprintf("unexpected %q", tokenText(t)))
+	return nil
+}
+
+func (p *parser) caseExpr() Expr {
+	c := &Case{}
+	if !p.isKw("WHEN") {
+		c.Operand = p.expr()
+	}
+	for p.acceptKw("WHEN") {
+		w := When{Cond: p.expr()}
+		p.expectKw("THEN")
+		w.Result = p.expr()
+		c.Whens = append(c.Whens, w)
+	}
+	if len(c.Whens) == 0 {
+		p.fail("CASE without WHEN")
+	}
+	if p.acceptKw("ELSE") {
+		c.Else = p.expr()
+	}
+	p.expectKw("END")
+	return c
+}
+
+func (p *parser) function() Expr {
+	f := &Func{Name: strings.ToUpper(p.next().Text)}
+	p.expectOp("(")
+	switch {
+	case p.acceptOp("*"):
+		f.Star = true
+	case p.isOp(")"):
+	default:
+		f.Distinct = p.acceptKw("DISTINCT")
+		f.Args = p.exprList()
+	}
+	p.expectOp(")")
+	if p.isKw("FILTER", "OVER") {
+		p.fail("window functions and FILTER are not supported")
+	}
+	return f
+}
+
+func (p *parser) columnRef() Expr {
+	name := p.ident()
+	if p.acceptOp(".") {
+		return &ColumnRef{Table: name, Name: p.ident()}
+	}
+	return &ColumnRef{Name: name}
+}
+
+func (p *parser) exprList() []Expr {
+	var out []Expr
+	for {
+		out = append(out, p.expr())
+		if !p.acceptOp(",") {
+			return out
+		}
+	}
+}
+
+// ---- token helpers ----
+
+func (p *parser) peek() Token { return p.toks[p.pos] }
+
+func (p *parser) next() Token {
+	t := p.toks[p.pos]
+	if t.Kind != TokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) prevEnd() int {
+	if p.pos == 0 {
+		return 0
+	}
+	return p.toks[p.pos-1].End
+}
+
+func (p *parser) at(n int) Token {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) isKw(kws ...string) bool { return p.peekKwAt(0, kws...) }
+
+func (p *parser) peekKwAt(n int, kws ...string) bool {
+	t := p.at(n)
+	if t.Kind != TokIdent {
+		return false
+	}
+	for _, kw := range kws {
+		if strings.EqualFold(t.Text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) acceptKw(kws ...string) bool {
+	if p.isKw(kws...) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKw(kws ...string) string {
+	if !p.isKw(kws...) {
+		p.fail(fmt.Sprintf("expected %s, got %q", strings.Join(kws, " or "), tokenText(p.peek())))
+	}
+	return p.next().Text
+}
+
+func (p *parser) isOp(ops ...string) bool { return p.peekOpAt(0, ops...) }
+
+func (p *parser) peekOpAt(n int, ops ...string) bool {
+	t := p.at(n)
+	if t.Kind != TokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.Text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) acceptOp(ops ...string) bool {
+	if p.isOp(ops...) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectOp(op string) {
+	if !p.acceptOp(op) {
+		p.fail(fmt.Sprintf("expected %q, got %q", op, tokenText(p.peek())))
+	}
+}
+
+// ident accepts a bare or quoted identifier; string literals are accepted
+// too, as SQLite does in identifier positions.
+func (p *parser) ident() string {
+	t := p.peek()
+	switch t.Kind {
+	case TokIdent, TokQuotedIdent, TokString:
+		p.next()
+		return t.Text
+	}
+	p.fail(fmt.Sprintf("expected identifier, got %q", tokenText(t)))
+	return ""
+}
+
+// qualifiedName parses [schema.]name and drops the schema.
+func (p *parser) qualifiedName() string {
+	name := p.ident()
+	if p.acceptOp(".") {
+		name = p.ident()
+	}
+	return name
+}
+
+func (p *parser) identList() []string {
+	p.expectOp("(")
+	var out []string
+	for {
+		out = append(out, p.ident())
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	p.expectOp(")")
+	return out
+}
+
+func (p *parser) ifNotExists() bool {
+	if p.acceptKw("IF") {
+		p.expectKw("NOT")
+		p.expectKw("EXISTS")
+		return true
+	}
+	return false
+}
+
+func (p *parser) ifExists() bool {
+	if p.acceptKw("IF") {
+		p.expectKw("EXISTS")
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, a ...any) error {
+	return &Error{Pos: p.peek().Pos, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (p *parser) fail(msg string) {
+	panic(bail{&Error{Pos: p.peek().Pos, Msg: msg}})
+}
+
+func tokenText(t Token) string {
+	if t.Kind == TokEOF {
+		return "end of input"
+	}
+	return t.Text
+}
+
+// parseNumber converts a numeric literal to int64 when it fits, float64
+// otherwise.
+func parseNumber(s string) any {
+	if len(s) > 2 && (s[1] == 'x' || s[1] == 'X') {
+		if u, err := strconv.ParseUint(s[2:], 16, 64); err == nil {
+			return int64(u)
+		}
+	}
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,4 @@
+// Package gentest holds structgen output for a table with BOOLEAN columns,
+// regenerated by structgen's tests, so the generated helpers can be run
+// against the fake server.
+package gentest
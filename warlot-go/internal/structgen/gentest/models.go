@@ -0,0 +1,58 @@
+// Code generated by warlotdev gen structs. DO NOT EDIT.
+
+package gentest
+
+import (
+	"context"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Flag is a row of table flags.
+type Flag struct {
+	ID       int64  `json:"id" warlot:"id,pk,omitempty"`
+	Name     string `json:"name" warlot:"name"`
+	Enabled  int64  `json:"enabled" warlot:"enabled"`
+	Archived *int64 `json:"archived" warlot:"archived"`
+}
+
+// TableName implements warlot.Tabler.
+func (Flag) TableName() string { return "flags" }
+
+// ListFlags returns every row of flags.
+func ListFlags(ctx context.Context, p warlot.Project) ([]Flag, error) {
+	return warlot.Query[Flag](ctx, p, "SELECT * FROM \"flags\"", nil)
+}
+
+// InsertFlag inserts v into flags.
+func InsertFlag(ctx context.Context, p warlot.Project, v Flag) error {
+	_, err := warlot.Insert(ctx, p, v)
+	return err
+}
+
+// GetFlag returns the flags row with the given key, or nil.
+func GetFlag(ctx context.Context, p warlot.Project, id int64) (*Flag, error) {
+	rows, err := warlot.Query[Flag](ctx, p, "SELECT * FROM \"flags\" WHERE \"id\" = ?", []any{id})
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+// UpdateFlag updates the flags row matching v's key.
+func UpdateFlag(ctx context.Context, p warlot.Project, v Flag) error {
+	_, err := warlot.Update(ctx, p, v)
+	return err
+}
+
+// UpsertFlag inserts v or updates the flags row with the same key.
+func UpsertFlag(ctx context.Context, p warlot.Project, v Flag) error {
+	_, err := warlot.Upsert(ctx, p, v)
+	return err
+}
+
+// DeleteFlag deletes the flags row matching v's key.
+func DeleteFlag(ctx context.Context, p warlot.Project, v Flag) error {
+	_, err := warlot.Delete(ctx, p, v)
+	return err
+}
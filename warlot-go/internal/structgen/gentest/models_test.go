@@ -0,0 +1,45 @@
+package gentest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/warlottest"
+)
+
+func TestGenerated_BooleanColumns(t *testing.T) {
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p := srv.Client().Project("p")
+	if _, err := p.SQL(ctx, `CREATE TABLE flags (id INTEGER PRIMARY KEY, name TEXT NOT NULL, enabled BOOLEAN NOT NULL DEFAULT 0, archived BOOLEAN);
+INSERT INTO flags (name, enabled, archived) VALUES ('sql', TRUE, FALSE)`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InsertFlag(ctx, p, Flag{Name: "dark_mode", Enabled: 1}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetFlag(ctx, p, 2)
+	if err != nil || got == nil {
+		t.Fatalf("GetFlag: %v %v", got, err)
+	}
+	if got.Name != "dark_mode" || got.Enabled != 1 || got.Archived != nil {
+		t.Fatalf("GetFlag = %+v", *got)
+	}
+
+	got.Enabled = 0
+	if err := UpdateFlag(ctx, p, *got); err != nil {
+		t.Fatal(err)
+	}
+	all, err := ListFlags(ctx, p)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("ListFlags: %+v %v", all, err)
+	}
+	if all[0].Enabled != 1 || all[0].Archived == nil || *all[0].Archived != 0 || all[1].Enabled != 0 {
+		t.Fatalf("ListFlags = %+v", all)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build cgo
+
+package gentest
+
+import _ "github.com/mattn/go-sqlite3" // registers the driver warlottest opens
@@ -0,0 +1,335 @@
+// Package structgen generates Go structs, and optionally typed CRUD
+// helpers, from Warlot table schemas.
+package structgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/affinity"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Column describes a table column.
+type Column struct {
+	Name    string
+	Type    string // declared SQL type
+	NotNull bool
+	PK      int // 1-based position in the primary key, 0 if not part of it
+}
+
+// Table describes a table.
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// Options control generation.
+type Options struct {
+	Package string // package clause; default "models"
+	CRUD    bool   // also emit typed CRUD helpers
+}
+
+// TableFromSchema reads a table schema as returned by GetTableSchema. It
+// uses the typed columns and falls back to parsing the CREATE TABLE
+// statement when the API lists bare column names without types.
+func TableFromSchema(name string, schema *warlot.TableSchema) (Table, error) {
+	t := Table{Name: name}
+	if schema.SQL == "" || typed(schema.Columns) {
+		for _, c := range schema.Columns {
+			if c.Name == "" {
+				return t, fmt.Errorf("table %s: column without name", name)
+			}
+			t.Columns = append(t.Columns, Column{Name: c.Name, Type: c.Type, NotNull: c.NotNull, PK: c.PK})
+		}
+		if len(t.Columns) > 0 {
+			return t, nil
+		}
+		return t, fmt.Errorf("table %s: schema has neither columns nor sql", name)
+	}
+	ct, err := sqlparse.ParseCreateTable(schema.SQL)
+	if err != nil {
+		return t, fmt.Errorf("table %s: %w", name, err)
+	}
+	return tableFromDDL(ct), nil
+}
+
+func typed(cols []warlot.ColumnSchema) bool {
+	for _, c := range cols {
+		if c.Type != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func tableFromDDL(ct *sqlparse.CreateTable) Table {
+	t := Table{Name: ct.Name}
+	pos := map[string]int{}
+	for i, c := range ct.PrimaryKey {
+		pos[strings.ToLower(c)] = i + 1
+	}
+	for _, c := range ct.Columns {
+		col := Column{Name: c.Name, Type: c.Type, NotNull: c.NotNull, PK: pos[strings.ToLower(c.Name)]}
+		if c.PrimaryKey {
+			col.PK = 1
+		}
+		t.Columns = append(t.Columns, col)
+	}
+	return t
+}
+
+// Generate renders gofmt'ed Go source for tables, in name order.
+func Generate(tables []Table, opts Options) ([]byte, error) {
+	if len(tables) == 0 {
+		return nil, errors.New("structgen: no tables")
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "models"
+	}
+	tables = append([]Table(nil), tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by warlotdev gen structs. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if opts.CRUD {
+		b.WriteString("import (\n\t\"context\"\n\n\t\"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot\"\n)\n\n")
+	}
+	seen := map[string]string{}
+	for _, t := range tables {
+		name := TypeName(t.Name)
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("structgen: tables %s and %s both map to type %s", prev, t.Name, name)
+		}
+		seen[name] = t.Name
+		writeStruct(&b, name, t)
+		if opts.CRUD {
+			writeCRUD(&b, name, t)
+		}
+	}
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("structgen: format: %w\n%s", err, b.Bytes())
+	}
+	return out, nil
+}
+
+func writeStruct(b *bytes.Buffer, name string, t Table) {
+	fmt.Fprintf(b, "// %s is a row of table %s.\ntype %s struct {\n", name, t.Name, name)
+	rowid := rowidAlias(t)
+	fields := map[string]bool{}
+	for _, c := range t.Columns {
+		field := FieldName(c.Name)
+		for fields[field] {
+			field += "_"
+		}
+		fields[field] = true
+		tag := c.Name
+		if c.PK > 0 {
+			tag += ",pk"
+		}
+		if c.Name == rowid {
+			tag += ",omitempty"
+		}
+		typ := affinity.GoType(c.Type, !c.NotNull && c.PK == 0)
+		fmt.Fprintf(b, "\t%s %s `json:%q warlot:%q`\n", field, typ, c.Name, tag)
+	}
+	fmt.Fprintf(b, "}\n\n// TableName implements warlot.Tabler.\nfunc (%s) TableName() string { return %q }\n\n", name, t.Name)
+}
+
+// rowidAlias returns the INTEGER PRIMARY KEY column, which SQLite assigns
+// when omitted, or "".
+func rowidAlias(t Table) string {
+	var pk []Column
+	for _, c := range t.Columns {
+		if c.PK > 0 {
+			pk = append(pk, c)
+		}
+	}
+	if len(pk) == 1 && strings.EqualFold(pk[0].Type, "INTEGER") {
+		return pk[0].Name
+	}
+	return ""
+}
+
+func writeCRUD(b *bytes.Buffer, name string, t Table) {
+	many := plural(name)
+	fmt.Fprintf(b, `// List%[2]s returns every row of %[3]s.
+func List%[2]s(ctx context.Context, p warlot.Project) ([]%[1]s, error) {
+	return warlot.Query[%[1]s](ctx, p, %[4]q, nil)
+}
+
+// Insert%[1]s inserts v into %[3]s.
+func Insert%[1]s(ctx context.Context, p warlot.Project, v %[1]s) error {
+	_, err := warlot.Insert(ctx, p, v)
+	return err
+}
+
+`, name, many, t.Name, "SELECT * FROM "+quoteIdent(t.Name))
+
+	var pk []Column
+	for _, c := range t.Columns {
+		if c.PK > 0 {
+			pk = append(pk, c)
+		}
+	}
+	if len(pk) == 0 {
+		return
+	}
+	sort.SliceStable(pk, func(i, j int) bool { return pk[i].PK < pk[j].PK })
+	var params, args, where []string
+	for _, c := range pk {
+		arg := argName(c.Name)
+		params = append(params, arg+" "+affinity.GoType(c.Type, false))
+		args = append(args, arg)
+		where = append(where, quoteIdent(c.Name)+" = ?")
+	}
+	query := "SELECT * FROM " + quoteIdent(t.Name) + " WHERE " + strings.Join(where, " AND ")
+	fmt.Fprintf(b, `// Get%[1]s returns the %[2]s row with the given key, or nil.
+func Get%[1]s(ctx context.Context, p warlot.Project, %[3]s) (*%[1]s, error) {
+	rows, err := warlot.Query[%[1]s](ctx, p, %[4]q, []any{%[5]s})
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+// Update%[1]s updates the %[2]s row matching v's key.
+func Update%[1]s(ctx context.Context, p warlot.Project, v %[1]s) error {
+	_, err := warlot.Update(ctx, p, v)
+	return err
+}
+
+// Upsert%[1]s inserts v or updates the %[2]s row with the same key.
+func Upsert%[1]s(ctx context.Context, p warlot.Project, v %[1]s) error {
+	_, err := warlot.Upsert(ctx, p, v)
+	return err
+}
+
+// Delete%[1]s deletes the %[2]s row matching v's key.
+func Delete%[1]s(ctx context.Context, p warlot.Project, v %[1]s) error {
+	_, err := warlot.Delete(ctx, p, v)
+	return err
+}
+
+`, name, t.Name, strings.Join(params, ", "), query, strings.Join(args, ", "))
+}
+
+func quoteIdent(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+
+// initialisms are rendered in upper case, following Go naming.
+var initialisms = map[string]bool{
+	"API": true, "DB": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SKU": true, "SQL": true, "UID": true, "URL": true, "UUID": true,
+}
+
+// FieldName converts a column name such as "created_at" or "userId" to an
+// exported Go identifier ("CreatedAt", "UserID").
+func FieldName(col string) string {
+	var sb strings.Builder
+	for _, w := range words(col) {
+		if u := strings.ToUpper(w); initialisms[u] {
+			sb.WriteString(u)
+			continue
+		}
+		rs := []rune(strings.ToLower(w))
+		rs[0] = unicode.ToUpper(rs[0])
+		sb.WriteString(string(rs))
+	}
+	s := sb.String()
+	if s == "" {
+		return "X"
+	}
+	if !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// TypeName converts a table name to a singular exported type name, for
+// example "order_items" to "OrderItem".
+func TypeName(table string) string {
+	ws := words(table)
+	if n := len(ws); n > 0 {
+		ws[n-1] = singular(ws[n-1])
+	}
+	return FieldName(strings.Join(ws, "_"))
+}
+
+func argName(col string) string {
+	f := []rune(FieldName(col))
+	i := 0
+	for i < len(f) && unicode.IsUpper(f[i]) && (i == 0 || i+1 >= len(f) || unicode.IsUpper(f[i+1])) {
+		f[i] = unicode.ToLower(f[i])
+		i++
+	}
+	s := string(f)
+	switch s {
+	case "type", "func", "range", "map", "chan", "go", "select", "case", "default", "var", "const",
+		"package", "import", "return", "break", "continue", "for", "if", "else", "switch",
+		"interface", "struct", "defer", "goto", "fallthrough", "ctx", "p", "v", "err", "rows":
+		return s + "Key"
+	}
+	return s
+}
+
+// words splits an identifier on non-alphanumerics and lower-to-upper case
+// changes.
+func words(s string) []string {
+	var out []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, string(cur))
+			cur = nil
+		}
+	}
+	rs := []rune(s)
+	for i, r := range rs {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(rs[i-1]) ||
+			(unicode.IsUpper(rs[i-1]) && i+1 < len(rs) && unicode.IsLower(rs[i+1]))):
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return out
+}
+
+// plural adds a common English plural ending.
+func plural(w string) string {
+	l := strings.ToLower(w)
+	switch {
+	case strings.HasSuffix(l, "y") && len(l) > 1 && !strings.ContainsRune("aeiou", rune(l[len(l)-2])):
+		return w[:len(w)-1] + "ies"
+	case strings.HasSuffix(l, "s"), strings.HasSuffix(l, "x"), strings.HasSuffix(l, "ch"), strings.HasSuffix(l, "sh"):
+		return w + "es"
+	}
+	return w + "s"
+}
+
+// singular strips common English plural endings.
+func singular(w string) string {
+	l := strings.ToLower(w)
+	switch {
+	case strings.HasSuffix(l, "ies") && len(l) > 3:
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(l, "sses"), strings.HasSuffix(l, "xes"), strings.HasSuffix(l, "ches"), strings.HasSuffix(l, "shes"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(l, "s") && !strings.HasSuffix(l, "ss") && !strings.HasSuffix(l, "us") && !strings.HasSuffix(l, "is") && len(l) > 1:
+		return w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,121 @@
+package structgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+func TestTableFromSchema(t *testing.T) {
+	cols, err := TableFromSchema("products", &warlot.TableSchema{
+		Columns: []warlot.ColumnSchema{
+			{Name: "id", Type: "INTEGER", PK: 1},
+			{Name: "sku", Type: "TEXT", NotNull: true},
+		},
+	})
+	if err != nil || len(cols.Columns) != 2 || cols.Columns[0].PK != 1 || !cols.Columns[1].NotNull {
+		t.Fatalf("columns form: %+v err=%v", cols, err)
+	}
+
+	ddl, err := TableFromSchema("line_items", &warlot.TableSchema{
+		SQL:     `CREATE TABLE line_items (order_id INTEGER NOT NULL, line INT, note TEXT, PRIMARY KEY (order_id, line))`,
+		Columns: []warlot.ColumnSchema{{Name: "order_id"}, {Name: "line"}, {Name: "note"}},
+	})
+	if err != nil || len(ddl.Columns) != 3 || ddl.Columns[0].PK != 1 || ddl.Columns[1].PK != 2 || ddl.Columns[2].PK != 0 {
+		t.Fatalf("sql form: %+v err=%v", ddl, err)
+	}
+
+	if _, err := TableFromSchema("x", &warlot.TableSchema{}); err == nil {
+		t.Fatal("expected error for empty schema")
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := map[string]string{
+		"order_items": "OrderItem",
+		"categories":  "Category",
+		"addresses":   "Address",
+		"status":      "Status",
+		"userAPIKeys": "UserAPIKey",
+	}
+	for in, want := range names {
+		if got := TypeName(in); got != want {
+			t.Errorf("TypeName(%q) = %s, want %s", in, got, want)
+		}
+	}
+	if got := FieldName("user_id"); got != "UserID" {
+		t.Errorf("FieldName(user_id) = %s", got)
+	}
+	if got := FieldName("2fa"); got != "X2fa" {
+		t.Errorf("FieldName(2fa) = %s", got)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tables := []Table{
+		{Name: "products", Columns: []Column{
+			{Name: "id", Type: "INTEGER", PK: 1},
+			{Name: "sku", Type: "TEXT", NotNull: true},
+			{Name: "price", Type: "REAL"},
+		}},
+		{Name: "tags", Columns: []Column{{Name: "name", Type: "TEXT"}}},
+	}
+	src, err := Generate(tables, Options{Package: "db", CRUD: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"// Code generated by warlotdev gen structs. DO NOT EDIT.",
+		"package db",
+		"type Product struct {",
+		"ID    int64    `json:\"id\" warlot:\"id,pk,omitempty\"`",
+		"Price *float64 `json:\"price\" warlot:\"price\"`",
+		`func (Product) TableName() string { return "products" }`,
+		"func GetProduct(ctx context.Context, p warlot.Project, id int64) (*Product, error) {",
+		`"SELECT * FROM \"products\" WHERE \"id\" = ?"`,
+		"func ListTags(ctx context.Context, p warlot.Project) ([]Tag, error) {",
+		"func InsertTag(",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "func GetTag(") {
+		t.Error("key helpers generated for a table without a primary key")
+	}
+
+	if _, err := Generate([]Table{{Name: "a_b"}, {Name: "a_bs"}}, Options{}); err == nil {
+		t.Fatal("expected type name collision error")
+	}
+}
+
+// gentestDDL is the schema behind gentest/models.go, whose tests run the
+// generated helpers against the SQLite-backed fake server.
+const gentestDDL = `CREATE TABLE flags (id INTEGER PRIMARY KEY, name TEXT NOT NULL, enabled BOOLEAN NOT NULL DEFAULT 0, archived BOOLEAN)`
+
+func TestGenerate_GentestUpToDate(t *testing.T) {
+	tbl, err := TableFromSchema("flags", &warlot.TableSchema{SQL: gentestDDL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate([]Table{tbl}, Options{Package: "gentest", CRUD: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("WARLOT_UPDATE_GENTEST") == "1" {
+		if err := os.WriteFile(filepath.Join("gentest", "models.go"), src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	have, err := os.ReadFile(filepath.Join("gentest", "models.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(have) != string(src) {
+		t.Fatalf("gentest/models.go is stale; rerun with WARLOT_UPDATE_GENTEST=1:\n%s", src)
+	}
+}
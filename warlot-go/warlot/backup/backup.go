@@ -0,0 +1,575 @@
+// Package backup saves a project's tables to a directory and restores
+// them into another project.
+//
+// A backup holds backup.json, describing every table (its CREATE
+// statement, columns, indexes, triggers, AUTOINCREMENT counter and
+// foreign key references) and the project's views, and one JSON Lines
+// file of rows per table under tables/. The _migrations ledger is
+// included, so a restored project reports the same applied migrations.
+// Schema objects a backup cannot carry are listed in Manifest.Skipped.
+//
+//	m, err := backup.Create(ctx, prod, "backups/2024-05-01", backup.Options{})
+//	...
+//	_, err = backup.Restore(ctx, fresh, "backups/2024-05-01", backup.RestoreOptions{Workers: 4})
+//
+// Tables are read one SELECT stream at a time, so a backup of a project
+// taking writes is not a point-in-time snapshot.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/export"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/ingest"
+)
+
+// ManifestFile is the name of the manifest inside a backup directory.
+const ManifestFile = "backup.json"
+
+// Version is the manifest format written by Create. Restore reads this
+// version and earlier ones.
+const Version = 2
+
+var (
+	// ErrChecksum is returned by Restore when a data file does not match
+	// the manifest.
+	ErrChecksum = errors.New("backup: data file checksum mismatch")
+	// ErrNotEmpty is returned by Restore when the target project already
+	// has a table from the backup that the restore did not create.
+	ErrNotEmpty = errors.New("backup: target project already has tables from the backup")
+	// ErrCheckpointMismatch is returned by Restore when its checkpoint
+	// was written for another backup or target project.
+	ErrCheckpointMismatch = errors.New("backup: restore checkpoint is for another backup or project")
+)
+
+// Manifest describes a backup.
+type Manifest struct {
+	Version   int       `json:"version"`
+	ProjectID string    `json:"project_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []Table   `json:"tables"`
+
+	// Objects holds views and the triggers defined on them, in creation
+	// order. They are restored after every table.
+	Objects []Object `json:"objects,omitempty"`
+	// Skipped lists schema objects that are not in the backup and will
+	// not be restored.
+	Skipped []Skipped `json:"skipped,omitempty"`
+}
+
+// Object is a view or a trigger on a view.
+type Object struct {
+	Type string `json:"type"` // "view" or "trigger"
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+// Skipped is a schema object left out of a backup.
+type Skipped struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Table is one table in a backup. File is relative to the backup
+// directory.
+type Table struct {
+	Name       string   `json:"name"`
+	SQL        string   `json:"sql"`
+	Columns    []string `json:"columns"`
+	Indexes    []string `json:"indexes,omitempty"`    // CREATE INDEX statements
+	Triggers   []string `json:"triggers,omitempty"`   // CREATE TRIGGER statements
+	Sequence   int64    `json:"sequence,omitempty"`   // AUTOINCREMENT counter
+	References []string `json:"references,omitempty"` // tables named by foreign keys
+	File       string   `json:"file"`
+	Rows       int64    `json:"rows"`
+	Bytes      int64    `json:"bytes"`
+	SHA256     string   `json:"sha256"`
+}
+
+// Options configures Create.
+type Options struct {
+	Tables      []string // tables to save; default all, including _migrations
+	Workers     int      // tables saved concurrently; default 1
+	CallOptions []warlot.CallOption
+}
+
+// Create writes a backup of p to dir, creating it if needed. The manifest
+// is written last, so a directory without one is an incomplete backup.
+// Indexes and triggers are saved with their table as stored in
+// sqlite_master; views are saved only when every table is.
+func Create(ctx context.Context, p warlot.Project, dir string, opts Options) (*Manifest, error) {
+	names := opts.Tables
+	all := len(names) == 0
+	if all {
+		lt, err := p.Tables(ctx, opts.CallOptions...)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range lt.Tables {
+			if !strings.HasPrefix(t, "sqlite_") && t != "_migrations_lock" {
+				names = append(names, t)
+			}
+		}
+		sort.Strings(names)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "tables"), 0o755); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Version: Version, ProjectID: p.ID, Tables: make([]Table, len(names))}
+	sch, err := loadObjects(ctx, p, names, all, m, opts.CallOptions)
+	if err != nil {
+		return nil, err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, workers)
+	)
+	for i, name := range names {
+		wg.Add(1)
+		go func(t *Table, i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := saveTable(ctx, p, dir, i, name, t, opts.CallOptions); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("backup %s: %w", name, err)
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(&m.Tables[i], i, name)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	for i := range m.Tables {
+		t := &m.Tables[i]
+		key := strings.ToLower(t.Name)
+		t.Indexes, t.Triggers, t.Sequence = sch.indexes[key], sch.triggers[key], sch.sequences[key]
+	}
+
+	m.CreatedAt = time.Now().UTC()
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(b, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func saveTable(ctx context.Context, p warlot.Project, dir string, i int, name string, t *Table, opts []warlot.CallOption) (err error) {
+	s, err := p.Schema(ctx, name, opts...)
+	if err != nil {
+		return err
+	}
+	if s.SQL == "" {
+		return errors.New("schema has no CREATE statement")
+	}
+	*t = Table{Name: name, SQL: s.SQL, File: fmt.Sprintf("tables/%03d_%s.jsonl", i, fileSafe(name))}
+	for _, c := range s.Columns {
+		t.Columns = append(t.Columns, c.Name)
+	}
+	seen := map[string]bool{}
+	for _, fk := range s.ForeignKeys {
+		if ref := fk.RefTable; ref != "" && !strings.EqualFold(ref, name) && !seen[strings.ToLower(ref)] {
+			seen[strings.ToLower(ref)] = true
+			t.References = append(t.References, ref)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, t.File))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(f, h)}
+	t.Rows, err = export.ToWriter(ctx, p, cw, export.Options{
+		Table:       name,
+		Format:      export.JSONL,
+		Columns:     t.Columns,
+		CallOptions: opts,
+	})
+	if err != nil {
+		return err
+	}
+	t.Bytes = cw.n
+	t.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// tableObjects holds the statements and counters saved with each table,
+// keyed by lower-case table name.
+type tableObjects struct {
+	indexes   map[string][]string
+	triggers  map[string][]string
+	sequences map[string]int64
+}
+
+// loadObjects reads sqlite_master for the indexes and triggers of the
+// tables in names, records views in m.Objects when all is set, and lists
+// everything else it cannot save in m.Skipped. Indexes backing PRIMARY
+// KEY and UNIQUE constraints have no SQL of their own and are recreated
+// by their table's statement.
+func loadObjects(ctx context.Context, p warlot.Project, names []string, all bool, m *Manifest, opts []warlot.CallOption) (*tableObjects, error) {
+	type object struct {
+		Type  string `json:"type"`
+		Name  string `json:"name"`
+		Table string `json:"tbl_name"`
+		SQL   string `json:"sql"`
+	}
+	objs, err := warlot.Query[object](ctx, p, `SELECT type, name, tbl_name, sql FROM sqlite_master
+WHERE sql IS NOT NULL ORDER BY rowid`, nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("backup: read sqlite_master: %w", err)
+	}
+	selected := map[string]bool{}
+	for _, n := range names {
+		selected[strings.ToLower(n)] = true
+	}
+	views := map[string]bool{}
+	sch := &tableObjects{indexes: map[string][]string{}, triggers: map[string][]string{}, sequences: map[string]int64{}}
+	hasSequence := false
+	for _, o := range objs {
+		table := strings.ToLower(o.Table)
+		switch {
+		case o.Type == "table" && o.Name == "sqlite_sequence":
+			hasSequence = true
+		case o.Type == "table" && strings.HasPrefix(o.Name, "sqlite_"):
+			m.Skipped = append(m.Skipped, Skipped{Type: o.Type, Name: o.Name, Reason: "SQLite internal table"})
+		case o.Type == "view" || (o.Type == "trigger" && views[table]):
+			if o.Type == "view" {
+				views[strings.ToLower(o.Name)] = true
+			}
+			if !all {
+				m.Skipped = append(m.Skipped, Skipped{Type: o.Type, Name: o.Name, Reason: "views are saved only when every table is"})
+				continue
+			}
+			m.Objects = append(m.Objects, Object{Type: o.Type, Name: o.Name, SQL: o.SQL})
+		case !selected[table]:
+		case o.Type == "index":
+			sch.indexes[table] = append(sch.indexes[table], o.SQL)
+		case o.Type == "trigger":
+			sch.triggers[table] = append(sch.triggers[table], o.SQL)
+		}
+	}
+	if !hasSequence {
+		return sch, nil
+	}
+	type sequence struct {
+		Name string `json:"name"`
+		Seq  int64  `json:"seq"`
+	}
+	seqs, err := warlot.Query[sequence](ctx, p, `SELECT name, seq FROM sqlite_sequence`, nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("backup: read sqlite_sequence: %w", err)
+	}
+	for _, sq := range seqs {
+		if selected[strings.ToLower(sq.Name)] {
+			sch.sequences[strings.ToLower(sq.Name)] = sq.Seq
+		}
+	}
+	return sch, nil
+}
+
+// ReadManifest loads the manifest of the backup in dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("backup: %s: %w", ManifestFile, err)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return nil, fmt.Errorf("backup: unsupported manifest version %d", m.Version)
+	}
+	return &m, nil
+}
+
+// Verify checks every data file in dir against the manifest.
+func Verify(dir string, m *Manifest) error {
+	for _, t := range m.Tables {
+		f, err := os.Open(filepath.Join(dir, t.File))
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if n != t.Bytes || hex.EncodeToString(h.Sum(nil)) != t.SHA256 {
+			return fmt.Errorf("%w: %s", ErrChecksum, t.File)
+		}
+	}
+	return nil
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	Workers int     // insert batches in flight per table; default 1
+	Rate    float64 // insert batches per second; 0 means unlimited
+	// Checkpoint is a file recording each finished step, with the rows
+	// of a table being loaded tracked in files beside it. A later
+	// Restore of the same backup into the same project resumes from it.
+	// It is removed when the restore finishes.
+	Checkpoint  string
+	CallOptions []warlot.CallOption
+}
+
+// Restore recreates the tables of the backup in dir in p and reloads their
+// rows. Data files are verified first, and p must not already contain any
+// of the tables, except those an interrupted run recorded in
+// opts.Checkpoint as created. Tables are created parents first, following
+// foreign keys; indexes, triggers and the AUTOINCREMENT counter follow
+// once a table's rows are loaded, and views come last.
+func Restore(ctx context.Context, p warlot.Project, dir string, opts RestoreOptions) (*Manifest, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(dir, m); err != nil {
+		return nil, err
+	}
+	st := &restoreState{ProjectID: p.ID, BackupProjectID: m.ProjectID, BackupCreatedAt: m.CreatedAt, Done: map[string]bool{}}
+	if opts.Checkpoint != "" {
+		prev, err := readRestoreState(opts.Checkpoint)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		case prev.ProjectID != st.ProjectID || prev.BackupProjectID != st.BackupProjectID || !prev.BackupCreatedAt.Equal(st.BackupCreatedAt):
+			return nil, fmt.Errorf("%w: %s", ErrCheckpointMismatch, opts.Checkpoint)
+		default:
+			st = prev
+		}
+	}
+	step := func(key string, fn func() error) error {
+		if st.Done[key] {
+			return nil
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		st.Done[key] = true
+		if opts.Checkpoint == "" {
+			return nil
+		}
+		return st.write(opts.Checkpoint)
+	}
+
+	lt, err := p.Tables(ctx, opts.CallOptions...)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, t := range lt.Tables {
+		existing[strings.ToLower(t)] = true
+	}
+	var clash []string
+	for _, t := range m.Tables {
+		if existing[strings.ToLower(t.Name)] && !st.Done["table:"+strings.ToLower(t.Name)] {
+			clash = append(clash, t.Name)
+		}
+	}
+	if len(clash) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotEmpty, strings.Join(clash, ", "))
+	}
+
+	exec := func(sql string, params []any) func() error {
+		return func() error {
+			_, err := p.SQL(ctx, sql, params, opts.CallOptions...)
+			return err
+		}
+	}
+	for _, t := range createOrder(m.Tables) {
+		key := strings.ToLower(t.Name)
+		if err := step("table:"+key, exec(t.SQL, nil)); err != nil {
+			return nil, fmt.Errorf("restore %s: create: %w", t.Name, err)
+		}
+		if err := step("rows:"+key, func() error { return loadRows(ctx, p, dir, t, opts) }); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", t.Name, err)
+		}
+		for i, ix := range t.Indexes {
+			if err := step(fmt.Sprintf("index:%s:%d", key, i), exec(ix, nil)); err != nil {
+				return nil, fmt.Errorf("restore %s: index: %w", t.Name, err)
+			}
+		}
+		for i, tr := range t.Triggers {
+			if err := step(fmt.Sprintf("trigger:%s:%d", key, i), exec(tr, nil)); err != nil {
+				return nil, fmt.Errorf("restore %s: trigger: %w", t.Name, err)
+			}
+		}
+		if t.Sequence > 0 {
+			err := step("sequence:"+key, func() error {
+				if err := exec(`UPDATE sqlite_sequence SET seq = MAX(seq, ?) WHERE name = ?`, []any{t.Sequence, t.Name})(); err != nil {
+					return err
+				}
+				return exec(`INSERT INTO sqlite_sequence (name, seq) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = ?)`,
+					[]any{t.Name, t.Sequence, t.Name})()
+			})
+			if err != nil {
+				return nil, fmt.Errorf("restore %s: sequence: %w", t.Name, err)
+			}
+		}
+	}
+	for _, o := range m.Objects {
+		if err := step(o.Type+":"+strings.ToLower(o.Name), exec(o.SQL, nil)); err != nil {
+			return nil, fmt.Errorf("restore %s %s: %w", o.Type, o.Name, err)
+		}
+	}
+	if opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// loadRows inserts the rows of t. With a restore checkpoint, progress is
+// kept in an import checkpoint beside it so a rerun resumes mid-table.
+func loadRows(ctx context.Context, p warlot.Project, dir string, t *Table, opts RestoreOptions) error {
+	if t.Rows == 0 {
+		return nil
+	}
+	cols := make(map[string]string, len(t.Columns))
+	for _, c := range t.Columns {
+		cols[c] = c
+	}
+	in := ingest.Options{
+		Table:       t.Name,
+		Format:      ingest.JSONL,
+		Columns:     cols,
+		Workers:     opts.Workers,
+		Rate:        opts.Rate,
+		CallOptions: opts.CallOptions,
+	}
+	if opts.Checkpoint != "" {
+		in.Checkpoint = opts.Checkpoint + "." + strings.TrimSuffix(filepath.Base(t.File), ".jsonl")
+	}
+	_, err := ingest.FromFile(ctx, p, filepath.Join(dir, t.File), in)
+	return err
+}
+
+// restoreState is the checkpoint of a restore: the target project, the
+// backup it came from, and the steps already applied.
+type restoreState struct {
+	ProjectID       string          `json:"project_id"`
+	BackupProjectID string          `json:"backup_project_id"`
+	BackupCreatedAt time.Time       `json:"backup_created_at"`
+	Done            map[string]bool `json:"done"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+func readRestoreState(path string) (*restoreState, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st restoreState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("backup: checkpoint %s: %w", path, err)
+	}
+	if st.Done == nil {
+		st.Done = map[string]bool{}
+	}
+	return &st, nil
+}
+
+// write replaces the checkpoint file atomically.
+func (st *restoreState) write(path string) error {
+	st.UpdatedAt = time.Now().UTC()
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// createOrder sorts tables so referenced tables come first. Tables in a
+// reference cycle keep manifest order.
+func createOrder(tables []Table) []*Table {
+	byName := map[string]*Table{}
+	for i := range tables {
+		byName[strings.ToLower(tables[i].Name)] = &tables[i]
+	}
+	var out []*Table
+	state := map[*Table]int{} // 1 visiting, 2 done
+	var visit func(t *Table)
+	visit = func(t *Table) {
+		if state[t] != 0 {
+			return
+		}
+		state[t] = 1
+		for _, ref := range t.References {
+			if dep := byName[strings.ToLower(ref)]; dep != nil {
+				visit(dep)
+			}
+		}
+		state[t] = 2
+		out = append(out, t)
+	}
+	for i := range tables {
+		visit(&tables[i])
+	}
+	return out
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// fileSafe maps a table name to a portable file name fragment.
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,233 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/export"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/warlottest"
+)
+
+var migrations = fstest.MapFS{
+	"m/001_orders.up.sql": {Data: []byte(`
+CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id), total REAL, note TEXT);
+CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL, big INTEGER);
+`)},
+	"m/002_seed.up.sql": {Data: []byte(`
+INSERT INTO customers (id, name, big) VALUES (1, 'ada', 9007199254740993), (2, 'grace', NULL);
+INSERT INTO orders (customer_id, total, note) VALUES (1, 10.25, 'first, "quoted"'), (2, 3, NULL), (1, 0.1, '');
+`)},
+	"m/003_objects.up.sql": {Data: []byte(`
+CREATE TABLE audit (id INTEGER PRIMARY KEY AUTOINCREMENT, msg TEXT);
+CREATE INDEX orders_noted ON orders (customer_id) WHERE note IS NOT NULL;
+CREATE INDEX customers_lower ON customers (lower(name));
+CREATE TRIGGER orders_audit AFTER INSERT ON orders BEGIN INSERT INTO audit (msg) VALUES ('order ' || NEW.id); END;
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 5;
+INSERT INTO audit (msg) VALUES ('a'), ('b'), ('c');
+DELETE FROM audit WHERE msg = 'c';
+ANALYZE;
+`)},
+}
+
+// objects lists the schema objects of p other than the migrations lock.
+func objects(t *testing.T, ctx context.Context, p warlot.Project) string {
+	t.Helper()
+	type object struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		SQL  string `json:"sql"`
+	}
+	objs, err := warlot.Query[object](ctx, p, `SELECT type, name, sql FROM sqlite_master
+WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_stat%' AND tbl_name != '_migrations_lock' ORDER BY type, name`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, o := range objs {
+		fmt.Fprintf(&b, "%s %s: %s\n", o.Type, o.Name, o.SQL)
+	}
+	return b.String()
+}
+
+func dump(t *testing.T, ctx context.Context, p warlot.Project, table string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := export.ToWriter(ctx, p, &buf, export.Options{Table: table, Format: export.JSONL}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCreateRestore(t *testing.T) {
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srcID, _ := srv.CreateProject("0xholder", "prod")
+	src := srv.Client().Project(srcID)
+	if _, err := warlot.Migrate.Up(ctx, src, migrations, "m"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	m, err := Create(ctx, src, dir, Options{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tb := range m.Tables {
+		names = append(names, tb.Name)
+	}
+	if strings.Join(names, ",") != "_migrations,audit,customers,orders" || m.Tables[3].Rows != 3 {
+		t.Fatalf("manifest tables: %+v", m.Tables)
+	}
+	if m.Tables[1].Sequence != 3 || len(m.Tables[3].Triggers) != 1 || len(m.Objects) != 1 ||
+		len(m.Skipped) != 1 || m.Skipped[0].Name != "sqlite_stat1" {
+		t.Fatalf("manifest objects: %+v", m)
+	}
+
+	dstID, _ := srv.CreateProject("0xholder", "restored")
+	dst := srv.Client().Project(dstID)
+	if _, err := Restore(ctx, dst, dir, RestoreOptions{Workers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range names {
+		if got, want := dump(t, ctx, dst, table), dump(t, ctx, src, table); got != want {
+			t.Fatalf("%s differs:\n got %s\nwant %s", table, got, want)
+		}
+	}
+	for _, q := range []string{`SELECT * FROM big_orders`, `SELECT * FROM sqlite_sequence`} {
+		got, err1 := dst.SQL(ctx, q, nil)
+		want, err2 := src.SQL(ctx, q, nil)
+		if err := errors.Join(err1, err2); err != nil || fmt.Sprint(got.Rows) != fmt.Sprint(want.Rows) {
+			t.Fatalf("%s: got %v, want %v (err %v)", q, got, want, err)
+		}
+	}
+	if got, want := objects(t, ctx, dst), objects(t, ctx, src); got != want {
+		t.Fatalf("schema objects differ:\n got %s\nwant %s", got, want)
+	}
+	st, err := warlot.Migrate.Status(ctx, dst, migrations, "m")
+	if err != nil || len(st.Pending) != 0 {
+		t.Fatalf("migrations after restore: %+v err=%v", st, err)
+	}
+
+	if _, err := Restore(ctx, dst, dir, RestoreOptions{}); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("restore over existing tables: %v", err)
+	}
+
+	data := filepath.Join(dir, m.Tables[2].File)
+	b, _ := os.ReadFile(data)
+	os.WriteFile(data, bytes.Replace(b, []byte("ada"), []byte("eve"), 1), 0o644)
+	otherID, _ := srv.CreateProject("0xholder", "tampered")
+	if _, err := Restore(ctx, srv.Client().Project(otherID), dir, RestoreOptions{}); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("tampered backup: %v", err)
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	tables := []Table{
+		{Name: "lines", References: []string{"orders", "products"}},
+		{Name: "orders", References: []string{"Customers"}},
+		{Name: "products"},
+		{Name: "customers", References: []string{"customers"}},
+	}
+	var got []string
+	for _, tb := range createOrder(tables) {
+		got = append(got, tb.Name)
+	}
+	if strings.Join(got, ",") != "customers,orders,products,lines" {
+		t.Fatalf("order: %v", got)
+	}
+}
+
+func TestRestore_Resume(t *testing.T) {
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srcID, _ := srv.CreateProject("0xholder", "prod")
+	src := srv.Client().Project(srcID)
+	if _, err := warlot.Migrate.Up(ctx, src, migrations, "m"); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := Create(ctx, src, dir, Options{Tables: []string{"customers", "orders"}}); err != nil {
+		t.Fatal(err)
+	}
+	ckpt := filepath.Join(t.TempDir(), "restore.json")
+
+	// Inserting orders fails with a 503 after customers is restored.
+	dstID, _ := srv.CreateProject("0xholder", "restored")
+	dst := srv.Client().Project(dstID)
+	flaky := dst
+	flaky.Client = warlot.New(
+		warlot.WithBaseURL(dst.Client.BaseURL),
+		warlot.WithRetries(0),
+		warlot.WithHTTPClient(&http.Client{Transport: unavailableFor{`INSERT INTO \"orders\"`}}),
+	)
+	if _, err := Restore(ctx, flaky, dir, RestoreOptions{Checkpoint: ckpt}); err == nil || !strings.Contains(err.Error(), "restore orders") {
+		t.Fatalf("interrupted restore: %v", err)
+	}
+	if _, err := Restore(ctx, dst, dir, RestoreOptions{}); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("restore without the checkpoint: %v", err)
+	}
+	otherID, _ := srv.CreateProject("0xholder", "other")
+	if _, err := Restore(ctx, srv.Client().Project(otherID), dir, RestoreOptions{Checkpoint: ckpt}); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("checkpoint for another project: %v", err)
+	}
+
+	m, err := Restore(ctx, dst, dir, RestoreOptions{Checkpoint: ckpt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"customers", "orders"} {
+		if got, want := dump(t, ctx, dst, table), dump(t, ctx, src, table); got != want {
+			t.Fatalf("%s differs:\n got %s\nwant %s", table, got, want)
+		}
+	}
+	if len(m.Skipped) != 2 || m.Skipped[0].Name != "big_orders" {
+		t.Fatalf("skipped: %+v", m.Skipped)
+	}
+	if _, err := os.Stat(ckpt); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint not removed: %v", err)
+	}
+}
+
+// unavailableFor answers 503 to requests whose body contains match and
+// forwards the rest.
+type unavailableFor struct{ match string }
+
+func (u unavailableFor) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body == nil {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(body, []byte(u.match)) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"unavailable"}`)),
+			Request:    r,
+		}, nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return http.DefaultTransport.RoundTrip(r)
+}
@@ -0,0 +1,104 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
+)
+
+// ErrEmptyBatch is returned when a batch contains no statements.
+var ErrEmptyBatch = errors.New("warlot: batch has no statements")
+
+// Batch executes several statements in a single request, wrapped in
+// BEGIN/COMMIT so they apply atomically. Parameters are flattened in
+// statement order, so each statement keeps its own positional placeholders.
+//
+// When no idempotency key is supplied, a random key is generated for the
+// call so a retried batch is not applied twice.
+//
+// The script carries no ROLLBACK of its own: execution stops at the
+// failing statement, so the server must discard the open transaction. When
+// the API reports an error, Batch also sends a best-effort ROLLBACK in a
+// separate request and returns the original error.
+func (p Project) Batch(ctx context.Context, stmts []SQLRequest, opts ...CallOption) (*SQLResponse, error) {
+	if len(stmts) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if !hasIdempotencyKey(opts) {
+		opts = append(opts, WithIdempotencyKey("batch-"+newIdempotencyKey()))
+	}
+	res, err := p.Client.ExecSQL(ctx, p.ID, batchRequest(stmts), opts...)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// The batch key would replay the failed response, so the rollback
+		// gets its own. Its error is ignored: "no transaction is active"
+		// means the server already rolled back.
+		rollback := append(opts[:len(opts):len(opts)], WithIdempotencyKey("rollback-"+newIdempotencyKey()))
+		_, _ = p.Client.ExecSQL(ctx, p.ID, SQLRequest{SQL: "ROLLBACK;"}, rollback...)
+	}
+	return res, err
+}
+
+// Tx collects statements for Project.Tx. Statements are buffered on the
+// client and sent together when the callback returns; reads issued inside
+// the callback through other APIs are not part of the transaction.
+type Tx struct {
+	stmts []SQLRequest
+}
+
+// Exec queues a statement for the transaction.
+func (tx *Tx) Exec(sql string, params ...any) {
+	tx.stmts = append(tx.stmts, SQLRequest{SQL: sql, Params: params})
+}
+
+// Statements returns the queued statements.
+func (tx *Tx) Statements() []SQLRequest { return tx.stmts }
+
+// Tx runs fn to collect statements and then executes them atomically via
+// Batch. If fn returns an error nothing is sent and the error is returned.
+// A callback that queues no statements is a no-op.
+func (p Project) Tx(ctx context.Context, fn func(tx *Tx) error, opts ...CallOption) error {
+	tx := &Tx{}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.stmts) == 0 {
+		return nil
+	}
+	_, err := p.Batch(ctx, tx.stmts, opts...)
+	return err
+}
+
+// batchRequest joins statements into a single BEGIN/COMMIT script.
+func batchRequest(stmts []SQLRequest) SQLRequest {
+	var sb strings.Builder
+	var params []any
+	sb.WriteString("BEGIN;\n")
+	for _, s := range stmts {
+		sb.WriteString(trimStatement(s.SQL))
+		sb.WriteString(";\n")
+		params = append(params, s.Params...)
+	}
+	sb.WriteString("COMMIT;")
+	return SQLRequest{SQL: sb.String(), Params: params}
+}
+
+// trimStatement cuts sql after its last token, dropping trailing
+// semicolons, whitespace and comments so that a terminator appended to it
+// cannot land inside a line comment. SQL that does not tokenize is kept
+// whole and ends with a newline instead.
+func trimStatement(sql string) string {
+	toks, err := sqlparse.Tokenize(sql)
+	if err != nil {
+		return strings.TrimSpace(sql) + "\n"
+	}
+	end := 0
+	for _, t := range toks {
+		if t.Kind != sqlparse.TokEOF && !(t.Kind == sqlparse.TokOp && t.Text == ";") {
+			end = t.End
+		}
+	}
+	return sql[:end]
+}
@@ -0,0 +1,130 @@
+package warlot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatch_SingleRequest_StableIdempotencyKey(t *testing.T) {
+	var calls int32
+	var keys []string
+	var got SQLRequest
+
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("x-idempotency-key"))
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, `{"error":"boom"}`, http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(2)})
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cl.Project("p").Batch(ctx, []SQLRequest{
+		{SQL: "INSERT INTO a (x) VALUES (?);", Params: []any{"1"}},
+		{SQL: "UPDATE b SET y = ? WHERE id = ? -- by id\n", Params: []any{"2", "3"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN;\nINSERT INTO a (x) VALUES (?);\nUPDATE b SET y = ? WHERE id = ?;\nCOMMIT;"
+	if got.SQL != want {
+		t.Fatalf("sql=%q", got.SQL)
+	}
+	if len(got.Params) != 3 || got.Params[0] != "1" || got.Params[2] != "3" {
+		t.Fatalf("params=%v", got.Params)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("idempotency keys across retries: %v", keys)
+	}
+
+	// An explicit key is preserved.
+	keys = nil
+	if _, err := cl.Project("p").Batch(ctx, []SQLRequest{{SQL: "DELETE FROM a"}}, WithIdempotencyKey("mine")); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "mine" {
+		t.Fatalf("keys=%v", keys)
+	}
+
+	if _, err := cl.Project("p").Batch(ctx, nil); !errors.Is(err, ErrEmptyBatch) {
+		t.Fatalf("empty batch err=%v", err)
+	}
+}
+
+func TestTx_CommitAndRollback(t *testing.T) {
+	var sqls []string
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var req SQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sqls = append(sqls, req.SQL)
+		json.NewEncoder(w).Encode(SQLResponse{OK: true})
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	errAbort := errors.New("abort")
+	err := proj.Tx(ctx, func(tx *Tx) error {
+		tx.Exec("INSERT INTO a (x) VALUES (?)", 1)
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) || len(sqls) != 0 {
+		t.Fatalf("rollback: err=%v sent=%v", err, sqls)
+	}
+
+	err = proj.Tx(ctx, func(tx *Tx) error {
+		tx.Exec("INSERT INTO a (x) VALUES (?)", 1)
+		tx.Exec("INSERT INTO a (x) VALUES (?)", 2)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqls) != 1 || strings.Count(sqls[0], "INSERT") != 2 || !strings.HasSuffix(sqls[0], "COMMIT;") {
+		t.Fatalf("commit sent=%v", sqls)
+	}
+}
+
+func TestBatch_RollsBackOnFailure(t *testing.T) {
+	var sqls, keys []string
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var req SQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sqls = append(sqls, req.SQL)
+		keys = append(keys, r.Header.Get("x-idempotency-key"))
+		if strings.HasPrefix(req.SQL, "BEGIN") {
+			http.Error(w, `{"error":"UNIQUE constraint failed: a.x"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true})
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cl.Project("p").Batch(ctx, []SQLRequest{{SQL: "INSERT INTO a (x) VALUES (1)"}}, WithIdempotencyKey("mine"))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err=%v", err)
+	}
+	if len(sqls) != 2 || sqls[1] != "ROLLBACK;" {
+		t.Fatalf("sent=%q", sqls)
+	}
+	if keys[0] != "mine" || keys[1] == "" || keys[1] == "mine" {
+		t.Fatalf("keys=%v", keys)
+	}
+}
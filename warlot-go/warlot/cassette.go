@@ -0,0 +1,305 @@
+package warlot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CassetteMode selects whether a Cassette records or replays interactions.
+type CassetteMode int
+
+const (
+	// CassetteReplay serves recorded responses and fails requests that have
+	// no unused matching interaction. No network access is performed.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord forwards requests to the network and records them.
+	CassetteRecord
+	// CassetteAuto replays when the cassette file exists and records otherwise.
+	CassetteAuto
+)
+
+// ErrNoInteraction is returned in replay mode when a request has no unused
+// matching interaction in the cassette.
+var ErrNoInteraction = errors.New("warlot: no matching cassette interaction")
+
+// Interaction is one recorded request/response pair. API keys are replaced
+// by "REDACTED" in headers and in apiKey fields anywhere in bodies before
+// they are stored.
+type Interaction struct {
+	Method          string      `json:"method"`
+	Path            string      `json:"path"`
+	Query           string      `json:"query,omitempty"`
+	RequestHeaders  http.Header `json:"request_headers,omitempty"`
+	RequestBody     string      `json:"request_body,omitempty"`
+	Status          int         `json:"status"`
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	ResponseBody    string      `json:"response_body,omitempty"`
+}
+
+// Cassette is an http.RoundTripper that records Warlot interactions to a
+// JSON file and replays them deterministically. Plug it in through
+// WithHTTPClient:
+//
+//	cas, err := warlot.OpenCassette("testdata/e2e.cassette.json", warlot.CassetteAuto)
+//	cl := warlot.New(warlot.WithHTTPClient(cas.Client()))
+//	defer cas.Save()
+//
+// Requests match on method, path, query parameters and, for SQL requests,
+// the statement and parameters. Matching interactions are served in
+// recorded order, so a recorded 429 followed by a 200 replays the same
+// retry sequence.
+type Cassette struct {
+	// Path is the cassette file.
+	Path string
+
+	// Transport performs real requests while recording. Nil means
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// MatchParams, when set, decides whether a recorded SQL request with
+	// the same statement matches a replayed one, given both parameter
+	// lists (numbers as json.Number). Use it for parameters that change
+	// between runs, such as timestamps. Nil requires equal parameters.
+	// Values that are also read back, like the Migrator's lock owner, must
+	// be fixed instead (Migrator.LockOwner), since the recorded response
+	// holds the old value.
+	MatchParams func(sql string, recorded, actual []any) bool
+
+	mode CassetteMode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// OpenCassette loads the cassette at path for replay, or prepares an empty
+// one for recording. In CassetteAuto mode the mode is chosen by whether the
+// file exists.
+func OpenCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{Path: path, mode: mode}
+	if mode == CassetteRecord {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && mode == CassetteAuto {
+		c.mode = CassetteRecord
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	if err := json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, fmt.Errorf("decode cassette %s: %w", path, err)
+	}
+	c.mode = CassetteReplay
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Mode reports whether the cassette is recording or replaying.
+func (c *Cassette) Mode() CassetteMode { return c.mode }
+
+// Client returns an *http.Client that uses the cassette as its transport.
+func (c *Cassette) Client() *http.Client { return &http.Client{Transport: c} }
+
+// Interactions returns a copy of the recorded or loaded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Save writes recorded interactions to Path. It is a no-op in replay mode.
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+	c.mu.Lock()
+	b, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+	if err := os.WriteFile(c.Path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.mode == CassetteReplay {
+		return c.replay(req, body)
+	}
+	return c.record(req, body)
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	sql, params := cassetteSQL(body)
+	query := cassetteQueryKey(req.URL.RawQuery)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, it := range c.interactions {
+		if c.used[i] || !strings.EqualFold(it.Method, req.Method) || it.Path != req.URL.Path {
+			continue
+		}
+		if cassetteQueryKey(it.Query) != query {
+			continue
+		}
+		if !c.matchBody(sql, params, []byte(it.RequestBody)) {
+			continue
+		}
+		c.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+			StatusCode:    it.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        it.ResponseHeaders.Clone(),
+			Body:          io.NopCloser(strings.NewReader(it.ResponseBody)),
+			ContentLength: int64(len(it.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL.RequestURI())
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, Interaction{
+		Method:          req.Method,
+		Path:            req.URL.Path,
+		Query:           req.URL.RawQuery,
+		RequestHeaders:  redactHeaders(req.Header, true),
+		RequestBody:     string(redactBody(body)),
+		Status:          res.StatusCode,
+		ResponseHeaders: redactHeaders(res.Header, true),
+		ResponseBody:    string(redactBody(resBody)),
+	})
+	c.used = append(c.used, false)
+	c.mu.Unlock()
+	return res, nil
+}
+
+// matchBody reports whether a recorded request body matches the SQL
+// statement and parameters of a replayed one. Bodies that are not SQL
+// requests match each other.
+func (c *Cassette) matchBody(sql string, params []any, recorded []byte) bool {
+	recSQL, recParams := cassetteSQL(recorded)
+	if recSQL != sql {
+		return false
+	}
+	if sql == "" {
+		return true
+	}
+	if c.MatchParams != nil {
+		return c.MatchParams(sql, recParams, params)
+	}
+	a, _ := json.Marshal(recParams)
+	b, _ := json.Marshal(params)
+	return bytes.Equal(a, b)
+}
+
+// cassetteSQL returns the statement and parameters of a SQL request body,
+// or "" for any other body.
+func cassetteSQL(body []byte) (string, []any) {
+	var req struct {
+		SQL    string `json:"sql"`
+		Params []any  `json:"params"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if dec.Decode(&req) != nil {
+		return "", nil
+	}
+	return req.SQL, req.Params
+}
+
+// cassetteQueryKey is the query part of the match key: the parameters in
+// canonical order, so recordings match regardless of encoding order.
+func cassetteQueryKey(raw string) string {
+	v, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+	return v.Encode()
+}
+
+// cassetteRedacted replaces secrets in stored interactions. Unlike log
+// redaction it keeps no part of the value, since cassettes are committed.
+const cassetteRedacted = "REDACTED"
+
+// redactBody replaces apiKey (or api_key) string fields at any depth of a
+// JSON body. Other bodies are returned unchanged.
+func redactBody(b []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) != nil || !redactJSON(v) {
+		return b
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// redactJSON replaces API key fields in a decoded JSON value in place and
+// reports whether it changed anything.
+func redactJSON(v any) bool {
+	changed := false
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			if _, ok := e.(string); ok && strings.EqualFold(strings.ReplaceAll(k, "_", ""), "apikey") {
+				x[k] = cassetteRedacted
+				changed = true
+				continue
+			}
+			changed = redactJSON(e) || changed
+		}
+	case []any:
+		for _, e := range x {
+			changed = redactJSON(e) || changed
+		}
+	}
+	return changed
+}
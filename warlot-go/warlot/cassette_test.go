@@ -0,0 +1,169 @@
+package warlot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCassette_RecordReplay(t *testing.T) {
+	var hits int32
+	srv, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/auth/issue":
+			json.NewEncoder(w).Encode(IssueKeyResponse{APIKey: "wk_secret_0123456789", URL: "u"})
+		case n == 2:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"rows":[]}`))
+		default:
+			var req SQLRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(map[string]any{"ok": true, "rows": []map[string]any{{"sql": req.SQL}}})
+		}
+	})
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	run := func(cas *Cassette) []string {
+		t.Helper()
+		cl := New(WithBaseURL(srv.URL), WithHTTPClient(cas.Client()), WithBackoff(time.Millisecond, time.Millisecond))
+		iss, err := cl.IssueAPIKey(ctx, IssueKeyRequest{ProjectID: "p"})
+		if err != nil {
+			t.Fatalf("issue: %v", err)
+		}
+		cl.APIKey = iss.APIKey
+		if _, err := cl.BrowseRows(ctx, "p", "t", 10, 0); err != nil {
+			t.Fatalf("browse page 1: %v", err)
+		}
+		if _, err := cl.BrowseRows(ctx, "p", "t", 10, 10); err != nil {
+			t.Fatalf("browse page 2: %v", err)
+		}
+		var got []string
+		for _, q := range []string{"SELECT 1", "SELECT 2"} {
+			res, err := cl.ExecSQL(ctx, "p", SQLRequest{SQL: q, Params: []any{1}})
+			if err != nil {
+				t.Fatalf("%s: %v", q, err)
+			}
+			got = append(got, res.Rows[0]["sql"].(string))
+		}
+		return got
+	}
+
+	rec, err := OpenCassette(path, CassetteAuto)
+	if err != nil || rec.Mode() != CassetteRecord {
+		t.Fatalf("open for record: mode=%v err=%v", rec.Mode(), err)
+	}
+	recorded := run(rec)
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "wk_s") || strings.Contains(string(raw), "6789") {
+		t.Fatalf("API key not fully redacted:\n%s", raw)
+	}
+	if len(rec.Interactions()) != 6 {
+		t.Fatalf("interactions = %d, want 6 (including the 429)", len(rec.Interactions()))
+	}
+
+	before := atomic.LoadInt32(&hits)
+	rep, err := OpenCassette(path, CassetteAuto)
+	if err != nil || rep.Mode() != CassetteReplay {
+		t.Fatalf("open for replay: mode=%v err=%v", rep.Mode(), err)
+	}
+	if got := run(rep); strings.Join(got, ",") != strings.Join(recorded, ",") {
+		t.Fatalf("replay = %v, want %v", got, recorded)
+	}
+	if atomic.LoadInt32(&hits) != before {
+		t.Fatal("replay reached the network")
+	}
+
+	cl := New(WithBaseURL(srv.URL), WithHTTPClient(rep.Client()), WithRetries(0))
+	if _, err := cl.ExecSQL(ctx, "p", SQLRequest{SQL: "SELECT 3"}); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("unmatched request: %v", err)
+	}
+
+	// Pages differ only in their query string and must not stand in for
+	// one another.
+	rep, _ = OpenCassette(path, CassetteReplay)
+	cl = New(WithBaseURL(srv.URL), WithHTTPClient(rep.Client()), WithRetries(0))
+	if _, err := cl.BrowseRows(ctx, "p", "t", 10, 20); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("unrecorded page: %v", err)
+	}
+	if _, err := cl.BrowseRows(ctx, "p", "t", 10, 10); err != nil {
+		t.Fatalf("recorded page 2: %v", err)
+	}
+}
+
+func TestCassette_RedactsNestedKeys(t *testing.T) {
+	in := []byte(`{"project":{"apiKey":"wk_secret","name":"p"},"keys":[{"api_key":"wk_other"}],"n":12345678901234567890}`)
+	out := string(redactBody(in))
+	if strings.Contains(out, "wk_") || strings.Count(out, cassetteRedacted) != 2 || !strings.Contains(out, "12345678901234567890") {
+		t.Fatalf("redactBody = %s", out)
+	}
+	if in := []byte(`{"sql":"SELECT 1"}`); string(redactBody(in)) != string(in) {
+		t.Fatalf("unchanged body rewritten: %s", redactBody(in))
+	}
+}
+
+func TestCassette_MatchParams(t *testing.T) {
+	srv, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"rows":[]}`))
+	})
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The second parameter is a timestamp that differs on every run.
+	const stmt = `INSERT INTO events (name, at) VALUES (?, ?)`
+	send := func(cas *Cassette, name string) error {
+		cl := New(WithBaseURL(srv.URL), WithHTTPClient(cas.Client()), WithRetries(0))
+		_, err := cl.ExecSQL(ctx, "p", SQLRequest{SQL: stmt, Params: []any{name, time.Now().UnixNano()}})
+		return err
+	}
+	path := filepath.Join(t.TempDir(), "events.json")
+	rec, _ := OpenCassette(path, CassetteRecord)
+	if err := send(rec, "signup"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []any
+	ignoreTime := func(sql string, recorded, actual []any) bool {
+		seen = recorded
+		return sql == stmt && len(recorded) == 2 && len(actual) == 2 && recorded[0] == actual[0]
+	}
+	for _, tc := range []struct {
+		name  string
+		match func(string, []any, []any) bool
+		ok    bool
+	}{
+		{"signup", nil, false},
+		{"signup", ignoreTime, true},
+		{"login", ignoreTime, false},
+	} {
+		rep, _ := OpenCassette(path, CassetteReplay)
+		rep.MatchParams = tc.match
+		if err := send(rep, tc.name); (err == nil) != tc.ok {
+			t.Fatalf("%s (matcher %v): err=%v", tc.name, tc.match != nil, err)
+		}
+	}
+	if _, ok := seen[1].(json.Number); !ok {
+		t.Fatalf("recorded params = %#v, want json.Number", seen)
+	}
+}
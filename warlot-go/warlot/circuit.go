@@ -0,0 +1,217 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the
+// circuit breaker for the client's base URL is open.
+var ErrCircuitOpen = errors.New("warlot: circuit breaker is open")
+
+// CircuitState is the state of one circuit.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen lets a few trial requests through to probe recovery.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops sending requests to a base URL that keeps failing.
+// After FailureThreshold consecutive failures (transport errors or 5xx
+// responses) the circuit opens and calls fail fast with ErrCircuitOpen.
+// Once OpenTimeout has passed it half-opens and lets HalfOpenRequests
+// trial requests through; if they all succeed the circuit closes, and any
+// failure opens it again.
+//
+// Circuits are kept per base URL. Set the breaker with WithCircuitBreaker;
+// state changes are logged as "circuit" events and passed to
+// OnStateChange.
+type CircuitBreaker struct {
+	FailureThreshold int           // default 5
+	OpenTimeout      time.Duration // default 30s
+	HalfOpenRequests int           // default 1
+
+	// OnStateChange, if set, is called after each transition.
+	OnStateChange func(baseURL string, from, to CircuitState)
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	trials    int // half-open requests let through
+	successes int // half-open requests that succeeded
+}
+
+type transition struct {
+	from, to CircuitState
+}
+
+// State returns the state of the circuit for baseURL.
+func (b *CircuitBreaker) State(baseURL string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(baseURL)
+	if c.state == CircuitOpen && b.clock().Sub(c.openedAt) >= b.openTimeout() {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// allow reports whether a request to baseURL may be sent, taking a trial
+// slot when the circuit is half-open.
+func (b *CircuitBreaker) allow(baseURL string) (*transition, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(baseURL)
+	var tr *transition
+	if c.state == CircuitOpen {
+		wait := b.openTimeout() - b.clock().Sub(c.openedAt)
+		if wait > 0 {
+			return nil, fmt.Errorf("%w: %s (retry in %s)", ErrCircuitOpen, baseURL, wait.Round(time.Millisecond))
+		}
+		tr = b.set(c, CircuitHalfOpen)
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials >= b.halfOpenRequests() {
+			return tr, fmt.Errorf("%w: %s (half-open)", ErrCircuitOpen, baseURL)
+		}
+		c.trials++
+	}
+	return tr, nil
+}
+
+// report records the outcome of a request allowed by allow. An outcome
+// that says nothing about the server's health, such as a cancelled
+// context, frees the trial slot and changes nothing else.
+func (b *CircuitBreaker) report(baseURL string, res *http.Response, err error) *transition {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(baseURL)
+	failed := err != nil || res.StatusCode/100 == 5
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		c.release()
+		return nil
+	}
+	switch c.state {
+	case CircuitClosed:
+		if !failed {
+			c.failures = 0
+			return nil
+		}
+		if c.failures++; c.failures >= b.failureThreshold() {
+			return b.set(c, CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			return b.set(c, CircuitOpen)
+		}
+		if c.successes++; c.successes >= b.halfOpenRequests() {
+			return b.set(c, CircuitClosed)
+		}
+	}
+	return nil
+}
+
+// release frees the trial slot taken by allow for a request that was
+// never sent.
+func (b *CircuitBreaker) release(baseURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.circuit(baseURL).release()
+}
+
+// release frees a half-open trial slot. The caller holds mu.
+func (c *circuit) release() {
+	if c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// set moves c to state to. The caller holds mu.
+func (b *CircuitBreaker) set(c *circuit, to CircuitState) *transition {
+	tr := &transition{c.state, to}
+	*c = circuit{state: to}
+	if to == CircuitOpen {
+		c.openedAt = b.clock()
+	}
+	return tr
+}
+
+// circuit returns the circuit for baseURL. The caller holds mu.
+func (b *CircuitBreaker) circuit(baseURL string) *circuit {
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+	c := b.circuits[baseURL]
+	if c == nil {
+		c = &circuit{}
+		b.circuits[baseURL] = c
+	}
+	return c
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) failureThreshold() int {
+	if b.FailureThreshold <= 0 {
+		return 5
+	}
+	return b.FailureThreshold
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	if b.OpenTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return b.OpenTimeout
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return b.HalfOpenRequests
+}
+
+// circuitChanged logs a transition and passes it to OnStateChange.
+func (c *Client) circuitChanged(baseURL string, tr *transition) {
+	if tr == nil {
+		return
+	}
+	if c.Logger != nil {
+		c.Logger("circuit", map[string]any{"base_url": baseURL, "from": tr.from.String(), "to": tr.to.String()})
+	}
+	if f := c.Breaker.OnStateChange; f != nil {
+		f(baseURL, tr.from, tr.to)
+	}
+}
@@ -0,0 +1,179 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpenHalfOpenClose(t *testing.T) {
+	var (
+		hits   int32
+		status int32 = http.StatusInternalServerError
+	)
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if s := atomic.LoadInt32(&status); s != http.StatusOK {
+			http.Error(w, `{"message":"down"}`, int(s))
+			return
+		}
+		w.Write([]byte(`{"tables":[]}`))
+	})
+	defer srv.Close()
+
+	now := time.Unix(1000, 0)
+	var changes []string
+	var logged int
+	cl.Breaker = &CircuitBreaker{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		OnStateChange: func(base string, from, to CircuitState) {
+			if base != srv.URL {
+				t.Errorf("base = %q", base)
+			}
+			changes = append(changes, fmt.Sprintf("%s->%s", from, to))
+		},
+		now: func() time.Time { return now },
+	}
+	cl.Logger = func(event string, _ map[string]any) {
+		if event == "circuit" {
+			logged++
+		}
+	}
+	ctx := context.Background()
+
+	// Retries stop as soon as the circuit opens.
+	if _, err := cl.ListTables(ctx, "x"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("first call: %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 2 || cl.Breaker.State(srv.URL) != CircuitOpen {
+		t.Fatalf("hits=%d state=%v", hits, cl.Breaker.State(srv.URL))
+	}
+
+	// Open: fail fast without contacting the server.
+	if _, err := cl.ListTables(ctx, "x"); !errors.Is(err, ErrCircuitOpen) || atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("open call: hits=%d err=%v", hits, err)
+	}
+
+	// Half-open trial fails: open again.
+	now = now.Add(time.Minute)
+	cl.RetryPolicy = NoRetry
+	if _, err := cl.ListTables(ctx, "x"); errors.Is(err, ErrCircuitOpen) || atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("trial: hits=%d err=%v", hits, err)
+	}
+	if st := cl.Breaker.State(srv.URL); st != CircuitOpen {
+		t.Fatalf("after failed trial: %v", st)
+	}
+
+	// Half-open trial succeeds: closed.
+	now = now.Add(time.Minute)
+	atomic.StoreInt32(&status, http.StatusOK)
+	if _, err := cl.ListTables(ctx, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if st := cl.Breaker.State(srv.URL); st != CircuitClosed {
+		t.Fatalf("after good trial: %v", st)
+	}
+
+	want := "closed->open open->half-open half-open->open open->half-open half-open->closed"
+	if got := fmt.Sprint(changes); got != "["+want+"]" || logged != len(changes) {
+		t.Fatalf("changes = %v, logged %d", got, logged)
+	}
+}
+
+func TestCircuitBreaker_ClientErrorsDoNotCount(t *testing.T) {
+	b := &CircuitBreaker{FailureThreshold: 2}
+	res := func(code int) *http.Response { return &http.Response{StatusCode: code} }
+	for i := 0; i < 5; i++ {
+		b.allow("u")
+		b.report("u", res(http.StatusBadRequest), nil)
+		b.allow("u")
+		b.report("u", nil, context.Canceled)
+	}
+	if st := b.State("u"); st != CircuitClosed {
+		t.Fatalf("state = %v", st)
+	}
+	b.allow("u")
+	b.report("u", res(http.StatusBadGateway), nil)
+	b.allow("u")
+	b.report("u", nil, errors.New("connection refused"))
+	if st := b.State("u"); st != CircuitOpen {
+		t.Fatalf("state = %v", st)
+	}
+	if st := b.State("other"); st != CircuitClosed {
+		t.Fatalf("circuits not per base URL: %v", st)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenLimitsTrials(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &CircuitBreaker{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenRequests: 2, now: func() time.Time { return now }}
+	b.allow("u")
+	b.report("u", nil, errors.New("down"))
+	now = now.Add(time.Second)
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.allow("u"); err != nil {
+			t.Fatalf("trial %d: %v", i, err)
+		}
+	}
+	if _, err := b.allow("u"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third trial allowed: %v", err)
+	}
+	b.report("u", &http.Response{StatusCode: 200}, nil)
+	if st := b.State("u"); st != CircuitHalfOpen {
+		t.Fatalf("closed after one of two trials: %v", st)
+	}
+	b.report("u", &http.Response{StatusCode: 200}, nil)
+	if st := b.State("u"); st != CircuitClosed {
+		t.Fatalf("state = %v", st)
+	}
+}
+
+func TestCircuitBreaker_GatesBeforeLimiterAndHooks(t *testing.T) {
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tables":[]}`))
+	})
+	defer srv.Close()
+
+	now := time.Unix(0, 0)
+	cl.Breaker = &CircuitBreaker{FailureThreshold: 1, OpenTimeout: time.Second, now: func() time.Time { return now }}
+	cl.Breaker.allow(srv.URL)
+	cl.Breaker.report(srv.URL, nil, errors.New("down"))
+	cl.RetryPolicy = NoRetry
+	var sent []string
+	cl.Logger = func(event string, _ map[string]any) {
+		if event == "request" {
+			sent = append(sent, "log")
+		}
+	}
+	cl.BeforeHooks = append(cl.BeforeHooks, func(*http.Request) { sent = append(sent, "hook") })
+	WithMaxInFlight(1)(cl)
+	cl.inflight <- struct{}{} // every slot taken
+
+	// Open: refused at once, without waiting for a slot.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := cl.ListTables(ctx, "x"); !errors.Is(err, ErrCircuitOpen) || len(sent) != 0 {
+		t.Fatalf("open: err=%v sent=%v", err, sent)
+	}
+
+	// Half-open: a trial that never gets a slot gives its trial back.
+	now = now.Add(time.Second)
+	short, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := cl.ListTables(short, "x"); !errors.Is(err, context.DeadlineExceeded) || len(sent) != 0 {
+		t.Fatalf("no slot: err=%v sent=%v", err, sent)
+	}
+	<-cl.inflight
+	if _, err := cl.ListTables(ctx, "x"); err != nil || fmt.Sprint(sent) != "[log hook]" {
+		t.Fatalf("trial: err=%v sent=%v", err, sent)
+	}
+	if st := cl.Breaker.State(srv.URL); st != CircuitClosed {
+		t.Fatalf("state = %v", st)
+	}
+}
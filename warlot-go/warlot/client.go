@@ -43,6 +43,27 @@ type Client struct {
 	InitialBackoff time.Duration
 	MaxBackoff     time.Duration
 
+	// RetryPolicy, when set, replaces the policy built from MaxRetries,
+	// InitialBackoff and MaxBackoff.
+	RetryPolicy RetryPolicy
+
+	// Limiter, when set, paces requests and slows down on 429 responses.
+	// See WithRateLimit.
+	Limiter *RateLimiter
+
+	// Breaker, when set, fails calls fast while the API keeps failing.
+	Breaker *CircuitBreaker
+
+	// Hedge, when set, duplicates slow read-only requests; see Hedging.
+	Hedge *Hedging
+
+	// inflight caps concurrent requests; see WithMaxInFlight.
+	inflight chan struct{}
+
+	// Idempotency controls keys for SQL writes sent without one. The zero
+	// value, IdempotencyAuto, generates them.
+	Idempotency IdempotencyMode
+
 	// Observability hooks.
 	Logger      Logger
 	BeforeHooks []func(*http.Request)
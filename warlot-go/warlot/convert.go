@@ -0,0 +1,229 @@
+package warlot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// sqlScanner matches database/sql.Scanner without importing database/sql.
+type sqlScanner interface {
+	Scan(src any) error
+}
+
+// timeLayouts are the textual timestamp formats accepted when scanning into
+// time.Time, covering RFC 3339 and SQLite's CURRENT_TIMESTAMP format.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// driverValue normalizes a decoded JSON value to the types database/sql
+// drivers produce: int64 or float64 for numbers, JSON text for composites.
+func driverValue(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]any, []any:
+		b, _ := json.Marshal(x)
+		return b
+	default:
+		return v
+	}
+}
+
+// convertAssign stores a decoded JSON value into dest.
+func convertAssign(dest, src any) error {
+	switch d := dest.(type) {
+	case nil:
+		return errors.New("destination is nil")
+	case sqlScanner:
+		return d.Scan(driverValue(src))
+	case *any:
+		*d = driverValue(src)
+		return nil
+	case *json.Number:
+		switch s := src.(type) {
+		case json.Number:
+			*d = s
+			return nil
+		case string:
+			*d = json.Number(s)
+			return nil
+		}
+	case *string:
+		switch s := src.(type) {
+		case string:
+			*d = s
+			return nil
+		case json.Number:
+			*d = s.String()
+			return nil
+		case bool:
+			*d = strconv.FormatBool(s)
+			return nil
+		case map[string]any, []any:
+			*d = string(driverValue(s).([]byte))
+			return nil
+		}
+	case *[]byte:
+		switch s := src.(type) {
+		case nil:
+			*d = nil
+			return nil
+		case string:
+			*d = []byte(s)
+			return nil
+		case json.Number:
+			*d = []byte(s)
+			return nil
+		case map[string]any, []any:
+			*d = driverValue(s).([]byte)
+			return nil
+		}
+	case *json.RawMessage:
+		b, err := json.Marshal(src)
+		if err != nil {
+			return err
+		}
+		*d = b
+		return nil
+	case *time.Time:
+		switch s := src.(type) {
+		case string:
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					*d = t
+					return nil
+				}
+			}
+			return fmt.Errorf("cannot parse %q as time", s)
+		case json.Number:
+			secs, err := s.Int64()
+			if err != nil {
+				return fmt.Errorf("cannot convert %s to time: %w", s, err)
+			}
+			*d = time.Unix(secs, 0).UTC()
+			return nil
+		}
+	}
+
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return fmt.Errorf("destination not a pointer: %T", dest)
+	}
+	ev := dv.Elem()
+
+	// Pointer destinations represent nullable columns.
+	if ev.Kind() == reflect.Pointer {
+		if src == nil {
+			ev.Set(reflect.Zero(ev.Type()))
+			return nil
+		}
+		nv := reflect.New(ev.Type().Elem())
+		if err := convertAssign(nv.Interface(), src); err != nil {
+			return err
+		}
+		ev.Set(nv)
+		return nil
+	}
+	if src == nil {
+		if k := ev.Kind(); k == reflect.Map || k == reflect.Slice {
+			ev.Set(reflect.Zero(ev.Type()))
+			return nil
+		}
+		return fmt.Errorf("converting NULL to %s is unsupported", ev.Type())
+	}
+
+	text := ""
+	switch s := src.(type) {
+	case json.Number:
+		text = s.String()
+	case string:
+		text = s
+	case bool:
+		switch ev.Kind() {
+		case reflect.Bool:
+			ev.SetBool(s)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			ev.SetInt(boolInt(s))
+			return nil
+		}
+	}
+
+	switch ev.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if text != "" {
+			i, err := strconv.ParseInt(text, 10, ev.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("converting %q to %s: %w", text, ev.Kind(), err)
+			}
+			ev.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if text != "" {
+			u, err := strconv.ParseUint(text, 10, ev.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("converting %q to %s: %w", text, ev.Kind(), err)
+			}
+			ev.SetUint(u)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if text != "" {
+			f, err := strconv.ParseFloat(text, ev.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("converting %q to %s: %w", text, ev.Kind(), err)
+			}
+			ev.SetFloat(f)
+			return nil
+		}
+	case reflect.Bool:
+		if text != "" {
+			b, err := strconv.ParseBool(text)
+			if err != nil {
+				return fmt.Errorf("converting %q to bool: %w", text, err)
+			}
+			ev.SetBool(b)
+			return nil
+		}
+	case reflect.String:
+		if text != "" || src == "" {
+			ev.SetString(text)
+			return nil
+		}
+	}
+
+	// Fall back to a JSON round-trip for structs, slices and maps.
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if s, ok := src.(string); ok && ev.Kind() != reflect.String && json.Valid([]byte(s)) {
+		// JSON stored as TEXT.
+		b = []byte(s)
+	}
+	if err := json.Unmarshal(b, dest); err != nil {
+		return fmt.Errorf("unsupported Scan, storing %T into %T: %w", src, dest, err)
+	}
+	return nil
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
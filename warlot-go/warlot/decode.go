@@ -0,0 +1,432 @@
+package warlot
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalJSON decodes a schema response. Columns may be PRAGMA
+// table_info rows or bare names; foreign keys may be grouped or one
+// PRAGMA foreign_key_list row per column.
+func (s *TableSchema) UnmarshalJSON(b []byte) error {
+	f, err := decodeFields(b)
+	if err != nil {
+		return err
+	}
+	*s = TableSchema{
+		Name:        f.str("name", "table"),
+		SQL:         f.str("sql", "ddl"),
+		Columns:     decodeColumns(f.value("columns")),
+		Indexes:     decodeIndexes(f.value("indexes")),
+		ForeignKeys: decodeForeignKeys(f.value("foreign_keys", "fks")),
+		Triggers:    decodeTriggers(f.value("triggers")),
+	}
+	s.Extra = f.extra()
+	s.raw = receive(f.m, s.typed)
+	return nil
+}
+
+// MarshalJSON encodes the typed fields and merges Extra. A schema that
+// has not been changed since it was decoded is written as it was
+// received, so decoding and re-encoding loses nothing.
+func (s TableSchema) MarshalJSON() ([]byte, error) { return s.raw.encode(s.typed) }
+
+func (s TableSchema) typed() ([]byte, error) {
+	type plain TableSchema
+	return marshalWithExtra(plain(s), s.Extra)
+}
+
+// Raw returns the schema as a generic map, for code written against the
+// untyped response. It reflects changes made to the typed fields.
+func (s TableSchema) Raw() map[string]any { return s.raw.get(s.typed) }
+
+// Column returns the named column, matched case-insensitively.
+func (s *TableSchema) Column(name string) (ColumnSchema, bool) {
+	for _, c := range s.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// PrimaryKey returns the primary key columns in key order.
+func (s *TableSchema) PrimaryKey() []string {
+	var pk []ColumnSchema
+	for _, c := range s.Columns {
+		if c.PK > 0 {
+			pk = append(pk, c)
+		}
+	}
+	sort.SliceStable(pk, func(i, j int) bool { return pk[i].PK < pk[j].PK })
+	out := make([]string, len(pk))
+	for i, c := range pk {
+		out[i] = c.Name
+	}
+	return out
+}
+
+// UnmarshalJSON decodes a status response. PendingChanges also accepts
+// pending_writes.
+func (s *ProjectStatus) UnmarshalJSON(b []byte) error {
+	f, err := decodeFields(b)
+	if err != nil {
+		return err
+	}
+	*s = ProjectStatus{
+		OK:             f.bool("ok"),
+		ProjectID:      f.str("project_id"),
+		DBID:           f.str("db_id"),
+		HolderID:       f.str("holder_id"),
+		ProjectName:    f.str("project_name"),
+		Tables:         f.int("tables", "table_count"),
+		PendingChanges: f.int("pending_changes", "pending_writes"),
+		Commits:        f.int("commits"),
+		LastCommitAt:   f.str("last_commit_at"),
+		LastTxDigest:   f.str("last_tx_digest", "last_commit_tx"),
+		LastBlobID:     f.str("last_blob_id"),
+		Epoch:          f.int("epoch", "current_epoch"),
+		EpochSet:       f.int("epoch_set"),
+		CycleEnd:       f.int("cycle_end"),
+	}
+	s.Extra = f.extra()
+	s.raw = receive(f.m, s.typed)
+	return nil
+}
+
+// MarshalJSON encodes the typed fields and merges Extra, or writes the
+// response as it was received when nothing has changed since decoding.
+func (s ProjectStatus) MarshalJSON() ([]byte, error) { return s.raw.encode(s.typed) }
+
+func (s ProjectStatus) typed() ([]byte, error) {
+	type plain ProjectStatus
+	return marshalWithExtra(plain(s), s.Extra)
+}
+
+// Raw returns the status as a generic map, for code written against the
+// untyped response. It reflects changes made to the typed fields.
+func (s ProjectStatus) Raw() map[string]any { return s.raw.get(s.typed) }
+
+// UnmarshalJSON decodes a commit response, including the legacy
+// PascalCase TxDigest and BlobID keys.
+func (c *CommitResponse) UnmarshalJSON(b []byte) error {
+	f, err := decodeFields(b)
+	if err != nil {
+		return err
+	}
+	*c = CommitResponse{
+		Committed:   f.bool("committed"),
+		ProjectID:   f.str("project_id"),
+		TxDigest:    f.str("tx_digest", "digest"),
+		BlobID:      f.str("blob_id"),
+		Writes:      f.int("writes"),
+		CommittedAt: f.str("committed_at"),
+	}
+	c.Extra = f.extra()
+	c.raw = receive(f.m, c.typed)
+	return nil
+}
+
+// MarshalJSON encodes the typed fields and merges Extra, or writes the
+// response as it was received when nothing has changed since decoding.
+func (c CommitResponse) MarshalJSON() ([]byte, error) { return c.raw.encode(c.typed) }
+
+func (c CommitResponse) typed() ([]byte, error) {
+	type plain CommitResponse
+	return marshalWithExtra(plain(c), c.Extra)
+}
+
+// Raw returns the commit response as a generic map, for code written
+// against the untyped response. It reflects changes made to the typed
+// fields.
+func (c CommitResponse) Raw() map[string]any { return c.raw.get(c.typed) }
+
+func decodeColumns(v any) []ColumnSchema {
+	list, _ := v.([]any)
+	var out []ColumnSchema
+	for i, item := range list {
+		switch x := item.(type) {
+		case string:
+			out = append(out, ColumnSchema{CID: i, Name: x})
+		case map[string]any:
+			f := newFields(x)
+			c := ColumnSchema{
+				CID:     i,
+				Name:    f.str("name"),
+				Type:    f.str("type"),
+				NotNull: f.bool("notnull", "not_null"),
+				Default: f.value("dflt_value", "default", "default_value"),
+				PK:      f.int("pk", "primary_key"),
+			}
+			if _, ok := f.get("cid"); ok {
+				c.CID = f.int("cid")
+			}
+			if n, ok := f.get("nullable"); ok {
+				c.NotNull = !asBool(n)
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func decodeIndexes(v any) []IndexSchema {
+	list, _ := v.([]any)
+	var out []IndexSchema
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		f := newFields(m)
+		out = append(out, IndexSchema{
+			Name:    f.str("name"),
+			Unique:  f.bool("unique"),
+			Origin:  f.str("origin"),
+			Partial: f.bool("partial"),
+			Columns: names(f.value("columns")),
+			SQL:     f.str("sql"),
+		})
+	}
+	return out
+}
+
+func decodeTriggers(v any) []Trigger {
+	list, _ := v.([]any)
+	var out []Trigger
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			f := newFields(m)
+			out = append(out, Trigger{Name: f.str("name"), SQL: f.str("sql")})
+		}
+	}
+	return out
+}
+
+// decodeForeignKeys accepts grouped keys and PRAGMA foreign_key_list rows,
+// merging rows that share an id.
+func decodeForeignKeys(v any) []ForeignKey {
+	list, _ := v.([]any)
+	var out []ForeignKey
+	byID := map[int]int{}
+	for i, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		f := newFields(m)
+		id := i
+		if _, ok := f.get("id"); ok {
+			id = f.int("id")
+		}
+		cols := names(f.value("columns"))
+		if from := f.str("from"); from != "" {
+			cols = append(cols, from)
+		}
+		refs := names(f.value("ref_columns", "references"))
+		if to := f.str("to"); to != "" {
+			refs = append(refs, to)
+		}
+		if j, ok := byID[id]; ok {
+			out[j].Columns = append(out[j].Columns, cols...)
+			out[j].RefColumns = append(out[j].RefColumns, refs...)
+			continue
+		}
+		byID[id] = len(out)
+		out = append(out, ForeignKey{
+			ID:         id,
+			Columns:    cols,
+			RefTable:   f.str("table", "ref_table"),
+			RefColumns: refs,
+			OnUpdate:   f.str("on_update"),
+			OnDelete:   f.str("on_delete"),
+		})
+	}
+	return out
+}
+
+// names reads a list of column names, given as strings or objects with a
+// name key.
+func names(v any) []string {
+	list, _ := v.([]any)
+	var out []string
+	for _, item := range list {
+		switch x := item.(type) {
+		case string:
+			out = append(out, x)
+		case map[string]any:
+			if n := newFields(x).str("name"); n != "" {
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+// fields reads a decoded JSON object by key, matching snake_case,
+// camelCase and PascalCase spellings alike, and remembers which keys were
+// read so the rest can be kept as extras.
+type fields struct {
+	m    map[string]any
+	keys map[string]string // normalized key -> key as received
+	used map[string]bool
+}
+
+func decodeFields(b []byte) (*fields, error) {
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = map[string]any{}
+	}
+	return newFields(m), nil
+}
+
+func newFields(m map[string]any) *fields {
+	f := &fields{m: m, keys: make(map[string]string, len(m)), used: map[string]bool{}}
+	for k := range m {
+		f.keys[normalizeKey(k)] = k
+	}
+	return f
+}
+
+func normalizeKey(k string) string {
+	return strings.ToLower(strings.ReplaceAll(k, "_", ""))
+}
+
+func (f *fields) get(names ...string) (any, bool) {
+	for _, n := range names {
+		if k, ok := f.keys[normalizeKey(n)]; ok {
+			f.used[k] = true
+			return f.m[k], true
+		}
+	}
+	return nil, false
+}
+
+func (f *fields) value(names ...string) any {
+	v, _ := f.get(names...)
+	return v
+}
+
+func (f *fields) str(names ...string) string {
+	switch x := f.value(names...).(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return ""
+}
+
+func (f *fields) int(names ...string) int { return asInt(f.value(names...)) }
+
+func (f *fields) bool(names ...string) bool { return asBool(f.value(names...)) }
+
+// extra returns the keys that were never read, or nil.
+func (f *fields) extra() map[string]any {
+	var out map[string]any
+	for k, v := range f.m {
+		if !f.used[k] {
+			if out == nil {
+				out = map[string]any{}
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func asInt(v any) int {
+	switch x := v.(type) {
+	case float64:
+		return int(x)
+	case bool:
+		if x {
+			return 1
+		}
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(x))
+		return n
+	}
+	return 0
+}
+
+func asBool(v any) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		switch strings.ToLower(strings.TrimSpace(x)) {
+		case "1", "true", "yes", "y", "on":
+			return true
+		}
+	}
+	return false
+}
+
+// marshalWithExtra encodes v and merges extra keys that v does not set.
+func marshalWithExtra(v any, extra map[string]any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for k, x := range extra {
+		if _, ok := m[k]; !ok {
+			m[k] = x
+		}
+	}
+	return json.Marshal(m)
+}
+
+// received is a decoded response: the object as it arrived and the
+// encoding of the typed fields it decoded to. The object stands in for
+// the typed fields only while they still encode the same way, so legacy
+// key names survive a round trip but edits are never lost.
+type received struct {
+	m     map[string]any
+	typed []byte
+}
+
+func receive(m map[string]any, typed func() ([]byte, error)) *received {
+	b, err := typed()
+	if err != nil {
+		return nil
+	}
+	return &received{m: m, typed: b}
+}
+
+// encode returns the received object when the typed fields are unchanged,
+// and their encoding otherwise. r may be nil.
+func (r *received) encode(typed func() ([]byte, error)) ([]byte, error) {
+	b, err := typed()
+	if err != nil || r == nil || !bytes.Equal(b, r.typed) {
+		return b, err
+	}
+	return json.Marshal(r.m)
+}
+
+// get returns the received object when the typed fields are unchanged,
+// and their encoding as a map otherwise. r may be nil.
+func (r *received) get(typed func() ([]byte, error)) map[string]any {
+	b, err := typed()
+	if err != nil {
+		return nil
+	}
+	if r != nil && bytes.Equal(b, r.typed) {
+		return r.m
+	}
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	return m
+}
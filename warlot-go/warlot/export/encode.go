@@ -0,0 +1,177 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/affinity"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/parquet"
+)
+
+// encoder writes rows in column order. close flushes buffered output; it
+// does not close the underlying writer.
+type encoder interface {
+	write(row map[string]any) error
+	close() error
+}
+
+func newEncoder(f Format, w io.Writer, cols []string, types map[string]string) (encoder, error) {
+	switch f {
+	case JSONL:
+		return &jsonlEncoder{w: bufio.NewWriter(w), cols: cols}, nil
+	case Parquet:
+		pc := make([]parquet.Column, len(cols))
+		for i, c := range cols {
+			pc[i] = parquet.Column{Name: c, Type: parquetType(types[c])}
+		}
+		return &parquetEncoder{w: parquet.NewWriter(w, pc), cols: pc}, nil
+	default:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(cols); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: cw, cols: cols, rec: make([]string, len(cols))}, nil
+	}
+}
+
+// csvEncoder writes a header row, then one record per row. NULL is an
+// empty field.
+type csvEncoder struct {
+	w    *csv.Writer
+	cols []string
+	rec  []string
+}
+
+func (e *csvEncoder) write(row map[string]any) error {
+	for i, c := range e.cols {
+		s, err := text(row[c])
+		if err != nil {
+			return err
+		}
+		e.rec[i] = s
+	}
+	return e.w.Write(e.rec)
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlEncoder writes one JSON object per line with keys in column order.
+type jsonlEncoder struct {
+	w    *bufio.Writer
+	cols []string
+}
+
+func (e *jsonlEncoder) write(row map[string]any) error {
+	e.w.WriteByte('{')
+	for i, c := range e.cols {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		k, _ := json.Marshal(c)
+		v, err := json.Marshal(row[c])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", c, err)
+		}
+		e.w.Write(k)
+		e.w.WriteByte(':')
+		e.w.Write(v)
+	}
+	e.w.WriteString("}\n")
+	return nil
+}
+
+func (e *jsonlEncoder) close() error { return e.w.Flush() }
+
+// parquetEncoder converts values to the column's Parquet type.
+type parquetEncoder struct {
+	w    *parquet.Writer
+	cols []parquet.Column
+	rec  []any
+}
+
+func (e *parquetEncoder) write(row map[string]any) error {
+	if e.rec == nil {
+		e.rec = make([]any, len(e.cols))
+	}
+	for i, c := range e.cols {
+		v, err := parquetValue(c.Type, row[c.Name])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", c.Name, err)
+		}
+		e.rec[i] = v
+	}
+	return e.w.Write(e.rec)
+}
+
+func (e *parquetEncoder) close() error { return e.w.Close() }
+
+// parquetType maps a declared column type by SQLite affinity. BOOLEAN
+// columns, stored as 0 and 1, are written as booleans; text, blob and
+// untyped columns are written as strings.
+func parquetType(declType string) parquet.Type {
+	if affinity.IsBool(declType) {
+		return parquet.Boolean
+	}
+	switch affinity.GoType(declType, false) {
+	case "int64":
+		return parquet.Int64
+	case "float64":
+		return parquet.Double
+	}
+	return parquet.String
+}
+
+func parquetValue(t parquet.Type, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch t {
+	case parquet.Int64:
+		return toInt64(v)
+	case parquet.Double:
+		switch x := v.(type) {
+		case json.Number:
+			return x.Float64()
+		case float64:
+			return x, nil
+		}
+	case parquet.Boolean:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case json.Number:
+			return x != "0", nil
+		case float64:
+			return x != 0, nil
+		}
+	default:
+		return text(v)
+	}
+	return nil, fmt.Errorf("cannot convert %v (%T) to %s", v, v, t)
+}
+
+// text formats a decoded JSON value as CSV or string column text. Nested
+// values are written as JSON.
+func text(v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return x, nil
+	case json.Number:
+		return x.String(), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,484 @@
+// Package export dumps a table to CSV, JSON Lines or Parquet.
+//
+// Rows are streamed with ExecSQLStream and written as they arrive, so
+// memory use does not grow with the table. When the table has a single
+// key column, rows are read in key order a page at a time; with Workers
+// above one, the key range is split into contiguous parts that are
+// exported concurrently to separate files.
+//
+//	m, err := export.ToFile(ctx, proj, "orders.parquet", export.Options{
+//		Table:   "orders",
+//		Format:  export.Parquet,
+//		Workers: 4,
+//	})
+//
+// ToFile writes a manifest next to the output listing every file with its
+// row count, size and SHA-256 checksum.
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Format is an output file format.
+type Format string
+
+const (
+	CSV     Format = "csv"
+	JSONL   Format = "jsonl"
+	Parquet Format = "parquet"
+)
+
+// FormatFromPath returns the format implied by a file extension, or ""
+// when the extension is not recognised.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSV
+	case ".jsonl", ".ndjson":
+		return JSONL
+	case ".parquet":
+		return Parquet
+	}
+	return ""
+}
+
+const defaultPageSize = 1000
+
+// Options configures an export.
+type Options struct {
+	Table   string
+	Format  Format   // default CSV
+	Columns []string // exported columns in order; default: all, from the schema
+
+	// Key is the column rows are ordered and paged by. It defaults to the
+	// table's primary key when that is a single column. Without a key the
+	// table is read with one streaming SELECT. A key that is neither the
+	// primary key nor covered by a unique index is paged together with the
+	// primary key, or rowid when there is none, so rows sharing a key
+	// value are not skipped at page boundaries.
+	Key string
+	// Workers splits the key range into this many parts exported
+	// concurrently; the key must hold integers. ToFile writes one file per
+	// part. Values below two export serially.
+	Workers int
+	// PageSize is the number of rows per SELECT when paging by Key;
+	// default 1000.
+	PageSize int
+
+	CallOptions []warlot.CallOption // applied to every call
+}
+
+// Manifest describes a finished export.
+type Manifest struct {
+	Table     string    `json:"table"`
+	Format    Format    `json:"format"`
+	Columns   []string  `json:"columns"`
+	Key       string    `json:"key,omitempty"`
+	Rows      int64     `json:"rows"`
+	Files     []File    `json:"files"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// File is one output file of an export. Path is relative to the
+// manifest's directory.
+type File struct {
+	Path   string    `json:"path"`
+	Rows   int64     `json:"rows"`
+	Bytes  int64     `json:"bytes"`
+	SHA256 string    `json:"sha256"`
+	Range  *[2]int64 `json:"range,omitempty"` // inclusive key range of a part
+}
+
+// ManifestPath returns where ToFile writes the manifest for path.
+func ManifestPath(path string) string { return path + ".manifest.json" }
+
+// plan is an export with defaults resolved against the table schema.
+type plan struct {
+	p     warlot.Project
+	opts  Options
+	types map[string]string // declared column types
+	order []string          // paging columns: Key, then any tiebreakers
+}
+
+func newPlan(ctx context.Context, p warlot.Project, opts Options) (*plan, error) {
+	if opts.Table == "" {
+		return nil, errors.New("export: Table is required")
+	}
+	if opts.Format == "" {
+		opts.Format = CSV
+	}
+	switch opts.Format {
+	case CSV, JSONL, Parquet:
+	default:
+		return nil, fmt.Errorf("export: unknown format %q", opts.Format)
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	s, err := p.Schema(ctx, opts.Table, opts.CallOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("export: schema %s: %w", opts.Table, err)
+	}
+	pl := &plan{p: p, types: map[string]string{}}
+	defaultCols := len(opts.Columns) == 0
+	for _, c := range s.Columns {
+		pl.types[c.Name] = c.Type
+		if defaultCols {
+			opts.Columns = append(opts.Columns, c.Name)
+		}
+	}
+	if len(opts.Columns) == 0 {
+		return nil, fmt.Errorf("export: table %s has no columns", opts.Table)
+	}
+	if opts.Key == "" {
+		if pk := s.PrimaryKey(); len(pk) == 1 {
+			opts.Key = pk[0]
+		}
+	}
+	if opts.Workers > 1 && opts.Key == "" {
+		return nil, fmt.Errorf("export: table %s has no single-column key; set Options.Key to export in parallel", opts.Table)
+	}
+	if opts.Key != "" {
+		pl.order = pagingColumns(s, opts.Key)
+	}
+	pl.opts = opts
+	return pl, nil
+}
+
+// pagingColumns returns key followed by the columns that make the order
+// unique when key alone does not: the primary key, or rowid for a table
+// without one.
+func pagingColumns(s *warlot.TableSchema, key string) []string {
+	pk := s.PrimaryKey()
+	if len(pk) == 1 && strings.EqualFold(pk[0], key) {
+		return []string{key}
+	}
+	for _, ix := range s.Indexes {
+		if ix.Unique && !ix.Partial && len(ix.Columns) == 1 && strings.EqualFold(ix.Columns[0], key) {
+			return []string{key}
+		}
+	}
+	if len(pk) == 0 {
+		return []string{key, "rowid"}
+	}
+	out := []string{key}
+	for _, c := range pk {
+		if !strings.EqualFold(c, key) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ToWriter exports the table serially to w and returns the number of rows
+// written. Workers is ignored.
+func ToWriter(ctx context.Context, p warlot.Project, w io.Writer, opts Options) (int64, error) {
+	opts.Workers = 0
+	pl, err := newPlan(ctx, p, opts)
+	if err != nil {
+		return 0, err
+	}
+	return pl.run(ctx, w, nil)
+}
+
+// ToFile exports the table to path and writes the manifest to
+// ManifestPath(path). With Workers above one, parts are written to
+// files named like orders-part-000.csv next to path instead. Files
+// written before an error are removed.
+func ToFile(ctx context.Context, p warlot.Project, path string, opts Options) (*Manifest, error) {
+	pl, err := newPlan(ctx, p, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts = pl.opts
+
+	var ranges []*[2]int64
+	if opts.Workers > 1 {
+		if ranges, err = pl.split(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		ranges = []*[2]int64{nil}
+	}
+
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(filepath.Base(path), ext)
+	files := make([]File, len(ranges))
+	for i, r := range ranges {
+		files[i] = File{Path: filepath.Base(path), Range: r}
+		if opts.Workers > 1 {
+			files[i].Path = fmt.Sprintf("%s-part-%03d%s", stem, i, ext)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i := range files {
+		wg.Add(1)
+		go func(f *File) {
+			defer wg.Done()
+			if err := pl.writeFile(ctx, filepath.Join(dir, f.Path), f); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(&files[i])
+	}
+	wg.Wait()
+	if firstErr != nil {
+		for _, f := range files {
+			_ = os.Remove(filepath.Join(dir, f.Path))
+		}
+		return nil, firstErr
+	}
+
+	m := &Manifest{
+		Table:     opts.Table,
+		Format:    opts.Format,
+		Columns:   opts.Columns,
+		Key:       opts.Key,
+		Files:     files,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, f := range files {
+		m.Rows += f.Rows
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(ManifestPath(path), append(b, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeFile exports f.Range to path, filling in the file statistics.
+func (pl *plan) writeFile(ctx context.Context, path string, f *File) (err error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(out, h)}
+	if f.Rows, err = pl.run(ctx, cw, f.Range); err != nil {
+		return fmt.Errorf("export %s: %w", f.Path, err)
+	}
+	f.Bytes = cw.n
+	f.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// split divides the integer key range into at most Workers contiguous
+// inclusive ranges. An empty table yields one empty range.
+func (pl *plan) split(ctx context.Context) ([]*[2]int64, error) {
+	k := quoteIdent(pl.opts.Key)
+	sql := fmt.Sprintf("SELECT MIN(%s) AS lo, MAX(%s) AS hi FROM %s", k, k, quoteIdent(pl.opts.Table))
+	res, err := pl.p.SQL(ctx, sql, nil, pl.opts.CallOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) == 0 || res.Rows[0]["lo"] == nil {
+		return []*[2]int64{{0, -1}}, nil
+	}
+	lo, err1 := toInt64(res.Rows[0]["lo"])
+	hi, err2 := toInt64(res.Rows[0]["hi"])
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, fmt.Errorf("export: key %s must hold integers to split: %w", pl.opts.Key, err)
+	}
+	// width is the span minus one, so a key range covering all of int64
+	// does not wrap the span to zero.
+	width := uint64(hi) - uint64(lo)
+	n := uint64(pl.opts.Workers)
+	if width < n-1 {
+		n = width + 1
+	}
+	step := width / n
+	if width%n == n-1 {
+		step++
+	}
+	out := make([]*[2]int64, n)
+	for i := uint64(0); i < n; i++ {
+		r := [2]int64{lo + int64(i*step), lo + int64((i+1)*step) - 1}
+		if i == n-1 {
+			r[1] = hi
+		}
+		out[i] = &r
+	}
+	return out, nil
+}
+
+// run streams the rows of r (nil for the whole table) to w.
+func (pl *plan) run(ctx context.Context, w io.Writer, r *[2]int64) (int64, error) {
+	enc, err := newEncoder(pl.opts.Format, w, pl.opts.Columns, pl.types)
+	if err != nil {
+		return 0, err
+	}
+	var rows int64
+	if pl.opts.Key == "" {
+		rows, err = pl.stream(ctx, enc, pl.query(nil, nil), nil, nil)
+	} else {
+		rows, err = pl.pages(ctx, enc, r)
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, enc.close()
+}
+
+// pages reads r in key order, PageSize rows per SELECT, continuing after
+// the paging columns of the previous page's last row.
+func (pl *plan) pages(ctx context.Context, enc encoder, r *[2]int64) (int64, error) {
+	var (
+		total int64
+		after []any
+	)
+	order := make([]string, len(pl.order))
+	for i, c := range pl.order {
+		order[i] = quoteIdent(c)
+	}
+	for {
+		var conds []string
+		var params []any
+		k := order[0]
+		if r != nil {
+			conds = append(conds, k+" >= ?", k+" <= ?")
+			params = append(params, r[0], r[1])
+		}
+		if after != nil {
+			if len(order) == 1 {
+				conds = append(conds, k+" > ?")
+			} else {
+				marks := strings.TrimSuffix(strings.Repeat("?, ", len(order)), ", ")
+				conds = append(conds, "("+strings.Join(order, ", ")+") > ("+marks+")")
+			}
+			params = append(params, after...)
+		}
+		sql := pl.query(conds, order) + " LIMIT ?"
+		params = append(params, pl.opts.PageSize)
+
+		n, err := pl.stream(ctx, enc, sql, params, &after)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < int64(pl.opts.PageSize) {
+			return total, nil
+		}
+	}
+}
+
+// query builds the SELECT for the exported columns. Paging columns are
+// selected too when they are not exported, so paging can continue from
+// them.
+func (pl *plan) query(conds, order []string) string {
+	cols := make([]string, 0, len(pl.opts.Columns)+len(pl.order))
+	for _, c := range pl.opts.Columns {
+		cols = append(cols, quoteIdent(c))
+	}
+	for _, c := range pl.order {
+		if !slices.Contains(pl.opts.Columns, c) {
+			cols = append(cols, quoteIdent(c))
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", strings.Join(cols, ", "), quoteIdent(pl.opts.Table))
+	if len(conds) > 0 {
+		b.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+	if len(order) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	}
+	return b.String()
+}
+
+// stream runs one SELECT through ExecSQLStream and encodes each row. When
+// last is non-nil it receives the paging column values of the final row.
+func (pl *plan) stream(ctx context.Context, enc encoder, sql string, params []any, last *[]any) (int64, error) {
+	sc, err := pl.p.Client.ExecSQLStream(ctx, pl.p.ID, warlot.SQLRequest{SQL: sql, Params: params}, pl.opts.CallOptions...)
+	if err != nil {
+		return 0, err
+	}
+	defer sc.Close()
+	var n int64
+	for {
+		var row map[string]any
+		if !sc.Next(&row) {
+			break
+		}
+		if err := enc.write(row); err != nil {
+			return n, err
+		}
+		n++
+		if last != nil {
+			vals := make([]any, len(pl.order))
+			for i, c := range pl.order {
+				if vals[i] = row[c]; vals[i] == nil {
+					return n, fmt.Errorf("export: key column %s is NULL or missing", c)
+				}
+			}
+			*last = vals
+		}
+	}
+	return n, sc.Err()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func toInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case json.Number:
+		return x.Int64()
+	case float64:
+		if x != float64(int64(x)) {
+			return 0, fmt.Errorf("%v is not an integer", x)
+		}
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case string:
+		return strconv.ParseInt(x, 10, 64)
+	}
+	return 0, fmt.Errorf("%v (%T) is not an integer", v, v)
+}
+
+func quoteIdent(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
@@ -0,0 +1,189 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Hedging sends a second copy of a slow read-only request and uses
+// whichever response succeeds first, cancelling the other. It applies to
+// GET endpoints (ListTables, BrowseRows, GetTableSchema, GetProjectStatus)
+// and to read-only SQL sent through ExecSQL or ExecSQLStream; writes are
+// never hedged.
+//
+// The hedge delay is counted from when the first request clears the rate
+// limiter and in-flight limit, not from the call, so time spent queued
+// does not trigger a hedge. The hedge fires after Delay, or, when
+// Percentile is set, after that percentile of recent latencies once
+// MinSamples have been observed. Samples are successful responses plus,
+// as a lower bound, requests cancelled because the other copy won, so a
+// slow tail that hedging cuts short still raises the percentile.
+type Hedging struct {
+	Delay      time.Duration // default 100ms
+	Percentile float64       // e.g. 0.95; 0 uses Delay only
+	MinSamples int           // default 20
+
+	mu      sync.Mutex
+	samples [256]time.Duration
+	n       int // samples recorded, including overwritten ones
+}
+
+// CurrentDelay returns how long a request waits before being hedged.
+func (h *Hedging) CurrentDelay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	min := h.MinSamples
+	if min <= 0 {
+		min = 20
+	}
+	if h.Percentile <= 0 || h.n < min {
+		if h.Delay <= 0 {
+			return 100 * time.Millisecond
+		}
+		return h.Delay
+	}
+	k := h.n
+	if k > len(h.samples) {
+		k = len(h.samples)
+	}
+	s := make([]time.Duration, k)
+	copy(s, h.samples[:k])
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	i := int(h.Percentile * float64(k))
+	if i >= k {
+		i = k - 1
+	}
+	return s[i]
+}
+
+func (h *Hedging) observe(d time.Duration) {
+	h.mu.Lock()
+	h.samples[h.n%len(h.samples)] = d
+	h.n++
+	h.mu.Unlock()
+}
+
+// errHedgeLost is the cancellation cause of the copy of a hedged request
+// that did not win.
+var errHedgeLost = errors.New("warlot: hedged request lost")
+
+// roundTrip sends one attempt of r, hedging it when r allows. The losing
+// request is cancelled and its response discarded.
+func (c *Client) roundTrip(ctx context.Context, r *request) (*http.Response, bool, error) {
+	if !r.hedge {
+		return c.try(ctx, r, nil)
+	}
+	type result struct {
+		res   *http.Response
+		gated bool
+		err   error
+		i     int
+	}
+	results := make(chan result, 2)
+	var cancels []context.CancelCauseFunc
+	launch := func(sent func()) {
+		hctx, cancel := context.WithCancelCause(ctx)
+		i := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			res, gated, err := c.try(hctx, r, sent)
+			results <- result{res, gated, err, i}
+		}()
+	}
+	// finish ties a result's context to its body, or ends it now.
+	finish := func(x result) (*http.Response, bool, error) {
+		cancel := cancels[x.i]
+		if x.res != nil {
+			x.res.Body = cancelBody{x.res.Body, func() { cancel(nil) }}
+		} else {
+			cancel(nil)
+		}
+		return x.res, x.gated, x.err
+	}
+	discard := func(x result) {
+		if x.res != nil {
+			x.res.Body.Close()
+		}
+		cancels[x.i](nil)
+	}
+
+	// The hedge timer starts once the first request is on its way.
+	started := make(chan struct{})
+	launch(func() { close(started) })
+	var (
+		timer  *time.Timer
+		fire   <-chan time.Time
+		hedged bool
+		failed *result
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for pending := 1; ; {
+		select {
+		case <-started:
+			started = nil
+			timer = time.NewTimer(c.Hedge.CurrentDelay())
+			fire = timer.C
+		case <-fire:
+			fire, hedged = nil, true
+			pending++
+			if c.Logger != nil {
+				c.Logger("hedge", map[string]any{"method": r.method, "url": r.url, "attempt": r.attempt})
+			}
+			launch(nil)
+		case x := <-results:
+			pending--
+			if x.err == nil && x.res.StatusCode/100 == 2 {
+				for i, cancel := range cancels {
+					if i != x.i {
+						cancel(errHedgeLost)
+					}
+				}
+				if failed != nil {
+					discard(*failed)
+				}
+				if pending > 0 {
+					go func() { discard(<-results) }()
+				}
+				return finish(x)
+			}
+			if !hedged {
+				// Failed before the hedge was sent: an ordinary failure.
+				return finish(x)
+			}
+			switch {
+			case failed == nil:
+				failed = &x
+			case failed.gated && failed.i != 0:
+				// A hedge refused by a half-open breaker says nothing
+				// about the request; report the primary's failure.
+				discard(*failed)
+				failed = &x
+			default:
+				discard(x)
+			}
+			if pending == 0 {
+				return finish(*failed)
+			}
+		}
+	}
+}
+
+// cancelBody ends a hedged request's context when its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,207 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowFirst stalls the first request until the client gives up on it and
+// answers later ones at once.
+func slowFirst(calls, cancelled *int32, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body) // the server notices a disconnect only after the body is read
+		if atomic.AddInt32(calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				atomic.AddInt32(cancelled, 1)
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(body))
+	}
+}
+
+func TestHedging_ReadsTakeFirstResponse(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name string
+		body string
+		call func(cl *Client) error
+	}{
+		{"ListTables", `{"tables":["a"]}`, func(cl *Client) error {
+			_, err := cl.ListTables(ctx, "x")
+			return err
+		}},
+		{"ExecSQL SELECT", `{"ok":true,"rows":[]}`, func(cl *Client) error {
+			_, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "SELECT 1"})
+			return err
+		}},
+		{"ExecSQLStream", `{"rows":[{"a":1}]}`, func(cl *Client) error {
+			sc, err := cl.ExecSQLStream(ctx, "x", SQLRequest{SQL: "SELECT a FROM t"})
+			if err != nil {
+				return err
+			}
+			defer sc.Close()
+			var n int
+			for row := map[string]any{}; sc.Next(&row); {
+				n++
+			}
+			if n != 1 {
+				t.Errorf("streamed %d rows", n)
+			}
+			return sc.Err()
+		}},
+	}
+	for _, tc := range cases {
+		var calls, cancelled int32
+		srv, cl := newTestServer(slowFirst(&calls, &cancelled, tc.body))
+		cl.Hedge = &Hedging{Delay: 20 * time.Millisecond}
+
+		start := time.Now()
+		if err := tc.call(cl); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if el := time.Since(start); el > time.Second {
+			t.Fatalf("%s: not hedged, took %v", tc.name, el)
+		}
+		srv.Close()
+		if atomic.LoadInt32(&calls) != 2 || atomic.LoadInt32(&cancelled) != 1 {
+			t.Fatalf("%s: calls=%d cancelled=%d", tc.name, calls, cancelled)
+		}
+		// The winner is sampled, and so is the cancelled slow copy.
+		if n := hedgeSamples(cl.Hedge, 2); n != 2 {
+			t.Fatalf("%s: %d latency samples", tc.name, n)
+		}
+	}
+}
+
+// hedgeSamples waits briefly for h to record want samples, since a
+// cancelled copy is recorded after the call returns.
+func hedgeSamples(h *Hedging, want int) int {
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.mu.Lock()
+		n := h.n
+		h.mu.Unlock()
+		if n >= want || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHedging_DelayStartsAfterGate(t *testing.T) {
+	var calls int32
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{"tables":[]}`))
+	})
+	defer srv.Close()
+	cl.Hedge = &Hedging{Delay: 50 * time.Millisecond}
+
+	// Every in-flight slot is taken for longer than the hedge delay.
+	cl.inflight = make(chan struct{}, 2)
+	cl.inflight <- struct{}{}
+	cl.inflight <- struct{}{}
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		<-cl.inflight
+		<-cl.inflight
+	}()
+
+	if _, err := cl.ListTables(context.Background(), "x"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("calls = %d; time queued for a slot triggered a hedge", n)
+	}
+}
+
+func TestHedging_WritesNotHedged(t *testing.T) {
+	var calls int32
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"ok":true,"row_count":1}`))
+	})
+	defer srv.Close()
+	cl.Hedge = &Hedging{Delay: time.Millisecond}
+	ctx := context.Background()
+
+	if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "INSERT INTO t VALUES (1)"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.CommitProject(ctx, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls = %d, want 2", n)
+	}
+}
+
+func TestHedging_FailedPrimaryWaitsForHedge(t *testing.T) {
+	var calls int32
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(60 * time.Millisecond)
+			http.Error(w, `{"message":"bad"}`, http.StatusBadRequest)
+			return
+		}
+		time.Sleep(120 * time.Millisecond)
+		w.Write([]byte(`{"tables":[]}`))
+	})
+	defer srv.Close()
+	cl.Hedge = &Hedging{Delay: 20 * time.Millisecond}
+
+	if _, err := cl.ListTables(context.Background(), "x"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHedging_PrimaryErrorBeatsGatedHedge(t *testing.T) {
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(60 * time.Millisecond)
+		http.Error(w, `{"message":"bad"}`, http.StatusBadRequest)
+	})
+	defer srv.Close()
+	now := time.Unix(0, 0)
+	cl.Breaker = &CircuitBreaker{FailureThreshold: 1, OpenTimeout: time.Second, now: func() time.Time { return now }}
+	cl.Breaker.allow(srv.URL)
+	cl.Breaker.report(srv.URL, nil, errors.New("down"))
+	now = now.Add(time.Second) // half-open: the primary takes the only trial
+	cl.Hedge = &Hedging{Delay: 10 * time.Millisecond}
+	cl.RetryPolicy = NoRetry
+
+	_, err := cl.ListTables(context.Background(), "x")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want the primary's 400", err)
+	}
+}
+
+func TestHedging_PercentileDelay(t *testing.T) {
+	h := &Hedging{Delay: time.Second, Percentile: 0.9, MinSamples: 10}
+	for i := 1; i <= 9; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if d := h.CurrentDelay(); d != time.Second {
+		t.Fatalf("delay before MinSamples = %v", d)
+	}
+	h.observe(10 * time.Millisecond)
+	if d := h.CurrentDelay(); d != 10*time.Millisecond {
+		t.Fatalf("p90 of 1..10ms = %v", d)
+	}
+	for i := 0; i < 300; i++ {
+		h.observe(time.Millisecond)
+	}
+	if d := h.CurrentDelay(); d != time.Millisecond {
+		t.Fatalf("old samples not replaced: %v", d)
+	}
+}
@@ -4,188 +4,214 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 // doJSON sends an HTTP request with a JSON encoded body and decodes a JSON response.
-// Retries are performed for 429 and 5xx responses using jittered backoff and
-// honoring Retry-After when present.
+// Failed attempts are retried as the client's RetryPolicy decides.
 func (c *Client) doJSON(ctx context.Context, method, path string, hdr http.Header, in, out any) error {
-	u := c.BaseURL + path
-
-	makeBody := func() (io.ReadCloser, []byte, error) {
-		if in == nil {
-			return nil, nil, nil
-		}
-		b, err := json.Marshal(in)
-		if err != nil {
-			return nil, nil, fmt.Errorf("marshal request: %w", err)
+	res, err := c.send(ctx, method, path, hdr, in)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	for _, h := range c.AfterHooks {
+		h(res, body, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s: read response: %w", method, c.BaseURL+path, err)
+	}
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("decode response: %w (body=%s)", err, string(body))
 		}
-		return io.NopCloser(bytes.NewReader(b)), b, nil
 	}
+	return nil
+}
 
-	var lastErr error
-	backoff, maxBack := normalizeBackoff(c.InitialBackoff, c.MaxBackoff)
-	retries := normalizeRetries(c.MaxRetries)
+// doRequest is similar to doJSON but returns a raw response for streaming.
+// The caller must close the response body.
+func (c *Client) doRequest(ctx context.Context, method, path string, hdr http.Header, in any) (*http.Response, error) {
+	return c.send(ctx, method, path, hdr, in)
+}
 
-	for attempt := 0; attempt <= retries; attempt++ {
-		rc, raw, err := makeBody()
-		if err != nil {
-			return err
-		}
-		req, err := http.NewRequestWithContext(ctx, method, u, rc)
+// send performs one logical call and returns the first 2xx response with
+// its body unread. Each attempt waits for the client's rate limiter and
+// in-flight cap and checks the circuit breaker; a returned body holds its
+// in-flight slot until closed. Failed attempts are passed to AfterHooks
+// before the RetryPolicy decides whether to try again. A 4xx response
+// other than 429 that is not retried is returned as a bare *APIError.
+func (c *Client) send(ctx context.Context, method, path string, hdr http.Header, in any) (*http.Response, error) {
+	r := &request{method: method, base: c.BaseURL, url: c.BaseURL + path, hdr: hdr}
+	if in != nil {
+		b, err := json.Marshal(in)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("marshal request: %w", err)
 		}
-		req.Header.Set("Content-Type", "application/json")
-		if c.UserAgent != "" {
-			req.Header.Set("User-Agent", c.UserAgent)
-		}
-		for k, vs := range hdr {
-			for _, v := range vs {
-				req.Header.Add(k, v)
+		r.raw = b
+	}
+
+	policy := c.retryPolicy()
+	if rc, ok := policy.(RequestCounter); ok {
+		rc.CountRequest()
+	}
+	ci := callInfoFrom(ctx)
+	keyed := hdr.Get("x-idempotency-key") != ""
+	readOnly := method == http.MethodGet || method == http.MethodHead || ci.readOnly
+	idempotent := readOnly || keyed
+	r.hedge = readOnly && c.Hedge != nil
+	u := r.url
+
+	var (
+		lastErr error
+		prev    time.Duration
+	)
+	for attempt := 0; ; attempt++ {
+		r.attempt = attempt
+		res, gated, err := c.roundTrip(ctx, r)
+		if gated {
+			if lastErr == nil {
+				return nil, err
 			}
+			return nil, fmt.Errorf("warlot request failed after %d attempts: %w (last error: %v)", attempt, err, lastErr)
 		}
-		if c.Logger != nil {
-			c.Logger("request", map[string]any{
-				"method": method, "url": u, "headers": redactHeaders(req.Header), "attempt": attempt,
-			})
-		}
-		for _, h := range c.BeforeHooks {
-			h(req)
+		if err == nil && res.StatusCode/100 == 2 {
+			return res, nil
 		}
 
-		res, err := c.HTTPClient.Do(req)
 		var body []byte
 		if err == nil {
-			defer res.Body.Close()
 			body, _ = io.ReadAll(res.Body)
-		}
-		if c.Logger != nil {
-			c.Logger("response", map[string]any{
-				"method": method, "url": u, "status": statusOf(res), "attempt": attempt,
-			})
+			res.Body.Close()
 		}
 		for _, h := range c.AfterHooks {
 			h(res, body, err)
 		}
 
+		a := RetryAttempt{
+			Method: method, URL: u, Attempt: attempt, PrevDelay: prev,
+			IdempotencyKey: keyed, Idempotent: idempotent,
+		}
+		transient := true
 		if err != nil {
+			a.Err = err
 			lastErr = fmt.Errorf("%s %s: %w", method, u, err)
-		} else if res.StatusCode/100 == 2 {
-			if out != nil && len(body) > 0 {
-				if err := json.Unmarshal(body, out); err != nil {
-					lastErr = fmt.Errorf("decode response: %w (body=%s)", err, string(body))
-				} else {
-					return nil
-				}
-			} else {
-				return nil
-			}
 		} else {
 			apiErr := parseAPIError(res.StatusCode, body)
-			if res.StatusCode == http.StatusTooManyRequests || res.StatusCode/100 == 5 {
-				lastErr = fmt.Errorf("%s %s: %w", method, u, apiErr)
-				if ra := parseRetryAfter(res.Header.Get("Retry-After")); ra > 0 && ra > backoff {
-					backoff = ra
+			a.Err, a.StatusCode = apiErr, res.StatusCode
+			a.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+			if c.Limiter != nil && (res.StatusCode == http.StatusTooManyRequests || a.RetryAfter > 0) {
+				c.Limiter.Throttle(a.RetryAfter)
+				if c.Logger != nil {
+					c.Logger("throttle", map[string]any{"url": u, "rate": c.Limiter.Rate(), "retry_after": a.RetryAfter})
 				}
-			} else {
-				return apiErr
+			}
+			lastErr = fmt.Errorf("%s %s: %w", method, u, apiErr)
+			if transient = res.StatusCode == http.StatusTooManyRequests || res.StatusCode/100 == 5; !transient {
+				lastErr = apiErr
 			}
 		}
 
-		// Stop retrying on non-retriable errors.
-		if e, ok := lastErr.(*APIError); ok && e.StatusCode != http.StatusTooManyRequests && e.StatusCode/100 != 5 {
-			return lastErr
+		delay, ok := policy.Next(a)
+		if !ok || ci.noRetry {
+			if !transient {
+				return nil, lastErr
+			}
+			return nil, fmt.Errorf("warlot request failed after %d attempts: %w", attempt+1, lastErr)
 		}
-
-		// Backoff with jitter.
-		if attempt < retries {
-			jitterSleep(ctx, backoff, maxBack)
-			backoff = nextBackoff(backoff, maxBack)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return nil, fmt.Errorf("warlot request failed after %d attempts: %w (last error: %v)", attempt+1, err, lastErr)
 		}
-		_ = raw
+		prev = delay
 	}
-	return fmt.Errorf("warlot request failed after %d attempts: %w", retries+1, lastErr)
 }
 
-// doRequest is similar to doJSON but returns a raw response for streaming.
-// The caller must close the response body.
-func (c *Client) doRequest(ctx context.Context, method, path string, hdr http.Header, in any) (*http.Response, error) {
-	u := c.BaseURL + path
+// request is one logical call as seen by each of its attempts.
+type request struct {
+	method, base, url string
+	hdr               http.Header
+	raw               []byte
+	attempt           int
+	hedge             bool // read-only and the client hedges
+}
 
-	makeBody := func() (io.ReadCloser, []byte, error) {
-		if in == nil {
-			return nil, nil, nil
-		}
-		b, err := json.Marshal(in)
-		if err != nil {
-			return nil, nil, fmt.Errorf("marshal request: %w", err)
+// try sends a single attempt of r. gated reports that it was not sent
+// because ctx ended while waiting for the rate limiter or an in-flight
+// slot, or because the circuit is open. sent, when non-nil, is called once
+// the attempt has cleared those gates and is about to go out. A response
+// body, 2xx or not, holds the in-flight slot until closed.
+func (c *Client) try(ctx context.Context, r *request, sent func()) (res *http.Response, gated bool, err error) {
+	var body io.Reader
+	if r.raw != nil {
+		body = bytes.NewReader(r.raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, body)
+	if err != nil {
+		return nil, true, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for k, vs := range r.hdr {
+		for _, v := range vs {
+			req.Header.Add(k, v)
 		}
-		return io.NopCloser(bytes.NewReader(b)), b, nil
 	}
 
-	var lastErr error
-	backoff, maxBack := normalizeBackoff(c.InitialBackoff, c.MaxBackoff)
-	retries := normalizeRetries(c.MaxRetries)
-
-	for attempt := 0; attempt <= retries; attempt++ {
-		rc, _, err := makeBody()
+	// A request the breaker refuses never waits for the limiter, and one
+	// that is not sent is neither logged nor passed to BeforeHooks.
+	if c.Breaker != nil {
+		tr, err := c.Breaker.allow(r.base)
+		c.circuitChanged(r.base, tr)
 		if err != nil {
-			return nil, err
-		}
-		req, err := http.NewRequestWithContext(ctx, method, u, rc)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Content-Type", "application/json")
-		if c.UserAgent != "" {
-			req.Header.Set("User-Agent", c.UserAgent)
-		}
-		for k, vs := range hdr {
-			for _, v := range vs {
-				req.Header.Add(k, v)
-			}
-		}
-		if c.Logger != nil {
-			c.Logger("request", map[string]any{
-				"method": method, "url": u, "headers": redactHeaders(req.Header), "attempt": attempt,
-			})
-		}
-		for _, h := range c.BeforeHooks {
-			h(req)
-		}
-
-		res, err := c.HTTPClient.Do(req)
-		if err == nil && res.StatusCode/100 == 2 {
-			return res, nil
-		}
-
-		var body []byte
-		if err == nil {
-			body, _ = io.ReadAll(res.Body)
-			res.Body.Close()
+			return nil, true, err
 		}
-		if err != nil {
-			lastErr = fmt.Errorf("%s %s: %w", method, u, err)
-		} else {
-			apiErr := parseAPIError(res.StatusCode, body)
-			if res.StatusCode == http.StatusTooManyRequests || res.StatusCode/100 == 5 {
-				lastErr = fmt.Errorf("%s %s: %w", method, u, apiErr)
-				if ra := parseRetryAfter(res.Header.Get("Retry-After")); ra > 0 && ra > backoff {
-					backoff = ra
-				}
-			} else {
-				return nil, apiErr
-			}
+	}
+	release, err := c.acquire(ctx)
+	if err != nil {
+		if c.Breaker != nil {
+			c.Breaker.release(r.base)
 		}
-
-		if attempt < retries {
-			jitterSleep(ctx, backoff, maxBack)
-			backoff = nextBackoff(backoff, maxBack)
+		return nil, true, err
+	}
+	if c.Logger != nil {
+		c.Logger("request", map[string]any{
+			"method": r.method, "url": r.url, "headers": redactHeaders(req.Header, false), "attempt": r.attempt,
+		})
+	}
+	for _, h := range c.BeforeHooks {
+		h(req)
+	}
+	if sent != nil {
+		sent()
+	}
+	start := time.Now()
+	res, err = c.HTTPClient.Do(req)
+	if c.Breaker != nil {
+		c.circuitChanged(r.base, c.Breaker.report(r.base, res, err))
+	}
+	if c.Logger != nil {
+		c.Logger("response", map[string]any{
+			"method": r.method, "url": r.url, "status": statusOf(res), "attempt": r.attempt,
+		})
+	}
+	if err != nil {
+		release()
+		if r.hedge && errors.Is(context.Cause(ctx), errHedgeLost) {
+			// The other copy won; this one took at least this long.
+			c.Hedge.observe(time.Since(start))
 		}
+		return nil, false, err
+	}
+	if r.hedge && res.StatusCode/100 == 2 {
+		c.Hedge.observe(time.Since(start))
 	}
-	return nil, fmt.Errorf("warlot request failed after %d attempts: %w", retries+1, lastErr)
+	res.Body = releaseBody{res.Body, release}
+	return res, false, nil
 }
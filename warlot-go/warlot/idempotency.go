@@ -0,0 +1,174 @@
+package warlot
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
+)
+
+// IdempotencyMode controls how ExecSQL and ExecSQLStream protect writes
+// sent without an idempotency key from being applied twice by a retry.
+type IdempotencyMode int
+
+const (
+	// IdempotencyAuto attaches a generated x-idempotency-key to mutating
+	// statements (INSERT, UPDATE, DELETE, DDL). The key is the same for
+	// every attempt of one call.
+	IdempotencyAuto IdempotencyMode = iota
+	// IdempotencyOff sends writes without a key unless the caller passes
+	// WithIdempotencyKey. The retry policy still applies.
+	IdempotencyOff
+	// IdempotencyStrict never retries a mutating statement sent without a
+	// caller-supplied key.
+	IdempotencyStrict
+)
+
+// callInfo carries what the SDK knows about a logical call down to the
+// shared request path.
+type callInfo struct {
+	readOnly bool // the call cannot change data
+	noRetry  bool // the call must not be retried
+}
+
+type callInfoKey struct{}
+
+func withCallInfo(ctx context.Context, ci callInfo) context.Context {
+	return context.WithValue(ctx, callInfoKey{}, ci)
+}
+
+func callInfoFrom(ctx context.Context) callInfo {
+	ci, _ := ctx.Value(callInfoKey{}).(callInfo)
+	return ci
+}
+
+// prepareSQL classifies a statement before it is sent: read-only SQL is
+// marked safe to retry, and writes without a key get one or lose their
+// retries according to the client's IdempotencyMode.
+func (c *Client) prepareSQL(ctx context.Context, sql string, h http.Header) context.Context {
+	if isReadOnlySQL(sql) {
+		return withCallInfo(ctx, callInfo{readOnly: true})
+	}
+	if h.Get("x-idempotency-key") != "" {
+		return ctx
+	}
+	switch c.Idempotency {
+	case IdempotencyAuto:
+		h.Set("x-idempotency-key", "sql-"+newIdempotencyKey())
+	case IdempotencyStrict:
+		return withCallInfo(ctx, callInfo{noRetry: true})
+	}
+	return ctx
+}
+
+// isReadOnlySQL reports whether every statement in sql only reads: SELECT,
+// VALUES, EXPLAIN, a WITH whose main statement is a SELECT, or a PRAGMA
+// query (see readOnlyPragma). Anything it cannot tokenize counts as a
+// write.
+func isReadOnlySQL(sql string) bool {
+	toks, err := sqlparse.Tokenize(sql)
+	if err != nil {
+		return false
+	}
+	seen := false
+	for i := 0; i < len(toks) && toks[i].Kind != sqlparse.TokEOF; {
+		if toks[i].Kind == sqlparse.TokOp && toks[i].Text == ";" {
+			i++
+			continue
+		}
+		end := i
+		depth := 0
+		for ; toks[end].Kind != sqlparse.TokEOF; end++ {
+			t := toks[end]
+			if t.Kind != sqlparse.TokOp {
+				continue
+			}
+			if t.Text == "(" {
+				depth++
+			} else if t.Text == ")" {
+				depth--
+			} else if t.Text == ";" && depth == 0 {
+				break
+			}
+		}
+		if !readOnlyStatement(toks[i:end]) {
+			return false
+		}
+		seen = true
+		i = end
+	}
+	return seen
+}
+
+func readOnlyStatement(toks []sqlparse.Token) bool {
+	if toks[0].Kind != sqlparse.TokIdent {
+		return false
+	}
+	switch strings.ToUpper(toks[0].Text) {
+	case "SELECT", "VALUES", "EXPLAIN":
+		return true
+	case "PRAGMA":
+		return readOnlyPragma(toks[1:])
+	case "WITH":
+		depth := 0
+		for _, t := range toks[1:] {
+			switch {
+			case t.Kind == sqlparse.TokOp && t.Text == "(":
+				depth++
+			case t.Kind == sqlparse.TokOp && t.Text == ")":
+				depth--
+			case depth == 0 && t.Kind == sqlparse.TokIdent:
+				switch strings.ToUpper(t.Text) {
+				case "SELECT", "VALUES":
+					return true
+				case "INSERT", "REPLACE", "UPDATE", "DELETE":
+					return false
+				}
+			}
+		}
+	}
+	return false
+}
+
+// queryPragmas are the pragmas that only read when given no argument.
+// Setting pragmas such as user_version change state when given a value,
+// in either the "= value" or the "(value)" form.
+var queryPragmas = map[string]bool{
+	"application_id": true, "auto_vacuum": true, "cache_size": true, "collation_list": true,
+	"compile_options": true, "data_version": true, "database_list": true, "encoding": true,
+	"foreign_key_check": true, "foreign_keys": true, "freelist_count": true, "function_list": true,
+	"integrity_check": true, "journal_mode": true, "module_list": true, "page_count": true,
+	"page_size": true, "pragma_list": true, "quick_check": true, "schema_version": true,
+	"synchronous": true, "table_list": true, "user_version": true,
+}
+
+// namePragmas are the pragmas whose argument names a table or index
+// rather than setting a value, so they read in the call form as well.
+var namePragmas = map[string]bool{
+	"foreign_key_check": true, "foreign_key_list": true, "index_info": true, "index_list": true,
+	"index_xinfo": true, "table_info": true, "table_list": true, "table_xinfo": true,
+}
+
+// readOnlyPragma reports whether the tokens after PRAGMA only read: a bare
+// "PRAGMA [schema.]name" on the queryPragmas list, or "name(object)" on
+// the namePragmas list. Action pragmas such as optimize, wal_checkpoint
+// and incremental_vacuum are on neither.
+func readOnlyPragma(toks []sqlparse.Token) bool {
+	if len(toks) > 2 && toks[1].Kind == sqlparse.TokOp && toks[1].Text == "." {
+		toks = toks[2:]
+	}
+	if len(toks) == 0 || (toks[0].Kind != sqlparse.TokIdent && toks[0].Kind != sqlparse.TokQuotedIdent) {
+		return false
+	}
+	name, rest := strings.ToLower(toks[0].Text), toks[1:]
+	switch len(rest) {
+	case 0:
+		return queryPragmas[name]
+	case 3:
+		arg := rest[1].Kind
+		return namePragmas[name] && rest[0].Text == "(" && rest[2].Text == ")" &&
+			(arg == sqlparse.TokIdent || arg == sqlparse.TokQuotedIdent || arg == sqlparse.TokString)
+	}
+	return false
+}
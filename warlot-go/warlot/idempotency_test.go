@@ -0,0 +1,122 @@
+package warlot
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIsReadOnlySQL(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT * FROM t":                                    true,
+		"  select 1; -- trailing":                            true,
+		"VALUES (1), (2)":                                    true,
+		"EXPLAIN QUERY PLAN SELECT 1":                        true,
+		"PRAGMA table_info(t)":                               true,
+		"WITH x AS (SELECT 1) SELECT * FROM x":               true,
+		"WITH RECURSIVE c(n) AS (VALUES(1)) SELECT n FROM c": true,
+		"PRAGMA foreign_keys = ON":                           false,
+		"PRAGMA main.user_version":                           true,
+		"pragma foreign_keys; SELECT 1":                      true,
+		"PRAGMA user_version(5)":                             false,
+		"PRAGMA foreign_keys(0)":                             false,
+		"PRAGMA optimize":                                    false,
+		"PRAGMA wal_checkpoint(TRUNCATE)":                    false,
+		"PRAGMA incremental_vacuum":                          false,
+		"PRAGMA table_info(1)":                               false,
+		"WITH x AS (SELECT 1) DELETE FROM t":                 false,
+		"INSERT INTO t SELECT * FROM u":                      false,
+		"update t set a = 1":                                 false,
+		"REPLACE INTO t VALUES (1)":                          false,
+		"CREATE TABLE t (id INTEGER)":                        false,
+		"SELECT 1; DROP TABLE t":                             false,
+		"SELECT 'unterminated":                               false,
+		"":                                                   false,
+	}
+	for sql, want := range cases {
+		if got := isReadOnlySQL(sql); got != want {
+			t.Errorf("isReadOnlySQL(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestIdempotency_AutoKeyStableAcrossAttempts(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("x-idempotency-key"))
+		n := len(keys)
+		mu.Unlock()
+		if n == 1 {
+			http.Error(w, `{"message":"temporary"}`, http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"row_count":1}`))
+	})
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "INSERT INTO t VALUES (1)"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("keys = %q", keys)
+	}
+
+	keys = nil
+	if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "INSERT INTO t VALUES (2)"}, WithIdempotencyKey("mine")); err != nil {
+		t.Fatal(err)
+	}
+	if keys[0] != "mine" {
+		t.Fatalf("caller key replaced: %q", keys)
+	}
+
+	keys = nil
+	if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "SELECT 1"}); err != nil {
+		t.Fatal(err)
+	}
+	if keys[0] != "" {
+		t.Fatalf("read got a key: %q", keys)
+	}
+}
+
+func TestIdempotency_Modes(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	srv, cl := newTestServer(dropServer(&calls))
+	defer srv.Close()
+
+	// Off: no key, so a dropped connection is not retried.
+	cl.Idempotency = IdempotencyOff
+	if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "DELETE FROM t"}); err == nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("off: calls=%d err=%v", calls, err)
+	}
+
+	// Read-only SQL is retried after a dropped connection in any mode.
+	atomic.StoreInt32(&calls, 0)
+	if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "SELECT 1"}); err != nil || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("select: calls=%d err=%v", calls, err)
+	}
+
+	// Strict: an unkeyed write is sent once even on a 5xx.
+	var fails int32
+	srv5, cl5 := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fails, 1)
+		http.Error(w, `{"message":"temporary"}`, http.StatusServiceUnavailable)
+	})
+	defer srv5.Close()
+	cl5.Idempotency = IdempotencyStrict
+	if _, err := cl5.ExecSQL(ctx, "x", SQLRequest{SQL: "DELETE FROM t"}); err == nil || atomic.LoadInt32(&fails) != 1 {
+		t.Fatalf("strict: calls=%d err=%v", fails, err)
+	}
+	atomic.StoreInt32(&fails, 0)
+	cl5.ExecSQL(ctx, "x", SQLRequest{SQL: "DELETE FROM t"}, WithIdempotencyKey("k"))
+	if got := atomic.LoadInt32(&fails); got != 3 {
+		t.Fatalf("strict keyed: calls=%d, want 3", got)
+	}
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrCheckpointMismatch is returned when a checkpoint was written for a
+// different table, column set, batch size or input file.
+var ErrCheckpointMismatch = errors.New("ingest: checkpoint does not match import options")
+
+// Checkpoint records the progress of an import. Batches counts the
+// leading batches known to be applied; batches finished out of order
+// beyond it are re-sent on resume and deduplicated by their idempotency
+// keys.
+type Checkpoint struct {
+	Table     string    `json:"table"`
+	Columns   []string  `json:"columns"`
+	BatchRows int       `json:"batch_rows"`
+	Batches   int       `json:"batches"`
+	Rows      int64     `json:"rows"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// InputSize and InputSHA256 identify the input file; both are zero
+	// for imports read from an io.Reader.
+	InputSize   int64  `json:"input_size,omitempty"`
+	InputSHA256 string `json:"input_sha256,omitempty"`
+}
+
+// ReadCheckpoint loads a checkpoint file.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("ingest: checkpoint %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Checkpoint) matches(o *Checkpoint) bool {
+	if !strings.EqualFold(c.Table, o.Table) || c.BatchRows != o.BatchRows || len(c.Columns) != len(o.Columns) ||
+		c.InputSize != o.InputSize || c.InputSHA256 != o.InputSHA256 {
+		return false
+	}
+	for i := range c.Columns {
+		if !strings.EqualFold(c.Columns[i], o.Columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// write replaces the checkpoint file atomically.
+func (c *Checkpoint) write(path string) error {
+	c.UpdatedAt = time.Now().UTC()
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,554 @@
+// Package ingest bulk-loads CSV or JSON Lines into a table.
+//
+// Records are grouped into multi-row parameterized INSERTs and sent by a
+// pool of workers, optionally paced to a number of batches per second.
+// Each batch carries an idempotency key derived from its position and
+// contents, so rerunning an import over the same input replays batches
+// the server has already applied instead of inserting them twice.
+//
+//	res, err := ingest.FromFile(ctx, proj, "orders.csv", ingest.Options{
+//		Table:      "orders",
+//		Workers:    4,
+//		Rate:       20,
+//		Checkpoint: "orders.csv.checkpoint.json",
+//	})
+//
+// With Checkpoint set, progress is saved after every batch; a later run
+// with the same options over the same input file skips the batches
+// already recorded.
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/affinity"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Format is an input file format.
+type Format string
+
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+// FormatFromPath returns the format implied by a file extension, or ""
+// when the extension is not recognised.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSV
+	case ".jsonl", ".ndjson":
+		return JSONL
+	}
+	return ""
+}
+
+// Options configures an import.
+type Options struct {
+	Table  string
+	Format Format // default CSV; FromFile uses the file extension
+
+	// Columns maps source fields (CSV header names or JSON keys) to table
+	// columns. Fields not in the map, or mapped to "", are ignored. When
+	// nil, every source field must match a table column by name, ignoring
+	// case; for JSON Lines the first record's keys pick the columns and a
+	// later record with any other key is an error.
+	Columns map[string]string
+
+	// BatchRows is the number of rows per INSERT, capped so a batch stays
+	// within warlot.DefaultMaxParams parameters. Default: the cap.
+	BatchRows int
+	// Workers is the number of batches in flight; default 1.
+	Workers int
+	// Rate limits batches started per second across all workers; 0 means
+	// unlimited.
+	Rate float64
+	// Checkpoint is a file recording completed batches. It is removed
+	// when the import finishes.
+	Checkpoint string
+
+	CallOptions []warlot.CallOption // applied to every call
+}
+
+// Result summarises an import run.
+type Result struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Rows    int64    `json:"rows"`    // rows sent by this run
+	Batches int      `json:"batches"` // batches sent by this run
+	Skipped int      `json:"skipped"` // batches skipped from the checkpoint
+}
+
+// FromFile imports the file at path. The format defaults to the file
+// extension. With Checkpoint set, the file is hashed first and the
+// checkpoint only resumes a run over identical contents.
+func FromFile(ctx context.Context, p warlot.Project, path string, opts Options) (*Result, error) {
+	if opts.Format == "" {
+		opts.Format = FormatFromPath(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var in input
+	if opts.Checkpoint != "" {
+		h := sha256.New()
+		if in.size, err = io.Copy(h, f); err != nil {
+			return nil, err
+		}
+		in.sha256 = hex.EncodeToString(h.Sum(nil))
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return fromReader(ctx, p, f, opts, in)
+}
+
+// FromReader imports records read from r. On error the returned Result
+// counts the batches that were applied; with Checkpoint set, a rerun over
+// the same input resumes after the last batch completed in order. The
+// checkpoint cannot identify a reader's contents, so resuming with the
+// same input is up to the caller; FromFile checks it.
+func FromReader(ctx context.Context, p warlot.Project, r io.Reader, opts Options) (*Result, error) {
+	return fromReader(ctx, p, r, opts, input{})
+}
+
+// input identifies an input file for its checkpoint. It is zero for
+// readers.
+type input struct {
+	size   int64
+	sha256 string
+}
+
+func fromReader(ctx context.Context, p warlot.Project, r io.Reader, opts Options, in input) (*Result, error) {
+	if opts.Table == "" {
+		return nil, errors.New("ingest: Table is required")
+	}
+	if opts.Format == "" {
+		opts.Format = CSV
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	src, err := newSource(opts.Format, r)
+	if err != nil {
+		return nil, err
+	}
+	s, err := p.Schema(ctx, opts.Table, opts.CallOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("ingest: schema %s: %w", opts.Table, err)
+	}
+	m, err := newMapping(s, src.fields(), opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+	m.text = opts.Format == CSV
+	if opts.Columns == nil && opts.Format == JSONL {
+		m.known = map[string]bool{}
+		for _, f := range m.fields {
+			m.known[f] = true
+		}
+	}
+	maxRows := warlot.DefaultMaxParams / len(m.columns)
+	if opts.BatchRows <= 0 || opts.BatchRows > maxRows {
+		opts.BatchRows = maxRows
+	}
+
+	res := &Result{Table: opts.Table, Columns: m.columns}
+	ck := &Checkpoint{
+		Table:       opts.Table,
+		Columns:     m.columns,
+		BatchRows:   opts.BatchRows,
+		InputSize:   in.size,
+		InputSHA256: in.sha256,
+	}
+	if opts.Checkpoint != "" {
+		prev, err := ReadCheckpoint(opts.Checkpoint)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		case !prev.matches(ck):
+			return nil, fmt.Errorf("%w: %s", ErrCheckpointMismatch, opts.Checkpoint)
+		default:
+			ck = prev
+		}
+	}
+	res.Skipped = ck.Batches
+
+	run := &run{
+		p:      p,
+		opts:   opts,
+		m:      m,
+		skip:   ck.Batches,
+		ck:     ck,
+		res:    res,
+		done:   map[int]int{},
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteIdent(opts.Table), quoteList(m.columns)),
+	}
+	if opts.Rate > 0 {
+		run.pace = &pacer{interval: time.Duration(float64(time.Second) / opts.Rate)}
+	}
+	if err := run.start(ctx, src); err != nil {
+		return res, err
+	}
+	if opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// batch is one multi-row INSERT.
+type batch struct {
+	index  int
+	rows   int
+	params []any
+}
+
+type run struct {
+	p      warlot.Project
+	opts   Options
+	m      *mapping
+	insert string
+	pace   *pacer
+	skip   int // leading batches already applied
+
+	mu   sync.Mutex
+	ck   *Checkpoint
+	res  *Result
+	done map[int]int // rows of batches finished beyond the watermark
+}
+
+func (r *run) start(ctx context.Context, src source) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan batch)
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		errMu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		errMu.Unlock()
+	}
+	for i := 0; i < r.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				if err := r.send(ctx, b); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	err := r.read(ctx, src, batches)
+	close(batches)
+	wg.Wait()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return err
+}
+
+// read groups records into batches, skipping those the checkpoint
+// already records.
+func (r *run) read(ctx context.Context, src source, out chan<- batch) error {
+	cur := batch{}
+	record := 0
+	emit := func() error {
+		defer func() { cur = batch{index: cur.index + 1} }()
+		if cur.index < r.skip {
+			return nil
+		}
+		select {
+		case out <- cur:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for {
+		rec, err := src.next()
+		if err == io.EOF {
+			break
+		}
+		record++
+		if err != nil {
+			return fmt.Errorf("ingest: %w", err)
+		}
+		if cur.index >= r.skip {
+			row, err := r.m.row(rec)
+			if err != nil {
+				return fmt.Errorf("ingest: record %d: %w", record, err)
+			}
+			cur.params = append(cur.params, row...)
+		}
+		if cur.rows++; cur.rows == r.opts.BatchRows {
+			if err := emit(); err != nil {
+				return err
+			}
+		}
+	}
+	if cur.rows > 0 {
+		return emit()
+	}
+	return nil
+}
+
+// send executes one batch under its idempotency key and records it.
+func (r *run) send(ctx context.Context, b batch) error {
+	if err := r.pace.wait(ctx); err != nil {
+		return err
+	}
+	marks := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(r.m.columns)), ", ") + ")"
+	sql := r.insert + strings.TrimSuffix(strings.Repeat(marks+", ", b.rows), ", ")
+	req := warlot.SQLRequest{SQL: sql, Params: b.params}
+	key, err := batchKey(r.opts.Table, b.index, req)
+	if err != nil {
+		return err
+	}
+	opts := append(r.opts.CallOptions[:len(r.opts.CallOptions):len(r.opts.CallOptions)], warlot.WithIdempotencyKey(key))
+	if _, err := r.p.Client.ExecSQL(ctx, r.p.ID, req, opts...); err != nil {
+		return fmt.Errorf("ingest: batch %d: %w", b.index, err)
+	}
+	return r.complete(b)
+}
+
+// complete counts a finished batch and advances the checkpoint past every
+// batch now finished in order.
+func (r *run) complete(b batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.res.Rows += int64(b.rows)
+	r.res.Batches++
+	r.done[b.index] = b.rows
+	advanced := false
+	for {
+		n, ok := r.done[r.ck.Batches]
+		if !ok {
+			break
+		}
+		delete(r.done, r.ck.Batches)
+		r.ck.Batches++
+		r.ck.Rows += int64(n)
+		advanced = true
+	}
+	if !advanced || r.opts.Checkpoint == "" {
+		return nil
+	}
+	return r.ck.write(r.opts.Checkpoint)
+}
+
+// batchKey derives a stable idempotency key from the batch position and
+// request, so the same input yields the same keys on every run.
+func batchKey(table string, index int, req warlot.SQLRequest) (string, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("ingest: batch %d: %w", index, err)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00", table, index)
+	h.Write(b)
+	return "import-" + hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// mapping turns source records into parameter rows.
+type mapping struct {
+	fields  []string // source field per column
+	columns []string // table columns, in schema order
+	types   []string // declared types
+	text    bool     // values are CSV text
+
+	// known holds every source field when records must not carry fields
+	// outside the mapping; nil otherwise.
+	known map[string]bool
+}
+
+func newMapping(s *warlot.TableSchema, fields []string, explicit map[string]string) (*mapping, error) {
+	if len(s.Columns) == 0 {
+		return nil, fmt.Errorf("ingest: table %s has no columns", s.Name)
+	}
+	byColumn := map[string]string{} // lower-case column → source field
+	if explicit != nil {
+		for f, c := range explicit {
+			if c != "" {
+				byColumn[strings.ToLower(c)] = f
+			}
+		}
+	} else {
+		var unknown []string
+		for _, f := range fields {
+			if _, ok := s.Column(f); !ok {
+				unknown = append(unknown, f)
+				continue
+			}
+			byColumn[strings.ToLower(f)] = f
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("ingest: fields %s match no column of %s; map them with Options.Columns", strings.Join(unknown, ", "), s.Name)
+		}
+	}
+
+	m := &mapping{}
+	for _, c := range s.Columns {
+		f, ok := byColumn[strings.ToLower(c.Name)]
+		if !ok {
+			continue
+		}
+		delete(byColumn, strings.ToLower(c.Name))
+		m.fields = append(m.fields, f)
+		m.columns = append(m.columns, c.Name)
+		m.types = append(m.types, c.Type)
+	}
+	if len(byColumn) > 0 {
+		var missing []string
+		for c := range byColumn {
+			missing = append(missing, c)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("ingest: table %s has no columns %s", s.Name, strings.Join(missing, ", "))
+	}
+	if len(m.columns) == 0 {
+		return nil, errors.New("ingest: no source fields map to table columns")
+	}
+	return m, nil
+}
+
+func (m *mapping) row(rec map[string]any) ([]any, error) {
+	if m.known != nil {
+		var unknown []string
+		for f := range rec {
+			if !m.known[f] {
+				unknown = append(unknown, f)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("fields %s are not in the first record; list every field there or map them with Options.Columns", strings.Join(unknown, ", "))
+		}
+	}
+	out := make([]any, len(m.fields))
+	for i, f := range m.fields {
+		v, err := param(m.types[i], rec[f], m.text)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// param converts a source value for a column of declType. CSV values
+// arrive as text: empty means NULL, and numbers are parsed by column
+// affinity, and "true" or "false" for BOOLEAN columns. Booleans are sent
+// as 1 or 0, as SQLite stores them. Other JSON scalars are sent unchanged;
+// nested JSON values are sent as JSON text.
+func param(declType string, v any, text bool) (any, error) {
+	if b, ok := v.(bool); ok {
+		if b {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	switch x := v.(type) {
+	case nil, json.Number:
+		return x, nil
+	case string:
+		if !text {
+			return x, nil
+		}
+		if x == "" {
+			return nil, nil
+		}
+		if affinity.IsBool(declType) {
+			if b, err := strconv.ParseBool(x); err == nil {
+				return param(declType, b, text)
+			}
+			return x, nil
+		}
+		switch affinity.GoType(declType, false) {
+		case "int64":
+			if n, err := strconv.ParseInt(x, 10, 64); err == nil {
+				return n, nil
+			}
+		case "float64":
+			if f, err := strconv.ParseFloat(x, 64); err == nil {
+				return f, nil
+			}
+		}
+		return x, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// pacer spaces calls at least interval apart across goroutines. A nil
+// pacer never waits.
+type pacer struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func (p *pacer) wait(ctx context.Context) error {
+	if p == nil {
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	at := p.next
+	p.next = p.next.Add(p.interval)
+	p.mu.Unlock()
+
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func quoteIdent(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+
+func quoteList(cols []string) string {
+	q := make([]string, len(cols))
+	for i, c := range cols {
+		q[i] = quoteIdent(c)
+	}
+	return strings.Join(q, ", ")
+}
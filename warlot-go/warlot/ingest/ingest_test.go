@@ -0,0 +1,227 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/warlottest"
+)
+
+func setup(t *testing.T) (context.Context, warlot.Project) {
+	t.Helper()
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	id, err := srv.CreateProject("0xholder", "ingest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj := srv.Client().Project(id)
+	_, err = proj.SQL(ctx, `CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT NOT NULL, qty INT, price REAL, meta TEXT)`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx, proj
+}
+
+func eventsCSV(n, badRow int) string {
+	var b strings.Builder
+	b.WriteString("Name,qty,price\n")
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("e%d", i)
+		if i == badRow {
+			name = ""
+		}
+		qty := fmt.Sprint(i)
+		if i%7 == 0 {
+			qty = ""
+		}
+		fmt.Fprintf(&b, "%s,%s,%d.5\n", name, qty, i)
+	}
+	return b.String()
+}
+
+type event struct {
+	Name  string  `json:"name"`
+	Qty   *int64  `json:"qty"`
+	Price float64 `json:"price"`
+	Meta  *string `json:"meta"`
+}
+
+func events(t *testing.T, ctx context.Context, p warlot.Project) []event {
+	t.Helper()
+	rows, err := warlot.Query[event](ctx, p, `SELECT name, qty, price, meta FROM events ORDER BY price`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestFromReader_CSV(t *testing.T) {
+	ctx, proj := setup(t)
+	res, err := FromReader(ctx, proj, strings.NewReader(eventsCSV(100, 0)), Options{
+		Table:     "events",
+		BatchRows: 9,
+		Workers:   4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 100 || res.Batches != 12 || strings.Join(res.Columns, ",") != "name,qty,price" {
+		t.Fatalf("result: %+v", res)
+	}
+	got := events(t, ctx, proj)
+	if len(got) != 100 || got[6].Qty != nil || *got[7].Qty != 8 || got[99].Name != "e100" || got[99].Price != 100.5 {
+		t.Fatalf("rows: %d, %+v", len(got), got[6:8])
+	}
+
+	// Rerunning the same input replays every batch by idempotency key.
+	if _, err := FromReader(ctx, proj, strings.NewReader(eventsCSV(100, 0)), Options{Table: "events", BatchRows: 9}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(events(t, ctx, proj)); n != 100 {
+		t.Fatalf("rerun inserted duplicates: %d rows", n)
+	}
+}
+
+func TestFromReader_JSONLMapping(t *testing.T) {
+	ctx, proj := setup(t)
+	in := `{"title":"a","amount":1.5,"extra":{"k":[1,2]},"ignored":true}
+{"title":"b","amount":2.5,"extra":null}
+`
+	res, err := FromReader(ctx, proj, strings.NewReader(in), Options{
+		Table:   "events",
+		Format:  JSONL,
+		Columns: map[string]string{"title": "name", "amount": "price", "extra": "meta", "ignored": ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := events(t, ctx, proj)
+	if res.Rows != 2 || len(got) != 2 || got[0].Name != "a" || *got[0].Meta != `{"k":[1,2]}` || got[1].Meta != nil {
+		t.Fatalf("res=%+v rows=%+v", res, got)
+	}
+
+	_, err = FromReader(ctx, proj, strings.NewReader(in), Options{Table: "events", Format: JSONL})
+	if err == nil || !strings.Contains(err.Error(), "amount, extra, ignored, title match no column") {
+		t.Fatalf("inferred mapping error: %v", err)
+	}
+
+	// A key missing from the first record is not dropped silently, even
+	// when it names a column.
+	in = `{"name":"c","price":3.5}
+{"name":"d","price":4.5,"meta":"m","bogus":1}
+`
+	_, err = FromReader(ctx, proj, strings.NewReader(in), Options{Table: "events", Format: JSONL})
+	if err == nil || !strings.Contains(err.Error(), "record 2: fields bogus, meta are not in the first record") {
+		t.Fatalf("later unknown keys: %v", err)
+	}
+}
+
+func TestFromFile_Resume(t *testing.T) {
+	ctx, proj := setup(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.csv")
+	ckpt := path + ".checkpoint.json"
+	opts := Options{Table: "events", BatchRows: 10, Checkpoint: ckpt}
+
+	// The batch holding e34 fails with a 503 that never reaches the
+	// server, so its idempotency key stays unused.
+	flaky := proj
+	flaky.Client = warlot.New(
+		warlot.WithBaseURL(proj.Client.BaseURL),
+		warlot.WithRetries(0),
+		warlot.WithHTTPClient(&http.Client{Transport: unavailableFor{`"e34"`}}),
+	)
+	os.WriteFile(path, []byte(eventsCSV(55, 0)), 0o644)
+	res, err := FromFile(ctx, flaky, path, opts)
+	var apiErr *warlot.APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "batch 3") {
+		t.Fatalf("err = %v", err)
+	}
+	if res.Rows != 30 {
+		t.Fatalf("rows before failure: %d", res.Rows)
+	}
+	ck, err := ReadCheckpoint(ckpt)
+	if err != nil || ck.Batches != 3 || ck.Rows != 30 || ck.InputSize != int64(len(eventsCSV(55, 0))) || len(ck.InputSHA256) != 64 {
+		t.Fatalf("checkpoint: %+v err=%v", ck, err)
+	}
+
+	if _, err := FromFile(ctx, proj, path, Options{Table: "events", BatchRows: 5, Checkpoint: ckpt}); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("mismatched options: %v", err)
+	}
+
+	os.WriteFile(path, []byte(eventsCSV(56, 0)), 0o644)
+	if _, err := FromFile(ctx, proj, path, opts); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("changed input: %v", err)
+	}
+
+	os.WriteFile(path, []byte(eventsCSV(55, 0)), 0o644)
+	res, err = FromFile(ctx, proj, path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Skipped != 3 || res.Rows != 25 || res.Batches != 3 {
+		t.Fatalf("resume: %+v", res)
+	}
+	if n := len(events(t, ctx, proj)); n != 55 {
+		t.Fatalf("rows after resume: %d", n)
+	}
+	if _, err := os.Stat(ckpt); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint not removed: %v", err)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	p := &pacer{interval: 20 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := p.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Fatalf("5 calls took %v", d)
+	}
+	var none *pacer
+	if err := none.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// unavailableFor answers 503 to requests whose body contains match and
+// forwards the rest.
+type unavailableFor struct{ match string }
+
+func (u unavailableFor) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body == nil {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(body, []byte(u.match)) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"unavailable"}`)),
+			Request:    r,
+		}, nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return http.DefaultTransport.RoundTrip(r)
+}
@@ -0,0 +1,137 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// source reads records keyed by source field name.
+type source interface {
+	// fields returns the field names known before the first record: the
+	// CSV header, or the keys of the first JSON object.
+	fields() []string
+	// next returns the next record, or io.EOF.
+	next() (map[string]any, error)
+}
+
+func newSource(f Format, r io.Reader) (source, error) {
+	switch f {
+	case CSV:
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil, errors.New("ingest: CSV input has no header row")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &csvSource{r: cr, header: header}, nil
+	case JSONL:
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		s := &jsonlSource{dec: dec}
+		if err := s.peek(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("ingest: unknown format %q", f)
+}
+
+type csvSource struct {
+	r      *csv.Reader
+	header []string
+}
+
+func (s *csvSource) fields() []string { return s.header }
+
+func (s *csvSource) next() (map[string]any, error) {
+	rec, err := s.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any, len(rec))
+	for i, v := range rec {
+		m[s.header[i]] = v
+	}
+	return m, nil
+}
+
+// jsonlSource reads a stream of JSON objects, one per line by convention.
+// Numbers are kept as json.Number so integers survive unchanged.
+type jsonlSource struct {
+	dec   *json.Decoder
+	first map[string]any
+	keys  []string
+	n     int
+}
+
+// peek decodes the first object and records its keys in document order.
+func (s *jsonlSource) peek() error {
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		return err
+	}
+	s.n++
+	keys, err := objectKeys(raw)
+	if err != nil {
+		return fmt.Errorf("record 1: %w", err)
+	}
+	if err := decodeObject(raw, &s.first); err != nil {
+		return fmt.Errorf("record 1: %w", err)
+	}
+	s.keys = keys
+	return nil
+}
+
+func (s *jsonlSource) fields() []string { return s.keys }
+
+func (s *jsonlSource) next() (map[string]any, error) {
+	if s.first != nil {
+		m := s.first
+		s.first = nil
+		return m, nil
+	}
+	var m map[string]any
+	if err := s.dec.Decode(&m); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("record %d: %w", s.n+1, err)
+	}
+	s.n++
+	if m == nil {
+		return nil, fmt.Errorf("record %d: not a JSON object", s.n)
+	}
+	return m, nil
+}
+
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func decodeObject(raw json.RawMessage, m *map[string]any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(m)
+}
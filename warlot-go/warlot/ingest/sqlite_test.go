@@ -0,0 +1,5 @@
+//go:build cgo
+
+package ingest
+
+import _ "github.com/mattn/go-sqlite3" // registers the driver warlottest opens
@@ -0,0 +1,108 @@
+//go:build go1.23
+
+package warlot
+
+import (
+	"context"
+	"iter"
+)
+
+// QueryIter streams a SELECT through ExecSQLStream and yields each row
+// decoded into T, mapping columns the same way as Query. The request is
+// sent when the loop starts, and the response body is closed when the
+// rows run out or the loop exits early.
+//
+//	for p, err := range warlot.QueryIter[Product](ctx, proj, "SELECT * FROM products", nil) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// An error is yielded at most once and ends the sequence.
+func QueryIter[T any](ctx context.Context, p Project, sql string, params []any, opts ...CallOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		sc, err := p.Client.ExecSQLStream(ctx, p.ID, SQLRequest{SQL: sql, Params: params}, opts...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer sc.Close()
+		for {
+			var t T
+			if !sc.Next(&t) {
+				break
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// All yields the remaining rows one at a time, fetching pages with Next
+// as the loop advances. Offset moves a page at a time, so rows left in
+// the current page when the loop exits early are skipped by a later
+// Next. An error is yielded at most once and ends the sequence.
+func (p *Pager) All(ctx context.Context) iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		for {
+			rows, err := p.Next(ctx)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if rows == nil {
+				return
+			}
+			for _, row := range rows {
+				if !yield(row, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// BrowseAll yields every row of table, paging through BrowseRows with the
+// server's default page size.
+//
+//	for row, err := range proj.BrowseAll(ctx, "products") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (p Project) BrowseAll(ctx context.Context, table string, opts ...CallOption) iter.Seq2[map[string]any, error] {
+	pg := &Pager{Project: p, Table: table, Options: opts}
+	return pg.All(ctx)
+}
+
+// All yields the remaining rows one at a time in key order, fetching pages
+// with Next as the loop advances. Cursor points at the end of the last
+// page fetched, so rows left in that page when the loop exits early are
+// skipped on resume. An error is yielded at most once and ends the
+// sequence.
+func (p *KeysetPager) All(ctx context.Context) iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		for {
+			rows, err := p.Next(ctx)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if rows == nil {
+				return
+			}
+			for _, row := range rows {
+				if !yield(row, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+//go:build go1.23
+
+package warlot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeTracker counts response bodies closed by the client.
+type closeTracker struct {
+	rt     http.RoundTripper
+	closed atomic.Int32
+}
+
+func (c *closeTracker) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := c.rt.RoundTrip(r)
+	if err == nil {
+		res.Body = &trackedBody{ReadCloser: res.Body, closed: &c.closed}
+	}
+	return res, err
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	closed *atomic.Int32
+}
+
+func (b *trackedBody) Close() error {
+	b.closed.Add(1)
+	return b.ReadCloser.Close()
+}
+
+func TestQueryIter(t *testing.T) {
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(b), "bad") {
+			http.Error(w, `{"error":"no such table: bad"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":true,"rows":[`)
+		for i := 1; i <= 100; i++ {
+			if i > 1 {
+				io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":%d,"name":"n%d"}`, i, i)
+		}
+		io.WriteString(w, `]}`)
+	})
+	defer srv.Close()
+	tracker := &closeTracker{rt: http.DefaultTransport}
+	cl.HTTPClient = &http.Client{Transport: tracker}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	type row struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	var got []row
+	for r, err := range QueryIter[row](ctx, proj, "SELECT id, name FROM t", nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if len(got) != 3 || got[2].ID != 3 || got[2].Name != "n3" {
+		t.Fatalf("rows: %+v", got)
+	}
+	if n := tracker.closed.Load(); n != 1 {
+		t.Fatalf("body closed %d times after early break, want 1", n)
+	}
+
+	n := 0
+	for _, err := range QueryIter[map[string]any](ctx, proj, "SELECT * FROM t", nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Fatalf("full iteration: %d rows", n)
+	}
+
+	var errs int
+	for _, err := range QueryIter[row](ctx, proj, "SELECT * FROM bad", nil) {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("err = %v", err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Fatalf("errors yielded: %d", errs)
+	}
+}
+
+func TestBrowseAll(t *testing.T) {
+	var requests atomic.Int32
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if r.Header.Get("X-Trace") != "on" {
+			t.Errorf("missing call option header")
+		}
+		var rows []string
+		for i := offset; i < offset+4 && i < 10; i++ {
+			rows = append(rows, fmt.Sprintf(`{"id":%d}`, i))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"limit":4,"offset":%d,"table":"t","rows":[%s]}`, offset, strings.Join(rows, ","))
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	var ids []string
+	for row, err := range proj.BrowseAll(ctx, "t", WithHeader("X-Trace", "on")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, fmt.Sprint(row["id"]))
+	}
+	if strings.Join(ids, ",") != "0,1,2,3,4,5,6,7,8,9" || requests.Load() != 4 {
+		t.Fatalf("ids=%v requests=%d", ids, requests.Load())
+	}
+
+	requests.Store(0)
+	for range proj.BrowseAll(ctx, "t", WithHeader("X-Trace", "on")) {
+		break
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("early break made %d requests", requests.Load())
+	}
+}
@@ -0,0 +1,192 @@
+package warlot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCursorMismatch is returned when a KeysetPager cursor was issued for a
+// different table, key or direction.
+var ErrCursorMismatch = errors.New("warlot: cursor does not match table, key and direction")
+
+const defaultKeysetLimit = 1000
+
+// KeysetPager iterates through table rows in key order using
+//
+//	SELECT ... FROM table WHERE key > ? ORDER BY key LIMIT n
+//
+// through ExecSQL. Unlike Pager, pages stay fast deep into large tables,
+// and rows inserted or deleted during iteration do not shift later pages.
+//
+// Keys must together be unique and non-null; they default to the table's
+// primary key. After each page Cursor holds an opaque token for the last
+// row returned. Saving it and setting it on a new pager resumes right
+// after that row, for example after a crash.
+type KeysetPager struct {
+	Project Project
+	Table   string
+	Keys    []string // ordering columns; default: the primary key
+	Columns []string // selected columns; default "*"; must include Keys
+	Desc    bool     // iterate in descending key order
+	Limit   int      // page size; default 1000
+	Cursor  string   // resume after this position; "" starts at the beginning
+	Done    bool
+	Options []CallOption // applied to every call
+}
+
+// keysetCursor is the decoded form of KeysetPager.Cursor.
+type keysetCursor struct {
+	Table  string   `json:"t"`
+	Keys   []string `json:"k"`
+	Values []any    `json:"v"`
+	Desc   bool     `json:"d,omitempty"`
+}
+
+// Next returns the next page of rows, or nil when iteration finishes. A
+// page shorter than Limit is the last one.
+func (p *KeysetPager) Next(ctx context.Context) ([]map[string]any, error) {
+	if p.Done {
+		return nil, nil
+	}
+	if len(p.Keys) == 0 {
+		s, err := p.Project.Schema(ctx, p.Table, p.Options...)
+		if err != nil {
+			return nil, err
+		}
+		if p.Keys = s.PrimaryKey(); len(p.Keys) == 0 {
+			return nil, fmt.Errorf("warlot: table %s has no primary key; set KeysetPager.Keys", p.Table)
+		}
+	}
+	var after []any
+	if p.Cursor != "" {
+		c, err := decodeKeysetCursor(p.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(c.Table, p.Table) || !equalFoldAll(c.Keys, p.Keys) || len(c.Values) != len(p.Keys) || c.Desc != p.Desc {
+			return nil, ErrCursorMismatch
+		}
+		after = c.Values
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = defaultKeysetLimit
+	}
+
+	sql, params := p.query(after, limit)
+	res, err := p.Project.SQL(ctx, sql, params, p.Options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) < limit {
+		p.Done = true
+	}
+	if len(res.Rows) == 0 {
+		return nil, nil
+	}
+	last := res.Rows[len(res.Rows)-1]
+	values := make([]any, len(p.Keys))
+	for i, k := range p.Keys {
+		v, ok := lookupFold(last, k)
+		if !ok {
+			return nil, fmt.Errorf("warlot: key column %s missing from selected columns", k)
+		}
+		if v == nil {
+			return nil, fmt.Errorf("warlot: key column %s is NULL; keyset keys must be non-null", k)
+		}
+		values[i] = v
+	}
+	if p.Cursor, err = encodeKeysetCursor(keysetCursor{Table: p.Table, Keys: p.Keys, Values: values, Desc: p.Desc}); err != nil {
+		return nil, err
+	}
+	return res.Rows, nil
+}
+
+func (p *KeysetPager) query(after []any, limit int) (string, []any) {
+	cols := "*"
+	if len(p.Columns) > 0 {
+		q := make([]string, len(p.Columns))
+		for i, c := range p.Columns {
+			q[i] = quoteIdent(c)
+		}
+		cols = strings.Join(q, ", ")
+	}
+	keys := make([]string, len(p.Keys))
+	order := make([]string, len(p.Keys))
+	dir, op := "", ">"
+	if p.Desc {
+		dir, op = " DESC", "<"
+	}
+	for i, k := range p.Keys {
+		keys[i] = quoteIdent(k)
+		order[i] = keys[i] + dir
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", cols, quoteIdent(p.Table))
+	var params []any
+	if after != nil {
+		if len(keys) == 1 {
+			fmt.Fprintf(&b, " WHERE %s %s ?", keys[0], op)
+		} else {
+			marks := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+			fmt.Fprintf(&b, " WHERE (%s) %s (%s)", strings.Join(keys, ", "), op, marks)
+		}
+		params = append(params, after...)
+	}
+	fmt.Fprintf(&b, " ORDER BY %s LIMIT ?", strings.Join(order, ", "))
+	return b.String(), append(params, limit)
+}
+
+func encodeKeysetCursor(c keysetCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("warlot: encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeKeysetCursor(s string) (keysetCursor, error) {
+	var c keysetCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		err = dec.Decode(&c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("warlot: invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+// lookupFold returns the value of column name in row, matching
+// case-insensitively when there is no exact match.
+func lookupFold(row map[string]any, name string) (any, bool) {
+	if v, ok := row[name]; ok {
+		return v, true
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func equalFoldAll(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package warlot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeysetPager(t *testing.T) {
+	var sqls []string
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/schema") {
+			fmt.Fprint(w, `{"name":"items","columns":[{"name":"shop","type":"TEXT","pk":1},{"name":"id","type":"INTEGER","pk":2}]}`)
+			return
+		}
+		var req struct {
+			SQL    string        `json:"sql"`
+			Params []json.Number `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		sqls = append(sqls, req.SQL)
+		// Ten rows (shop "a", id 0..9); the cursor's id is the second param.
+		from := int64(0)
+		if len(req.Params) == 3 {
+			n, _ := req.Params[1].Int64()
+			from = n + 1
+		}
+		limit, _ := req.Params[len(req.Params)-1].Int64()
+		var rows []string
+		for i := from; i < 10 && i < from+limit; i++ {
+			rows = append(rows, fmt.Sprintf(`{"shop":"a","id":%d}`, i))
+		}
+		fmt.Fprintf(w, `{"ok":true,"rows":[%s]}`, strings.Join(rows, ","))
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	pg := &KeysetPager{Project: proj, Table: "items", Limit: 4}
+	first, err := pg.Next(ctx)
+	if err != nil || len(first) != 4 {
+		t.Fatalf("first page: %v err=%v", first, err)
+	}
+	if want := `SELECT * FROM "items" ORDER BY "shop", "id" LIMIT ?`; sqls[0] != want {
+		t.Fatalf("sql = %s", sqls[0])
+	}
+	saved := pg.Cursor
+
+	// Resume from the saved cursor with a fresh pager.
+	resumed := &KeysetPager{Project: proj, Table: "items", Keys: []string{"shop", "id"}, Limit: 4, Cursor: saved}
+	var ids []string
+	for {
+		rows, err := resumed.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows == nil {
+			break
+		}
+		for _, r := range rows {
+			ids = append(ids, fmt.Sprint(r["id"]))
+		}
+	}
+	if strings.Join(ids, ",") != "4,5,6,7,8,9" || !resumed.Done {
+		t.Fatalf("resumed ids = %v", ids)
+	}
+	if want := `SELECT * FROM "items" WHERE ("shop", "id") > (?, ?) ORDER BY "shop", "id" LIMIT ?`; sqls[1] != want {
+		t.Fatalf("sql = %s", sqls[1])
+	}
+	if n := len(sqls); n != 3 {
+		t.Fatalf("short page should end iteration; %d queries", n)
+	}
+
+	single := &KeysetPager{Project: proj, Table: "items", Keys: []string{"id"}, Columns: []string{"id"}, Desc: true}
+	if q, _ := single.query([]any{5}, 10); q != `SELECT "id" FROM "items" WHERE "id" < ? ORDER BY "id" DESC LIMIT ?` {
+		t.Fatalf("desc sql = %s", q)
+	}
+
+	other := &KeysetPager{Project: proj, Table: "orders", Keys: []string{"shop", "id"}, Cursor: saved}
+	if _, err := other.Next(ctx); !errors.Is(err, ErrCursorMismatch) {
+		t.Fatalf("mismatch err = %v", err)
+	}
+	reversed := &KeysetPager{Project: proj, Table: "items", Keys: []string{"shop", "id"}, Desc: true, Cursor: saved}
+	if _, err := reversed.Next(ctx); !errors.Is(err, ErrCursorMismatch) {
+		t.Fatalf("direction mismatch err = %v", err)
+	}
+	bad := &KeysetPager{Project: proj, Table: "items", Keys: []string{"id"}, Cursor: "!!"}
+	if _, err := bad.Next(ctx); err == nil || !strings.Contains(err.Error(), "invalid cursor") {
+		t.Fatalf("bad cursor err = %v", err)
+	}
+	missing := &KeysetPager{Project: proj, Table: "items", Keys: []string{"sku"}}
+	if _, err := missing.Next(ctx); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("missing key err = %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+package warlot
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrNoPrimaryKey is returned by Update, Upsert and Delete for a struct
+// without a field tagged pk.
+var ErrNoPrimaryKey = errors.New("warlot: struct has no pk field")
+
+// DefaultMaxParams bounds the parameters in one bulk INSERT. It matches
+// SQLite's historical SQLITE_MAX_VARIABLE_NUMBER.
+const DefaultMaxParams = 999
+
+// Tabler is implemented by structs that name their table explicitly.
+type Tabler interface {
+	TableName() string
+}
+
+// WithMaxParams sets the parameter limit used to chunk InsertAll and
+// UpsertAll into multi-row statements.
+func WithMaxParams(n int) CallOption {
+	return func(co *callOptions) { co.maxParams = n }
+}
+
+// Insert inserts v into its table. Columns come from `warlot:"col"` tags:
+//
+//	type Product struct {
+//		ID    int64   `warlot:"id,pk,omitempty"`
+//		SKU   string  `warlot:"sku"`
+//		Price float64 `warlot:"price"`
+//	}
+//
+// Options after the column name are pk (part of the primary key) and
+// omitempty (left out of INSERT when zero, so defaults and rowid
+// assignment apply). Fields without a warlot tag, or tagged "-", are
+// ignored. The table is TableName() when T implements Tabler, else the
+// tag of a blank field (`_ struct{} warlot:"products"`), else the type
+// name in snake_case. A row whose columns are all omitted is inserted
+// with DEFAULT VALUES.
+//
+// Only writes use warlot tags. Query[T] and the other readers decode rows
+// with encoding/json, so a struct used for both needs a json tag naming
+// the same column as its warlot tag.
+func Insert[T any](ctx context.Context, p Project, v T, opts ...CallOption) (*SQLResponse, error) {
+	m, err := mappingOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	req, _, err := m.insertChunk([]reflect.Value{reflect.ValueOf(v)}, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.ExecSQL(ctx, p.ID, req, opts...)
+}
+
+// Upsert inserts v or, when its primary key exists, updates the other
+// columns with ON CONFLICT ... DO UPDATE.
+func Upsert[T any](ctx context.Context, p Project, v T, opts ...CallOption) (*SQLResponse, error) {
+	m, err := mappingOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.pk) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+	req, _, err := m.insertChunk([]reflect.Value{reflect.ValueOf(v)}, true, 0)
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.ExecSQL(ctx, p.ID, req, opts...)
+}
+
+// Update sets every non-key column of v on the row matching its primary
+// key.
+func Update[T any](ctx context.Context, p Project, v T, opts ...CallOption) (*SQLResponse, error) {
+	m, err := mappingOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.pk) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+	rv := reflect.ValueOf(v)
+	var sb strings.Builder
+	var params []any
+	sb.WriteString("UPDATE " + quoteIdent(m.table) + " SET ")
+	n := 0
+	for _, f := range m.fields {
+		if f.pk {
+			continue
+		}
+		if n > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(f.column) + " = ?")
+		v, err := f.value(rv)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, v)
+		n++
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("warlot: %s has no non-key columns to update", m.table)
+	}
+	params, err = m.wherePK(&sb, rv, params)
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.ExecSQL(ctx, p.ID, SQLRequest{SQL: sb.String(), Params: params}, opts...)
+}
+
+// Delete removes the row matching the primary key of v.
+func Delete[T any](ctx context.Context, p Project, v T, opts ...CallOption) (*SQLResponse, error) {
+	m, err := mappingOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.pk) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+	var sb strings.Builder
+	sb.WriteString("DELETE FROM " + quoteIdent(m.table))
+	params, err := m.wherePK(&sb, reflect.ValueOf(v), nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.ExecSQL(ctx, p.ID, SQLRequest{SQL: sb.String(), Params: params}, opts...)
+}
+
+// InsertAll inserts vs with multi-row INSERTs, each holding at most
+// WithMaxParams parameters (DefaultMaxParams by default). Chunks are sent
+// as separate requests; on error the rows written by earlier chunks are
+// reported. Each chunk carries its own idempotency key, derived from one
+// supplied with WithIdempotencyKey or generated.
+func InsertAll[T any](ctx context.Context, p Project, vs []T, opts ...CallOption) (int, error) {
+	return writeAll(ctx, p, vs, false, opts)
+}
+
+// UpsertAll is the bulk form of Upsert, chunked like InsertAll.
+func UpsertAll[T any](ctx context.Context, p Project, vs []T, opts ...CallOption) (int, error) {
+	return writeAll(ctx, p, vs, true, opts)
+}
+
+func writeAll[T any](ctx context.Context, p Project, vs []T, upsert bool, opts []CallOption) (int, error) {
+	m, err := mappingOf[T]()
+	if err != nil {
+		return 0, err
+	}
+	if upsert && len(m.pk) == 0 {
+		return 0, ErrNoPrimaryKey
+	}
+	co := &callOptions{}
+	for _, o := range opts {
+		o(co)
+	}
+	maxParams := co.maxParams
+	if maxParams <= 0 {
+		maxParams = DefaultMaxParams
+	}
+	key := buildHeaders(nil, opts...).Get("x-idempotency-key")
+	if key == "" {
+		key = "bulk-" + newIdempotencyKey()
+	}
+
+	rows := make([]reflect.Value, len(vs))
+	for i := range vs {
+		rows[i] = reflect.ValueOf(vs[i])
+	}
+	written := 0
+	for chunk := 0; len(rows) > 0; chunk++ {
+		req, n, err := m.insertChunk(rows, upsert, maxParams)
+		if err != nil {
+			return written, err
+		}
+		copts := append(opts[:len(opts):len(opts)], WithIdempotencyKey(fmt.Sprintf("%s-%d", key, chunk)))
+		if _, err := p.Client.ExecSQL(ctx, p.ID, req, copts...); err != nil {
+			return written, err
+		}
+		written += n
+		rows = rows[n:]
+	}
+	return written, nil
+}
+
+// ---- mapping ----
+
+type fieldMap struct {
+	column    string
+	index     []int
+	pk        bool
+	omitEmpty bool
+}
+
+// value returns the field of rv as a request parameter. driver.Valuer
+// implementations are honored; nil pointers become NULL.
+func (f fieldMap) value(rv reflect.Value) (any, error) {
+	fv, ok := fieldByIndex(rv, f.index)
+	if !ok {
+		return nil, nil
+	}
+	for {
+		if fv.Kind() == reflect.Pointer && fv.IsNil() {
+			return nil, nil
+		}
+		if vr, ok := fv.Interface().(driver.Valuer); ok {
+			v, err := vr.Value()
+			if err != nil {
+				return nil, fmt.Errorf("warlot: column %s: %w", f.column, err)
+			}
+			return v, nil
+		}
+		if fv.Kind() != reflect.Pointer {
+			return fv.Interface(), nil
+		}
+		fv = fv.Elem()
+	}
+}
+
+func (f fieldMap) omitted(rv reflect.Value) bool {
+	if !f.omitEmpty {
+		return false
+	}
+	fv, ok := fieldByIndex(rv, f.index)
+	return !ok || fv.IsZero()
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex that tolerates nil embedded
+// pointers.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}, false
+		}
+		rv = rv.Elem()
+	}
+	for i, x := range index {
+		if i > 0 {
+			for rv.Kind() == reflect.Pointer {
+				if rv.IsNil() {
+					return reflect.Value{}, false
+				}
+				rv = rv.Elem()
+			}
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+type mapping struct {
+	table  string
+	fields []fieldMap
+	pk     []fieldMap
+}
+
+var mappings sync.Map // reflect.Type -> *mapping
+
+func mappingOf[T any]() (*mapping, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if m, ok := mappings.Load(t); ok {
+		return m.(*mapping), nil
+	}
+	st := t
+	for st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("warlot: %s is not a struct", t)
+	}
+	m := &mapping{}
+	collectFields(st, nil, m)
+	if len(m.fields) == 0 {
+		return nil, fmt.Errorf("warlot: %s has no warlot-tagged fields", t)
+	}
+	if tb, ok := reflect.New(st).Interface().(Tabler); ok {
+		m.table = tb.TableName()
+	}
+	if m.table == "" {
+		m.table = snakeCase(st.Name())
+	}
+	for _, f := range m.fields {
+		if f.pk {
+			m.pk = append(m.pk, f)
+		}
+	}
+	mappings.Store(t, m)
+	return m, nil
+}
+
+func collectFields(t reflect.Type, index []int, m *mapping) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("warlot")
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Name == "_" {
+			if tagged && m.table == "" {
+				m.table = name
+			}
+			continue
+		}
+		idx := append(index[:len(index):len(index)], i)
+		if sf.Anonymous && !tagged {
+			// Like encoding/json, promote fields of embedded structs, but
+			// not through unexported pointers, which reflect cannot read.
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer && sf.IsExported() {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, idx, m)
+			}
+			continue
+		}
+		if !tagged || name == "-" || !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+		f := fieldMap{column: name, index: idx}
+		for _, o := range strings.Split(opts, ",") {
+			switch strings.TrimSpace(o) {
+			case "pk":
+				f.pk = true
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+		m.fields = append(m.fields, f)
+	}
+}
+
+// insertChunk renders an INSERT for the longest prefix of rows that omit
+// the same columns and fit in maxParams parameters (0 means unlimited).
+// It returns the request and the number of rows it covers; at least one
+// row is always included. A row with no columns to write becomes
+// INSERT ... DEFAULT VALUES, which covers that row alone; it has no
+// conflict clause, since every key column was left to its default.
+func (m *mapping) insertChunk(rows []reflect.Value, upsert bool, maxParams int) (SQLRequest, int, error) {
+	cols := m.columnsFor(rows[0])
+	if len(cols) == 0 {
+		return SQLRequest{SQL: "INSERT INTO " + quoteIdent(m.table) + " DEFAULT VALUES"}, 1, nil
+	}
+	n := 1
+	for n < len(rows) {
+		if maxParams > 0 && (n+1)*len(cols) > maxParams {
+			break
+		}
+		if !sameFields(cols, m.columnsFor(rows[n])) {
+			break
+		}
+		n++
+	}
+
+	var sb strings.Builder
+	var params []any
+	sb.WriteString("INSERT INTO " + quoteIdent(m.table) + " (")
+	for i, f := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(f.column))
+	}
+	sb.WriteString(") VALUES ")
+	ph := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"
+	for i, rv := range rows[:n] {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(ph)
+		for _, f := range cols {
+			v, err := f.value(rv)
+			if err != nil {
+				return SQLRequest{}, 0, err
+			}
+			params = append(params, v)
+		}
+	}
+	if upsert {
+		sb.WriteString(" ON CONFLICT (")
+		for i, f := range m.pk {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(quoteIdent(f.column))
+		}
+		sb.WriteString(")")
+		var set []string
+		for _, f := range cols {
+			if !f.pk {
+				set = append(set, quoteIdent(f.column)+" = excluded."+quoteIdent(f.column))
+			}
+		}
+		if len(set) == 0 {
+			sb.WriteString(" DO NOTHING")
+		} else {
+			sb.WriteString(" DO UPDATE SET " + strings.Join(set, ", "))
+		}
+	}
+	return SQLRequest{SQL: sb.String(), Params: params}, n, nil
+}
+
+// columnsFor returns the fields written when inserting rv.
+func (m *mapping) columnsFor(rv reflect.Value) []fieldMap {
+	cols := make([]fieldMap, 0, len(m.fields))
+	for _, f := range m.fields {
+		if !f.omitted(rv) {
+			cols = append(cols, f)
+		}
+	}
+	return cols
+}
+
+func sameFields(a, b []fieldMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].column != b[i].column {
+			return false
+		}
+	}
+	return true
+}
+
+// wherePK appends " WHERE pk1 = ? AND ..." and the key values.
+func (m *mapping) wherePK(sb *strings.Builder, rv reflect.Value, params []any) ([]any, error) {
+	sb.WriteString(" WHERE ")
+	for i, f := range m.pk {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(quoteIdent(f.column) + " = ?")
+		v, err := f.value(rv)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, v)
+	}
+	return params, nil
+}
+
+// quoteIdent double-quotes an identifier, doubling embedded quotes.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// snakeCase converts a Go identifier such as "OrderItem" or "HTTPLog" to
+// "order_item" or "http_log".
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,126 @@
+package warlot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mapProduct struct {
+	_      struct{} `warlot:"products"`
+	ID     int64    `warlot:"id,pk,omitempty"`
+	SKU    string   `warlot:"sku"`
+	Price  float64  `warlot:"price"`
+	Note   *string  `warlot:"note"`
+	Hidden string
+}
+
+type mapAudit struct {
+	CreatedBy string `warlot:"created_by"`
+}
+
+type orderItem struct {
+	OrderID int64 `warlot:"order_id,pk"`
+	Line    int   `warlot:"line,pk"`
+	Qty     int   `warlot:"qty"`
+	mapAudit
+}
+
+func TestMapper_Statements(t *testing.T) {
+	var reqs []SQLRequest
+	var keys []string
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var req SQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		reqs = append(reqs, req)
+		keys = append(keys, r.Header.Get("x-idempotency-key"))
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+	note := "n"
+
+	if _, err := Insert(ctx, proj, mapProduct{SKU: "A", Price: 2, Hidden: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Update(ctx, proj, &mapProduct{ID: 7, SKU: "B", Note: &note}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Upsert(ctx, proj, orderItem{OrderID: 1, Line: 2, Qty: 3, mapAudit: mapAudit{"me"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Delete(ctx, proj, orderItem{OrderID: 1, Line: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SQLRequest{
+		{SQL: `INSERT INTO "products" ("sku", "price", "note") VALUES (?, ?, ?)`, Params: []any{"A", 2.0, nil}},
+		{SQL: `UPDATE "products" SET "sku" = ?, "price" = ?, "note" = ? WHERE "id" = ?`, Params: []any{"B", 0.0, "n", 7.0}},
+		{SQL: `INSERT INTO "order_item" ("order_id", "line", "qty", "created_by") VALUES (?, ?, ?, ?) ON CONFLICT ("order_id", "line") DO UPDATE SET "qty" = excluded."qty", "created_by" = excluded."created_by"`, Params: []any{1.0, 2.0, 3.0, "me"}},
+		{SQL: `DELETE FROM "order_item" WHERE "order_id" = ? AND "line" = ?`, Params: []any{1.0, 2.0}},
+	}
+	if got, exp := fmt.Sprint(reqs), fmt.Sprint(want); got != exp {
+		t.Fatalf("requests:\n got %v\nwant %v", got, exp)
+	}
+
+	type noKey struct {
+		A string `warlot:"a"`
+	}
+	if _, err := Update(ctx, proj, noKey{}); !errors.Is(err, ErrNoPrimaryKey) {
+		t.Fatalf("update without pk: %v", err)
+	}
+
+	// Bulk: 5 rows of 3 params under a limit of 7 params is 2+2+1 rows;
+	// a row that sets the omitempty id starts a new chunk.
+	reqs, keys = nil, nil
+	rows := []mapProduct{{SKU: "a"}, {SKU: "b"}, {SKU: "c"}, {ID: 9, SKU: "d"}, {SKU: "e"}}
+	n, err := InsertAll(ctx, proj, rows, WithMaxParams(7), WithIdempotencyKey("imp"))
+	if err != nil || n != 5 {
+		t.Fatalf("InsertAll: n=%d err=%v", n, err)
+	}
+	var counts []int
+	for _, r := range reqs {
+		counts = append(counts, strings.Count(r.SQL, "(?"))
+	}
+	if fmt.Sprint(counts) != "[2 1 1 1]" {
+		t.Fatalf("chunk rows = %v\n%v", counts, reqs)
+	}
+	if !strings.HasPrefix(reqs[2].SQL, `INSERT INTO "products" ("id", "sku"`) {
+		t.Fatalf("chunk with id: %s", reqs[2].SQL)
+	}
+	if keys[0] != "imp-0" || keys[3] != "imp-3" {
+		t.Fatalf("chunk keys = %v", keys)
+	}
+
+	// Rows that omit every column fall back to DEFAULT VALUES, one per
+	// statement.
+	type counter struct {
+		_  struct{} `warlot:"hits"`
+		ID int64    `warlot:"id,pk,omitempty"`
+		At string   `warlot:"at,omitempty"`
+	}
+	reqs = nil
+	if _, err := Insert(ctx, proj, counter{}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := UpsertAll(ctx, proj, []counter{{}, {}, {At: "x"}}); err != nil || n != 3 {
+		t.Fatalf("UpsertAll: n=%d err=%v", n, err)
+	}
+	want = []SQLRequest{
+		{SQL: `INSERT INTO "hits" DEFAULT VALUES`},
+		{SQL: `INSERT INTO "hits" DEFAULT VALUES`},
+		{SQL: `INSERT INTO "hits" DEFAULT VALUES`},
+		{SQL: `INSERT INTO "hits" ("at") VALUES (?) ON CONFLICT ("id") DO UPDATE SET "at" = excluded."at"`, Params: []any{"x"}},
+	}
+	if got, exp := fmt.Sprint(reqs), fmt.Sprint(want); got != exp {
+		t.Fatalf("default values:\n got %v\nwant %v", got, exp)
+	}
+}
@@ -2,16 +2,48 @@ package warlot
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/fs"
+	"path"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
 )
 
 // Migrator applies SQL migration files idempotently and records them in
-// a ledger table named _migrations (id TEXT PRIMARY KEY, applied_at TEXT).
-type Migrator struct{}
+// a ledger table named _migrations (id TEXT PRIMARY KEY, applied_at TEXT,
+// checksum TEXT, down_sql TEXT, down_checksum TEXT).
+//
+// Files are either plain NNN_name.sql (forward only) or paired
+// NNN_name.up.sql / NNN_name.down.sql. The ledger keeps the reverse script
+// of each applied migration so it can be rolled back even after the files
+// change or are removed.
+//
+// The SHA-256 of each applied file is recorded. If an applied file's content
+// later changes, operations fail with ErrMigrationDrift unless AllowDrift is
+// set, in which case the drift is reported through the client Logger.
+type Migrator struct {
+	// AllowDrift turns drift errors into "migrate_drift" log events.
+	AllowDrift bool
+
+	// Up, Down, To and Redo hold a lease in the _migrations_lock table so
+	// concurrent runners do not apply the same migration twice. LockTTL is
+	// the lease duration (renewed in the background every TTL/3), LockWait
+	// how long to wait for another runner, and LockOwner the identity
+	// written to the row (default: host, pid and a random suffix). An
+	// expired lease is taken over. DisableLock skips locking entirely.
+	LockTTL     time.Duration
+	LockWait    time.Duration
+	LockOwner   string
+	DisableLock bool
+
+	funcs map[string]migration // Go migrations added via Register
+}
 
 // Migrate is a package-level migrator instance for convenience.
 var Migrate Migrator
@@ -19,64 +51,655 @@ var Migrate Migrator
 // Deprecated: use Migrate.
 var migrate = Migrate
 
-// Up applies .sql files in fsys under dir, sorted by filename. Already-applied
-// migration IDs are skipped based on the _migrations ledger.
-func (Migrator) Up(ctx context.Context, p Project, fsys fs.FS, dir string) (applied []string, err error) {
-	// Ensure ledger exists.
-	if _, err = p.SQL(ctx, `
-		CREATE TABLE IF NOT EXISTS _migrations (
-			id TEXT PRIMARY KEY,
-			applied_at TEXT NOT NULL
-		)
-	`, nil); err != nil {
-		return nil, fmt.Errorf("create _migrations: %w", err)
+// ErrMigrationDrift is returned when an applied migration file no longer
+// matches the checksum recorded in the ledger.
+var ErrMigrationDrift = errors.New("warlot: applied migration file has changed")
+
+// ErrIrreversible is returned when rolling back a migration that has no
+// down script.
+var ErrIrreversible = errors.New("warlot: migration has no down script")
+
+// migration is a single migration read from the filesystem.
+type migration struct {
+	ID      string // up filename; the ledger key
+	Version string // leading numeric prefix of the filename
+	Up      string
+	Down    string
+	HasDown bool
+
+	UpFunc   MigrationFunc // set for Go migrations instead of Up
+	DownFunc MigrationFunc
+}
+
+// reversible reports whether the migration has a down script or function.
+func (mig migration) reversible() bool { return mig.HasDown || mig.DownFunc != nil }
+
+// sum returns the checksum of the up script, or "" for Go migrations,
+// which have no content to compare.
+func (mig migration) sum() string {
+	if mig.UpFunc != nil {
+		return ""
 	}
+	return checksum(mig.Up)
+}
 
-	entries, err := fs.ReadDir(fsys, dir)
+// ledgerRow is an applied migration as stored in _migrations.
+type ledgerRow struct {
+	ID           string `json:"id"`
+	AppliedAt    string `json:"applied_at"`
+	Checksum     string `json:"checksum"`
+	DownSQL      string `json:"down_sql"`
+	DownChecksum string `json:"down_checksum"`
+}
+
+// MigrationInfo describes one migration in a status report.
+type MigrationInfo struct {
+	ID         string `json:"id"`
+	Version    string `json:"version"`
+	AppliedAt  string `json:"applied_at,omitempty"`
+	Checksum   string `json:"checksum,omitempty"`      // recorded in the ledger
+	FileSum    string `json:"file_checksum,omitempty"` // current file content
+	Reversible bool   `json:"reversible"`
+	Missing    bool   `json:"missing,omitempty"` // applied but no longer on disk
+
+	// DownSQL is the script a rollback runs: the one recorded in the
+	// ledger, else the .down.sql file. Empty for Go down functions.
+	DownSQL string `json:"down_sql,omitempty"`
+}
+
+// MigrationStatus reports applied, pending and drifted migrations.
+// Drifted entries are also listed in Applied.
+type MigrationStatus struct {
+	Applied []MigrationInfo `json:"applied"`
+	Pending []MigrationInfo `json:"pending"`
+	Drifted []MigrationInfo `json:"drifted"`
+}
+
+// Up applies .sql files in fsys under dir, sorted by version. Already-applied
+// migration IDs are skipped based on the _migrations ledger. Each file runs in
+// the same BEGIN/COMMIT batch as its ledger insert, so migration files must
+// not contain their own transaction statements.
+func (m Migrator) Up(ctx context.Context, p Project, fsys fs.FS, dir string) (applied []string, err error) {
+	lk, ctx, err := m.lock(ctx, p)
 	if err != nil {
 		return nil, err
 	}
-	names := make([]string, 0, len(entries))
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(strings.ToLower(e.Name()), ".sql") {
-			names = append(names, e.Name())
+	defer lk.release(ctx)
+	migs, ledger, err := m.prepare(ctx, p, fsys, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := backfillChecksums(ctx, p, migs, ledger); err != nil {
+		return nil, err
+	}
+	for _, mig := range migs {
+		if _, done := ledger[mig.ID]; done {
+			continue
+		}
+		if err := m.apply(ctx, p, mig, lk); err != nil {
+			return applied, err
+		}
+		applied = append(applied, mig.ID)
+	}
+	return applied, nil
+}
+
+// Down rolls back the most recently applied migration using the down script
+// recorded in the ledger. For ledger rows written before down scripts were
+// recorded, the .down.sql file in fsys is used instead.
+func (m Migrator) Down(ctx context.Context, p Project, fsys fs.FS, dir string) (rolledBack []string, err error) {
+	lk, ctx, err := m.lock(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer lk.release(ctx)
+	migs, ledger, err := m.prepare(ctx, p, fsys, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	ids := sortedLedgerIDs(ledger)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	last := ids[len(ids)-1]
+	if err := m.revert(ctx, p, ledger[last], findMigration(migs, last), lk); err != nil {
+		return nil, err
+	}
+	return []string{last}, nil
+}
+
+// To migrates up or down until version is the latest applied version.
+// Applied migrations with a greater version are rolled back newest first;
+// pending migrations up to and including version are applied. Use "0" to
+// roll back everything.
+func (m Migrator) To(ctx context.Context, p Project, fsys fs.FS, dir, version string) (applied, rolledBack []string, err error) {
+	lk, ctx, err := m.lock(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lk.release(ctx)
+	migs, ledger, err := m.prepare(ctx, p, fsys, dir, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := sortedLedgerIDs(ledger)
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		if compareVersions(migrationVersion(id), version) <= 0 {
+			continue
+		}
+		if err := m.revert(ctx, p, ledger[id], findMigration(migs, id), lk); err != nil {
+			return nil, rolledBack, err
 		}
+		rolledBack = append(rolledBack, id)
+	}
+	for _, mig := range migs {
+		if _, done := ledger[mig.ID]; done || compareVersions(mig.Version, version) > 0 {
+			continue
+		}
+		if err := m.apply(ctx, p, mig, lk); err != nil {
+			return applied, rolledBack, err
+		}
+		applied = append(applied, mig.ID)
+	}
+	return applied, rolledBack, nil
+}
+
+// Redo rolls back the most recently applied migration and applies it again
+// from its current file. It returns the ID of the migration redone, or an
+// empty string when nothing is applied. The redone migration itself is
+// exempt from drift checks, since re-applying an edited file is its purpose.
+func (m Migrator) Redo(ctx context.Context, p Project, fsys fs.FS, dir string) (string, error) {
+	lk, ctx, err := m.lock(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	defer lk.release(ctx)
+	migs, ledger, err := m.prepare(ctx, p, fsys, dir, true)
+	if err != nil {
+		return "", err
+	}
+	ids := sortedLedgerIDs(ledger)
+	if len(ids) == 0 {
+		return "", nil
+	}
+	last := ids[len(ids)-1]
+	mig := findMigration(migs, last)
+	if mig == nil {
+		return "", fmt.Errorf("redo %s: migration file not found in %s", last, dir)
+	}
+	if err := m.revert(ctx, p, ledger[last], mig, lk); err != nil {
+		return "", err
+	}
+	if err := m.apply(ctx, p, *mig, lk); err != nil {
+		return "", err
+	}
+	return last, nil
+}
+
+// Status reports applied, pending and drifted migrations without applying
+// anything. Drift is reported rather than returned as an error. Status only
+// reads from the project: a missing ledger means nothing has been applied.
+func (m Migrator) Status(ctx context.Context, p Project, fsys fs.FS, dir string) (*MigrationStatus, error) {
+	migs, err := m.load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := readLedger(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	st := &MigrationStatus{Applied: []MigrationInfo{}, Pending: []MigrationInfo{}, Drifted: []MigrationInfo{}}
+	for _, id := range sortedLedgerIDs(ledger) {
+		row := ledger[id]
+		info := MigrationInfo{
+			ID: id, Version: migrationVersion(id), AppliedAt: row.AppliedAt,
+			Checksum: row.Checksum, Reversible: row.DownSQL != "", DownSQL: row.DownSQL,
+		}
+		if mig := findMigration(migs, id); mig != nil {
+			info.FileSum = mig.sum()
+			info.Reversible = info.Reversible || mig.reversible()
+			if info.DownSQL == "" && mig.HasDown {
+				info.DownSQL = mig.Down
+			}
+		} else {
+			info.Missing = true
+		}
+		st.Applied = append(st.Applied, info)
+		if isDrifted(info) {
+			st.Drifted = append(st.Drifted, info)
+		}
+	}
+	for _, mig := range migs {
+		if _, done := ledger[mig.ID]; done {
+			continue
+		}
+		st.Pending = append(st.Pending, MigrationInfo{
+			ID: mig.ID, Version: mig.Version, FileSum: mig.sum(), Reversible: mig.reversible(), DownSQL: mig.Down,
+		})
+	}
+	return st, nil
+}
+
+// Plan reports what To(version) would do without changing anything: the
+// migrations it would roll back, newest first, and those it would apply.
+// An empty version plans Up. Like Status, Plan only reads from the project.
+func (m Migrator) Plan(ctx context.Context, p Project, fsys fs.FS, dir, version string) (apply, rollback []MigrationInfo, err error) {
+	st, err := m.Status(ctx, p, fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version != "" {
+		for i := len(st.Applied) - 1; i >= 0; i-- {
+			if mi := st.Applied[i]; compareVersions(mi.Version, version) > 0 {
+				rollback = append(rollback, mi)
+			}
+		}
+	}
+	for _, mi := range st.Pending {
+		if version == "" || compareVersions(mi.Version, version) <= 0 {
+			apply = append(apply, mi)
+		}
+	}
+	return apply, rollback, nil
+}
+
+// prepare ensures the ledger exists, loads both the migration files and the
+// applied set, and checks applied files for drift. When exemptLatest is set
+// the most recently applied migration is not checked.
+func (m Migrator) prepare(ctx context.Context, p Project, fsys fs.FS, dir string, exemptLatest bool) ([]migration, map[string]ledgerRow, error) {
+	if err := ensureLedger(ctx, p); err != nil {
+		return nil, nil, err
+	}
+	migs, err := m.load(fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	ledger, err := loadLedger(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	exempt := ""
+	if ids := sortedLedgerIDs(ledger); exemptLatest && len(ids) > 0 {
+		exempt = ids[len(ids)-1]
+	}
+	var drifted []string
+	for _, mig := range migs {
+		row, ok := ledger[mig.ID]
+		if !ok || mig.ID == exempt {
+			continue
+		}
+		info := MigrationInfo{ID: mig.ID, Checksum: row.Checksum, FileSum: mig.sum()}
+		if isDrifted(info) {
+			drifted = append(drifted, mig.ID)
+		}
+	}
+	if len(drifted) > 0 {
+		if !m.AllowDrift {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMigrationDrift, strings.Join(drifted, ", "))
+		}
+		if p.Client.Logger != nil {
+			p.Client.Logger("migrate_drift", map[string]any{"project": p.ID, "migrations": drifted})
+		}
+	}
+	return migs, ledger, nil
+}
+
+// loadLedger reads the applied set from _migrations.
+func loadLedger(ctx context.Context, p Project) (map[string]ledgerRow, error) {
+	rows, err := Query[ledgerRow](ctx, p, `SELECT id, applied_at, checksum, down_sql, down_checksum FROM _migrations`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load applied migrations: %w", err)
 	}
-	sort.Strings(names)
+	ledger := make(map[string]ledgerRow, len(rows))
+	for _, r := range rows {
+		ledger[r.ID] = r
+	}
+	return ledger, nil
+}
 
-	// Load applied set.
-	type migRow struct {
-		ID string `json:"id"`
+// readLedger loads the applied set without creating or upgrading the
+// ledger. Columns an older ledger lacks read as empty.
+func readLedger(ctx context.Context, p Project) (map[string]ledgerRow, error) {
+	type colRow struct {
+		Name string `json:"name"`
+	}
+	cols, err := Query[colRow](ctx, p, `PRAGMA table_info(_migrations)`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("inspect _migrations: %w", err)
+	}
+	if len(cols) == 0 {
+		return map[string]ledgerRow{}, nil
 	}
-	existing, err := Query[migRow](ctx, p, `SELECT id FROM _migrations`, nil)
+	have := map[string]bool{}
+	for _, c := range cols {
+		have[strings.ToLower(c.Name)] = true
+	}
+	sel := []string{"id", "applied_at"}
+	for _, col := range []string{"checksum", "down_sql", "down_checksum"} {
+		if have[col] {
+			sel = append(sel, col)
+		} else {
+			sel = append(sel, "NULL AS "+col)
+		}
+	}
+	rows, err := Query[ledgerRow](ctx, p, `SELECT `+strings.Join(sel, ", ")+` FROM _migrations`, nil)
 	if err != nil {
 		return nil, fmt.Errorf("load applied migrations: %w", err)
 	}
-	appliedSet := map[string]struct{}{}
-	for _, r := range existing {
-		appliedSet[r.ID] = struct{}{}
+	ledger := make(map[string]ledgerRow, len(rows))
+	for _, r := range rows {
+		ledger[r.ID] = r
+	}
+	return ledger, nil
+}
+
+// backfillChecksums records the current file checksum for ledger rows
+// written before checksums were tracked.
+func backfillChecksums(ctx context.Context, p Project, migs []migration, ledger map[string]ledgerRow) error {
+	for _, mig := range migs {
+		row, ok := ledger[mig.ID]
+		sum := mig.sum()
+		if !ok || row.Checksum != "" || sum == "" {
+			continue
+		}
+		if _, err := p.SQL(ctx, `UPDATE _migrations SET checksum = ? WHERE id = ? AND checksum IS NULL`, []any{sum, mig.ID}); err != nil {
+			return fmt.Errorf("record checksum for %s: %w", mig.ID, err)
+		}
+		row.Checksum = sum
+		ledger[mig.ID] = row
+	}
+	return nil
+}
+
+// isDrifted reports whether a recorded checksum differs from the file.
+// Rows without a recorded checksum or without a file cannot drift.
+func isDrifted(info MigrationInfo) bool {
+	return info.Checksum != "" && info.FileSum != "" && info.Checksum != info.FileSum
+}
+
+// apply runs an up script together with its ledger insert in one batch so
+// a failure cannot leave the schema applied but unrecorded. The idempotency
+// key is unique per application because a rolled-back migration may be
+// applied again later.
+//
+// Go migrations cannot share a batch with the ledger insert; the row is
+// recorded after the function returns successfully.
+func (Migrator) apply(ctx context.Context, p Project, mig migration, lk *migrationLock) (err error) {
+	defer func() { err = lk.lost(ctx, err) }()
+	if err := lk.refresh(ctx); err != nil {
+		return err
+	}
+	var sum, down, downSum any
+	if s := mig.sum(); s != "" {
+		sum = s
+	}
+	if mig.HasDown {
+		down, downSum = mig.Down, checksum(mig.Down)
+	}
+	record := SQLRequest{
+		SQL:    `INSERT INTO _migrations (id, applied_at, checksum, down_sql, down_checksum) VALUES (?, ?, ?, ?, ?)`,
+		Params: []any{mig.ID, time.Now().UTC().Format(time.RFC3339), sum, down, downSum},
+	}
+	if mig.UpFunc != nil {
+		if err := mig.UpFunc(ctx, p); err != nil {
+			return fmt.Errorf("apply %s: %w", mig.ID, err)
+		}
+		if _, err := p.SQL(ctx, record.SQL, record.Params); err != nil {
+			return fmt.Errorf("record %s: %w", mig.ID, err)
+		}
+		return nil
+	}
+	if err := runBatch(ctx, p, []SQLRequest{{SQL: mig.Up}, record}, "mig-"+mig.ID+"-"+newIdempotencyKey()); err != nil {
+		return fmt.Errorf("apply %s: %w", mig.ID, err)
+	}
+	return nil
+}
+
+// revert runs the recorded down script and removes the ledger row in one
+// batch. file is the migration read from disk, or nil if it no longer exists.
+func (Migrator) revert(ctx context.Context, p Project, row ledgerRow, file *migration, lk *migrationLock) (err error) {
+	defer func() { err = lk.lost(ctx, err) }()
+	if err := lk.refresh(ctx); err != nil {
+		return err
+	}
+	down := row.DownSQL
+	if down != "" && row.DownChecksum != "" && checksum(down) != row.DownChecksum {
+		return fmt.Errorf("rollback %s: recorded down script does not match its checksum", row.ID)
+	}
+	if down == "" && file != nil && file.HasDown {
+		down = file.Down
+	}
+	if down == "" && file != nil && file.DownFunc != nil {
+		if err := file.DownFunc(ctx, p); err != nil {
+			return fmt.Errorf("rollback %s: %w", row.ID, err)
+		}
+		if _, err := p.SQL(ctx, `DELETE FROM _migrations WHERE id = ?`, []any{row.ID}); err != nil {
+			return fmt.Errorf("rollback %s: %w", row.ID, err)
+		}
+		return nil
+	}
+	if down == "" {
+		return fmt.Errorf("rollback %s: %w", row.ID, ErrIrreversible)
+	}
+	if err := runBatch(ctx, p, []SQLRequest{
+		{SQL: down},
+		{SQL: `DELETE FROM _migrations WHERE id = ?`, Params: []any{row.ID}},
+	}, "mig-down-"+row.ID+"-"+newIdempotencyKey()); err != nil {
+		return fmt.Errorf("rollback %s: %w", row.ID, err)
+	}
+	return nil
+}
+
+// runBatch runs a migration script and its ledger update in one batch.
+// SQLite ignores PRAGMA foreign_keys inside a transaction, so when the
+// script opens with PRAGMA foreign_keys = OFF (as table rebuilds do) the
+// pragma is sent ahead of BEGIN and the previous setting restored after
+// COMMIT.
+func runBatch(ctx context.Context, p Project, stmts []SQLRequest, key string) error {
+	if !disablesForeignKeys(stmts[0].SQL) {
+		_, err := p.Batch(ctx, stmts, WithIdempotencyKey(key))
+		return err
+	}
+	type fkRow struct {
+		On int `json:"foreign_keys"`
+	}
+	rows, err := Query[fkRow](ctx, p, `PRAGMA foreign_keys`, nil)
+	if err != nil {
+		return err
+	}
+	restore := "OFF"
+	if len(rows) > 0 && rows[0].On != 0 {
+		restore = "ON"
+	}
+	req := batchRequest(stmts)
+	req.SQL = "PRAGMA foreign_keys = OFF;\n" + req.SQL + "\nPRAGMA foreign_keys = " + restore + ";"
+	if _, err := p.Client.ExecSQL(ctx, p.ID, req, WithIdempotencyKey(key)); err != nil {
+		if restore == "ON" {
+			_, _ = p.SQL(context.WithoutCancel(ctx), `PRAGMA foreign_keys = ON`, nil)
+		}
+		return err
+	}
+	return nil
+}
+
+// disablesForeignKeys reports whether script opens with
+// PRAGMA foreign_keys = OFF.
+func disablesForeignKeys(script string) bool {
+	toks, err := sqlparse.Tokenize(script)
+	if err != nil || len(toks) < 4 {
+		return false
+	}
+	word := func(i int, want ...string) bool {
+		for _, w := range want {
+			if strings.EqualFold(toks[i].Text, w) {
+				return true
+			}
+		}
+		return false
 	}
+	return word(0, "PRAGMA") && word(1, "foreign_keys") && word(2, "=") && word(3, "OFF", "0", "FALSE", "NO")
+}
 
-	for _, name := range names {
-		if _, done := appliedSet[name]; done {
+// ensureLedger creates the ledger table and adds columns introduced after
+// its first release.
+func ensureLedger(ctx context.Context, p Project) error {
+	if _, err := p.SQL(ctx, `
+		CREATE TABLE IF NOT EXISTS _migrations (
+			id TEXT PRIMARY KEY,
+			applied_at TEXT NOT NULL,
+			checksum TEXT,
+			down_sql TEXT,
+			down_checksum TEXT
+		)
+	`, nil); err != nil {
+		return fmt.Errorf("create _migrations: %w", err)
+	}
+	type colRow struct {
+		Name string `json:"name"`
+	}
+	cols, err := Query[colRow](ctx, p, `PRAGMA table_info(_migrations)`, nil)
+	if err != nil {
+		return fmt.Errorf("inspect _migrations: %w", err)
+	}
+	have := map[string]bool{}
+	for _, c := range cols {
+		have[strings.ToLower(c.Name)] = true
+	}
+	for _, col := range []string{"checksum", "down_sql", "down_checksum"} {
+		if have[col] {
 			continue
 		}
-		b, err := fs.ReadFile(fsys, dir+"/"+name)
+		if _, err := p.SQL(ctx, `ALTER TABLE _migrations ADD COLUMN `+col+` TEXT`, nil); err != nil {
+			return fmt.Errorf("upgrade _migrations: %w", err)
+		}
+	}
+	return nil
+}
+
+// load reads migration files under dir and merges registered Go migrations
+// into the same version order.
+func (m Migrator) load(fsys fs.FS, dir string) ([]migration, error) {
+	migs, err := loadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.funcs) == 0 {
+		return migs, nil
+	}
+	for id, fn := range m.funcs {
+		if findMigration(migs, id) != nil {
+			return nil, fmt.Errorf("Go migration %s conflicts with a migration file of the same name", id)
+		}
+		migs = append(migs, fn)
+	}
+	sort.Slice(migs, func(i, j int) bool { return compareMigrationIDs(migs[i].ID, migs[j].ID) < 0 })
+	return migs, nil
+}
+
+// loadMigrations reads migration files under dir and pairs up/down scripts.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byBase := map[string]*migration{}
+	downs := map[string]string{}
+	for _, e := range entries {
+		name := e.Name()
+		lower := strings.ToLower(name)
+		if e.IsDir() || !strings.HasSuffix(lower, ".sql") {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, name))
 		if err != nil {
-			return applied, fmt.Errorf("read %s: %w", name, err)
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		switch {
+		case strings.HasSuffix(lower, ".down.sql"):
+			downs[name[:len(name)-len(".down.sql")]] = string(b)
+		case strings.HasSuffix(lower, ".up.sql"):
+			base := name[:len(name)-len(".up.sql")]
+			byBase[base] = &migration{ID: name, Version: migrationVersion(name), Up: string(b)}
+		default:
+			byBase[name] = &migration{ID: name, Version: migrationVersion(name), Up: string(b)}
 		}
-		sqlText := string(b)
+	}
+	for base, down := range downs {
+		mig, ok := byBase[base]
+		if !ok {
+			return nil, fmt.Errorf("down migration %s.down.sql has no matching up file", base)
+		}
+		mig.Down, mig.HasDown = down, true
+	}
+	out := make([]migration, 0, len(byBase))
+	for _, mig := range byBase {
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return compareMigrationIDs(out[i].ID, out[j].ID) < 0 })
+	return out, nil
+}
 
-		if _, err := p.SQL(ctx, sqlText, nil, WithIdempotencyKey("mig-"+name)); err != nil {
-			return applied, fmt.Errorf("apply %s: %w", name, err)
+func findMigration(migs []migration, id string) *migration {
+	for i := range migs {
+		if migs[i].ID == id {
+			return &migs[i]
 		}
-		if _, err := p.SQL(ctx,
-			`INSERT INTO _migrations (id, applied_at) VALUES (?, ?)`,
-			[]any{name, time.Now().UTC().Format(time.RFC3339)},
-		); err != nil {
-			return applied, fmt.Errorf("record %s: %w", name, err)
+	}
+	return nil
+}
+
+func sortedLedgerIDs(ledger map[string]ledgerRow) []string {
+	ids := make([]string, 0, len(ledger))
+	for id := range ledger {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return compareMigrationIDs(ids[i], ids[j]) < 0 })
+	return ids
+}
+
+// migrationVersion returns the leading digits of a migration ID.
+func migrationVersion(id string) string {
+	i := 0
+	for i < len(id) && id[i] >= '0' && id[i] <= '9' {
+		i++
+	}
+	return id[:i]
+}
+
+// compareVersions compares numeric version strings without overflow,
+// ignoring leading zeros. Non-numeric versions compare as strings.
+func compareVersions(a, b string) int {
+	if migrationVersion(a) != a || migrationVersion(b) != b || a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
 		}
-		applied = append(applied, name)
+		return 1
 	}
-	return applied, nil
+	return strings.Compare(a, b)
+}
+
+// compareMigrationIDs orders IDs by numeric version, then by full ID. An
+// ID without a numeric prefix compares lexically, as every ID did before
+// versions were compared as numbers, so existing sets of such names keep
+// their order and sort after numbered ones.
+func compareMigrationIDs(a, b string) int {
+	va, vb := migrationVersion(a), migrationVersion(b)
+	if va == "" || vb == "" {
+		return strings.Compare(a, b)
+	}
+	if c := compareVersions(va, vb); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// checksum returns the hex SHA-256 of a migration script.
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
 }
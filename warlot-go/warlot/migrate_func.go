@@ -0,0 +1,47 @@
+package warlot
+
+import (
+	"context"
+	"fmt"
+)
+
+// MigrationFunc is a migration implemented in Go, for changes such as data
+// backfills that are awkward in plain SQL.
+type MigrationFunc func(ctx context.Context, p Project) error
+
+// Register adds a forward-only Go migration. It is ordered by the numeric
+// prefix of id together with the .sql files passed to Up and recorded in
+// the same _migrations ledger. Register panics if id is empty, fn is nil,
+// or id is already registered.
+//
+//	func init() {
+//		warlot.Migrate.Register("0007_backfill", backfillPrices)
+//	}
+func (m *Migrator) Register(id string, fn MigrationFunc) {
+	m.register(id, fn, nil)
+}
+
+// RegisterReversible adds a Go migration with a down function used by
+// Down, To and Redo.
+func (m *Migrator) RegisterReversible(id string, up, down MigrationFunc) {
+	if down == nil {
+		panic("warlot: RegisterReversible down function is nil for " + id)
+	}
+	m.register(id, up, down)
+}
+
+func (m *Migrator) register(id string, up, down MigrationFunc) {
+	if id == "" || up == nil {
+		panic("warlot: Register requires an id and a migration function")
+	}
+	if _, dup := m.funcs[id]; dup {
+		panic(fmt.Sprintf("warlot: Register called twice for migration %s", id))
+	}
+	if m.funcs == nil {
+		m.funcs = map[string]migration{}
+	}
+	m.funcs[id] = migration{
+		ID: id, Version: migrationVersion(id),
+		UpFunc: up, DownFunc: down,
+	}
+}
@@ -0,0 +1,199 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Defaults for the migration lease lock.
+const (
+	DefaultMigrationLockTTL  = time.Minute
+	DefaultMigrationLockWait = 2 * time.Minute
+
+	migrationLockPoll = 500 * time.Millisecond
+
+	// lockNowSQL is the database clock in Unix milliseconds. Lease expiry
+	// is always computed on the server so clock skew between runners
+	// cannot let two of them hold the lock.
+	lockNowSQL = `CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)`
+)
+
+var (
+	// ErrMigrationLocked is returned when another runner holds the migration
+	// lock for longer than Migrator.LockWait.
+	ErrMigrationLocked = errors.New("warlot: migrations are locked by another runner")
+
+	// ErrMigrationLockLost is returned when the lease expired and was taken
+	// over by another runner while migrations were running.
+	ErrMigrationLockLost = errors.New("warlot: migration lock lost")
+)
+
+// migrationLock is a lease on the single row of _migrations_lock. A row whose
+// expires_at (Unix milliseconds, database clock) is in the past is stale and
+// may be taken over by any runner. While held, a heartbeat goroutine renews
+// the lease every third of its TTL.
+type migrationLock struct {
+	p      Project
+	owner  string
+	ttl    time.Duration
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// lock acquires the migration lease, waiting up to LockWait for a live lease
+// held by another runner, and starts its heartbeat. The returned context is
+// cancelled with ErrMigrationLockLost as its cause if the lease is taken
+// over. It returns a nil lock and ctx unchanged when locking is disabled.
+func (m Migrator) lock(ctx context.Context, p Project) (*migrationLock, context.Context, error) {
+	if m.DisableLock {
+		return nil, ctx, nil
+	}
+	l := &migrationLock{p: p, owner: m.LockOwner, ttl: m.LockTTL}
+	if l.owner == "" {
+		l.owner = defaultLockOwner()
+	}
+	if l.ttl <= 0 {
+		l.ttl = DefaultMigrationLockTTL
+	}
+	wait := m.LockWait
+	if wait <= 0 {
+		wait = DefaultMigrationLockWait
+	}
+
+	if _, err := p.SQL(ctx, `
+		CREATE TABLE IF NOT EXISTS _migrations_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			owner TEXT NOT NULL,
+			expires_at INTEGER NOT NULL
+		)
+	`, nil); err != nil {
+		return nil, ctx, fmt.Errorf("create _migrations_lock: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		holder, err := l.tryAcquire(ctx)
+		if err != nil {
+			return nil, ctx, err
+		}
+		if holder == l.owner {
+			ctx, l.cancel = context.WithCancelCause(ctx)
+			l.done = make(chan struct{})
+			go l.heartbeat(ctx)
+			return l, ctx, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ctx, fmt.Errorf("%w (owner %s)", ErrMigrationLocked, holder)
+		}
+		timer := time.NewTimer(migrationLockPoll)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx, ctx.Err()
+		}
+	}
+}
+
+// tryAcquire inserts the lease row, or takes it over when it is stale or
+// already ours, and returns the owner that holds it afterwards.
+func (l *migrationLock) tryAcquire(ctx context.Context) (string, error) {
+	if _, err := l.p.SQL(ctx, `
+		INSERT INTO _migrations_lock (id, owner, expires_at) VALUES (1, ?, `+lockNowSQL+` + ?)
+		ON CONFLICT(id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE _migrations_lock.expires_at < `+lockNowSQL+` OR _migrations_lock.owner = excluded.owner
+	`, []any{l.owner, l.ttl.Milliseconds()}); err != nil {
+		return "", fmt.Errorf("acquire migration lock: %w", err)
+	}
+	type lockRow struct {
+		Owner string `json:"owner"`
+	}
+	rows, err := Query[lockRow](ctx, l.p, `SELECT owner FROM _migrations_lock WHERE id = 1`, nil)
+	if err != nil {
+		return "", fmt.Errorf("read migration lock: %w", err)
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	return rows[0].Owner, nil
+}
+
+// heartbeat renews the lease until release stops it. A failed renewal is
+// retried on the next tick; a lease found taken over cancels the run.
+func (l *migrationLock) heartbeat(ctx context.Context) {
+	defer close(l.done)
+	ticker := time.NewTicker(max(l.ttl/3, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.refresh(ctx); errors.Is(err, ErrMigrationLockLost) {
+				l.cancel(ErrMigrationLockLost)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh extends the lease. Besides the heartbeat, it runs before each
+// migration step so a lost lease is reported before any work starts. A nil
+// lock (locking disabled) is a no-op.
+func (l *migrationLock) refresh(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if err := context.Cause(ctx); errors.Is(err, ErrMigrationLockLost) {
+		return err
+	}
+	res, err := l.p.SQL(ctx, `UPDATE _migrations_lock SET expires_at = `+lockNowSQL+` + ? WHERE id = 1 AND owner = ?`,
+		[]any{l.ttl.Milliseconds(), l.owner})
+	if err != nil {
+		return fmt.Errorf("refresh migration lock: %w", err)
+	}
+	if res.RowCount != nil && *res.RowCount == 0 {
+		return ErrMigrationLockLost
+	}
+	return nil
+}
+
+// lost reports err as ErrMigrationLockLost when the heartbeat cancelled ctx
+// because the lease was taken over, so the step that was interrupted is
+// not mistaken for an ordinary cancellation.
+func (l *migrationLock) lost(ctx context.Context, err error) error {
+	if l == nil || err == nil || errors.Is(err, ErrMigrationLockLost) {
+		return err
+	}
+	if errors.Is(context.Cause(ctx), ErrMigrationLockLost) {
+		return fmt.Errorf("%w: %w", ErrMigrationLockLost, err)
+	}
+	return err
+}
+
+// release stops the heartbeat and deletes the lease if it is still ours. It
+// runs even when ctx has been cancelled so an interrupted run does not hold
+// the lock until expiry.
+func (l *migrationLock) release(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	l.cancel(context.Canceled)
+	<-l.done
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	_, _ = l.p.SQL(ctx, `DELETE FROM _migrations_lock WHERE id = 1 AND owner = ?`, []any{l.owner})
+}
+
+// defaultLockOwner identifies this process in the lock row.
+func defaultLockOwner() string {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + newIdempotencyKey()[:8]
+}
@@ -3,9 +3,13 @@ package warlot
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -20,6 +24,7 @@ func TestMigrator_Up(t *testing.T) {
 
 	// backend simulator state
 	applied := map[string]bool{}
+	lockOwner := ""
 
 	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/sql") {
@@ -33,7 +38,14 @@ func TestMigrator_Up(t *testing.T) {
 			case strings.HasPrefix(sql, "create table"):
 				json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(0)})
 
-			case strings.HasPrefix(sql, "insert into _migrations"):
+			case strings.Contains(sql, "insert into _migrations_lock"):
+				lockOwner = req.Params[0].(string)
+				json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+
+			case strings.HasPrefix(sql, "select owner from _migrations_lock"):
+				json.NewEncoder(w).Encode(SQLResponse{OK: true, Rows: []map[string]any{{"owner": lockOwner}}})
+
+			case strings.HasPrefix(sql, "begin;") && strings.Contains(sql, "insert into _migrations"):
 				// capture id value (name)
 				if len(req.Params) >= 1 {
 					if id, ok := req.Params[0].(string); ok {
@@ -42,7 +54,7 @@ func TestMigrator_Up(t *testing.T) {
 				}
 				json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
 
-			case strings.HasPrefix(sql, "select id from _migrations"):
+			case strings.HasPrefix(sql, "select id") && strings.Contains(sql, "from _migrations"):
 				// return already applied rows
 				rows := []map[string]any{}
 				for id := range applied {
@@ -85,3 +97,380 @@ func TestMigrator_Up(t *testing.T) {
 
 // mapFSTrim adapts fstest.MapFS to fs.FS (no change; just explicit type)
 type mapFSTrim struct{ fs.FS }
+
+// ledgerServer simulates the _migrations ledger for paired up/down files
+// and the _migrations_lock lease. Scripts records the first statement of
+// every migration batch executed; sent records every statement.
+type ledgerServer struct {
+	mu        sync.Mutex
+	rows      map[string]map[string]any
+	scripts   []string
+	sent      []string
+	created   bool
+	lockOwner string
+	lockUntil int64
+}
+
+func (l *ledgerServer) handle(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var req SQLRequest
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	_ = dec.Decode(&req)
+	sql := strings.ToLower(req.SQL)
+	l.sent = append(l.sent, strings.TrimSpace(sql))
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasPrefix(sql, "begin;") {
+		l.scripts = append(l.scripts, strings.SplitN(req.SQL, "\n", 3)[1])
+	}
+	switch {
+	case strings.Contains(sql, "create table if not exists _migrations ("):
+		l.created = true
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(0)})
+	case strings.HasPrefix(sql, "pragma table_info(_migrations)"):
+		rows := []map[string]any{}
+		for _, c := range []string{"id", "applied_at", "checksum", "down_sql", "down_checksum"} {
+			if l.created {
+				rows = append(rows, map[string]any{"name": c})
+			}
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, Rows: rows})
+	case strings.Contains(sql, "insert into _migrations_lock"):
+		owner := req.Params[0].(string)
+		ttl, _ := req.Params[1].(json.Number).Int64()
+		now := time.Now().UnixMilli()
+		if l.lockOwner == "" || l.lockUntil < now || l.lockOwner == owner {
+			l.lockOwner, l.lockUntil = owner, now+ttl
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+	case strings.HasPrefix(sql, "select owner from _migrations_lock"):
+		rows := []map[string]any{}
+		if l.lockOwner != "" {
+			rows = append(rows, map[string]any{"owner": l.lockOwner})
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, Rows: rows})
+	case strings.HasPrefix(sql, "update _migrations_lock"):
+		n := 0
+		if l.lockOwner == req.Params[1].(string) {
+			ttl, _ := req.Params[0].(json.Number).Int64()
+			l.lockUntil = time.Now().UnixMilli() + ttl
+			n = 1
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(n)})
+	case strings.HasPrefix(sql, "delete from _migrations_lock"):
+		if l.lockOwner == req.Params[0].(string) {
+			l.lockOwner = ""
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+	case strings.Contains(sql, "insert into _migrations"):
+		id := req.Params[0].(string)
+		l.rows[id] = map[string]any{"id": id, "checksum": req.Params[2], "down_sql": req.Params[3], "down_checksum": req.Params[4]}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+	case strings.Contains(sql, "delete from _migrations"):
+		delete(l.rows, req.Params[len(req.Params)-1].(string))
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+	case strings.HasPrefix(sql, "begin;"):
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, RowCount: intPtr(1)})
+	case strings.HasPrefix(sql, "select id") && strings.Contains(sql, "from _migrations"):
+		rows := []map[string]any{}
+		for _, row := range l.rows {
+			rows = append(rows, row)
+		}
+		json.NewEncoder(w).Encode(SQLResponse{OK: true, Rows: rows})
+	default:
+		json.NewEncoder(w).Encode(SQLResponse{OK: true})
+	}
+}
+
+func TestCompareMigrationIDs(t *testing.T) {
+	ids := []string{"init.sql", "10_b.sql", "9_a.sql", "002_c.sql", "baseline.sql", "2_a.sql"}
+	sort.Slice(ids, func(i, j int) bool { return compareMigrationIDs(ids[i], ids[j]) < 0 })
+	if got := strings.Join(ids, ","); got != "002_c.sql,2_a.sql,9_a.sql,10_b.sql,baseline.sql,init.sql" {
+		t.Fatalf("order = %s", got)
+	}
+}
+
+func TestMigrator_Down_To_Redo(t *testing.T) {
+	mfs := fstest.MapFS{
+		"m/001_users.up.sql":    {Data: []byte(`CREATE TABLE users (id INTEGER)`)},
+		"m/001_users.down.sql":  {Data: []byte(`DROP TABLE users`)},
+		"m/002_orders.up.sql":   {Data: []byte(`CREATE TABLE orders (id INTEGER)`)},
+		"m/002_orders.down.sql": {Data: []byte(`DROP TABLE orders`)},
+		"m/010_legacy.sql":      {Data: []byte(`CREATE INDEX i ON users (id)`)},
+	}
+	l := &ledgerServer{rows: map[string]map[string]any{}}
+	srv, cl := newTestServer(l.handle)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	got, err := Migrate.Up(ctx, proj, mfs, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "001_users.up.sql,002_orders.up.sql,010_legacy.sql" {
+		t.Fatalf("up=%v", got)
+	}
+
+	// The newest migration has no down script.
+	if _, err := Migrate.Down(ctx, proj, mfs, "m"); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("down legacy err=%v", err)
+	}
+
+	// Roll back to version 1 after the legacy migration is removed from the ledger.
+	delete(l.rows, "010_legacy.sql")
+	// Down uses the recorded script even if the file changed.
+	mfs["m/002_orders.down.sql"] = &fstest.MapFile{Data: []byte(`DROP TABLE changed`)}
+	apply, rollback, err := Migrate.Plan(ctx, proj, mfs, "m", "1")
+	if err != nil || len(apply) != 0 || len(rollback) != 1 || rollback[0].DownSQL != "DROP TABLE orders" {
+		t.Fatalf("plan: apply=%+v rollback=%+v err=%v", apply, rollback, err)
+	}
+	applied, rolled, err := Migrate.To(ctx, proj, mfs, "m", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 || strings.Join(rolled, ",") != "002_orders.up.sql" {
+		t.Fatalf("to: applied=%v rolled=%v", applied, rolled)
+	}
+	if last := l.scripts[len(l.scripts)-1]; last != "DROP TABLE orders;" {
+		t.Fatalf("down script=%q", last)
+	}
+
+	id, err := Migrate.Redo(ctx, proj, mfs, "m")
+	if err != nil || id != "001_users.up.sql" {
+		t.Fatalf("redo id=%q err=%v", id, err)
+	}
+	n := len(l.scripts)
+	if l.scripts[n-2] != "DROP TABLE users;" || l.scripts[n-1] != "CREATE TABLE users (id INTEGER);" {
+		t.Fatalf("redo scripts=%v", l.scripts[n-2:])
+	}
+
+	// Tampered ledger rows are refused.
+	l.rows["001_users.up.sql"]["down_sql"] = "DROP TABLE everything"
+	if _, err := Migrate.Down(ctx, proj, mfs, "m"); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("tamper err=%v", err)
+	}
+
+	mfs["m/003_orphan.down.sql"] = &fstest.MapFile{Data: []byte(`DROP TABLE x`)}
+	if _, err := Migrate.Up(ctx, proj, mfs, "m"); err == nil {
+		t.Fatal("expected error for down file without up file")
+	}
+}
+
+func TestMigrator_Checksums_Drift_Status(t *testing.T) {
+	mfs := fstest.MapFS{
+		"m/001_a.sql":      {Data: []byte(`CREATE TABLE a (id INTEGER)`)},
+		"m/002_b.up.sql":   {Data: []byte(`CREATE TABLE b (id INTEGER)`)},
+		"m/002_b.down.sql": {Data: []byte(`DROP TABLE b`)},
+	}
+	l := &ledgerServer{rows: map[string]map[string]any{}}
+	srv, cl := newTestServer(l.handle)
+	defer srv.Close()
+
+	var events []string
+	cl.Logger = func(event string, _ map[string]any) { events = append(events, event) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	// Status on a fresh project reads only and reports everything pending.
+	st, err := Migrate.Status(ctx, proj, mfs, "m")
+	if err != nil || len(st.Pending) != 2 || len(st.Applied) != 0 {
+		t.Fatalf("fresh status=%+v err=%v", st, err)
+	}
+	for _, q := range l.sent {
+		if !strings.HasPrefix(q, "pragma") && !strings.HasPrefix(q, "select") {
+			t.Fatalf("status wrote to the project: %q", q)
+		}
+	}
+
+	if _, err := Migrate.Up(ctx, proj, mfs, "m"); err != nil {
+		t.Fatal(err)
+	}
+	mfs["m/003_c.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE c (id INTEGER)`)}
+	mfs["m/001_a.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE a (id INTEGER, x TEXT)`)}
+
+	st, err = Migrate.Status(ctx, proj, mfs, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Applied) != 2 || len(st.Pending) != 1 || st.Pending[0].ID != "003_c.sql" {
+		t.Fatalf("status=%+v", st)
+	}
+	if len(st.Drifted) != 1 || st.Drifted[0].ID != "001_a.sql" || !st.Applied[1].Reversible {
+		t.Fatalf("drifted=%+v applied=%+v", st.Drifted, st.Applied)
+	}
+
+	if _, err := Migrate.Up(ctx, proj, mfs, "m"); !errors.Is(err, ErrMigrationDrift) {
+		t.Fatalf("drift err=%v", err)
+	}
+
+	lenient := Migrator{AllowDrift: true}
+	got, err := lenient.Up(ctx, proj, mfs, "m")
+	if err != nil || len(got) != 1 || got[0] != "003_c.sql" {
+		t.Fatalf("lenient up=%v err=%v", got, err)
+	}
+	if !containsEvent(events, "migrate_drift") {
+		t.Fatalf("events=%v", events)
+	}
+}
+
+func containsEvent(events []string, want string) bool {
+	for _, e := range events {
+		if e == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMigrator_GoMigrations(t *testing.T) {
+	mfs := fstest.MapFS{
+		"m/0006_items.sql": {Data: []byte(`CREATE TABLE items (id INTEGER, data TEXT)`)},
+		"m/0008_index.sql": {Data: []byte(`CREATE INDEX items_id ON items (id)`)},
+	}
+	l := &ledgerServer{rows: map[string]map[string]any{}}
+	srv, cl := newTestServer(l.handle)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	var order []string
+	var m Migrator
+	m.RegisterReversible("0007_backfill",
+		func(ctx context.Context, p Project) error {
+			order = append(order, "up")
+			_, err := p.SQL(ctx, `UPDATE items SET data = ?`, []any{"{}"})
+			return err
+		},
+		func(ctx context.Context, p Project) error {
+			order = append(order, "down")
+			return nil
+		},
+	)
+
+	got, err := m.Up(ctx, proj, mfs, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "0006_items.sql,0007_backfill,0008_index.sql" {
+		t.Fatalf("order=%v", got)
+	}
+	if l.rows["0007_backfill"]["checksum"] != nil {
+		t.Fatalf("go migration checksum=%v", l.rows["0007_backfill"]["checksum"])
+	}
+	if got, _ := m.Up(ctx, proj, mfs, "m"); len(got) != 0 || len(order) != 1 {
+		t.Fatalf("rerun applied=%v calls=%v", got, order)
+	}
+
+	if _, rolled, err := m.To(ctx, proj, mfs, "m", "6"); err == nil {
+		t.Fatalf("expected irreversible 0008, rolled=%v", rolled)
+	}
+	delete(l.rows, "0008_index.sql")
+	if _, rolled, err := m.To(ctx, proj, mfs, "m", "6"); err != nil || len(rolled) != 1 || order[len(order)-1] != "down" {
+		t.Fatalf("to: rolled=%v err=%v calls=%v", rolled, err, order)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate Register")
+		}
+	}()
+	m.Register("0007_backfill", func(context.Context, Project) error { return nil })
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	mfs := fstest.MapFS{"m/001_a.sql": {Data: []byte(`CREATE TABLE a (id INTEGER)`)}}
+	l := &ledgerServer{rows: map[string]map[string]any{}}
+	srv, cl := newTestServer(l.handle)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	// A live lease held by another runner blocks until LockWait elapses.
+	l.lockOwner, l.lockUntil = "other", time.Now().Add(time.Hour).UnixMilli()
+	blocked := Migrator{LockOwner: "me", LockWait: 100 * time.Millisecond}
+	if _, err := blocked.Up(ctx, proj, mfs, "m"); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("locked err=%v", err)
+	}
+	if len(l.rows) != 0 {
+		t.Fatalf("applied while locked: %v", l.rows)
+	}
+
+	// A stale lease is taken over, and the lock is released afterwards.
+	l.lockUntil = time.Now().Add(-time.Second).UnixMilli()
+	got, err := blocked.Up(ctx, proj, mfs, "m")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("takeover applied=%v err=%v", got, err)
+	}
+	if l.lockOwner != "" {
+		t.Fatalf("lock not released: %q", l.lockOwner)
+	}
+
+	// Concurrent runners apply each migration once.
+	mfs["m/002_b.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE b (id INTEGER)`)}
+	var wg sync.WaitGroup
+	results := make([][]string, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m := Migrator{LockOwner: fmt.Sprintf("runner-%d", i)}
+			results[i], _ = m.Up(ctx, proj, mfs, "m")
+		}(i)
+	}
+	wg.Wait()
+	if n := len(results[0]) + len(results[1]); n != 1 {
+		t.Fatalf("concurrent results=%v", results)
+	}
+}
+
+func TestMigrator_LockHeartbeat(t *testing.T) {
+	l := &ledgerServer{rows: map[string]map[string]any{}}
+	srv, cl := newTestServer(l.handle)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	// A step that outlives the TTL keeps the lease renewed, so a second
+	// runner cannot take it over mid-step.
+	var m Migrator
+	m.LockOwner, m.LockTTL = "slow", 60*time.Millisecond
+	m.Register("0001_slow", func(ctx context.Context, p Project) error {
+		other := Migrator{LockOwner: "other", LockWait: 200 * time.Millisecond}
+		if _, err := other.Up(ctx, p, fstest.MapFS{}, "."); !errors.Is(err, ErrMigrationLocked) {
+			return fmt.Errorf("second runner err=%v", err)
+		}
+		return nil
+	})
+	if got, err := m.Up(ctx, proj, fstest.MapFS{}, "."); err != nil || len(got) != 1 {
+		t.Fatalf("applied=%v err=%v", got, err)
+	}
+
+	// A lease taken over mid-step cancels the step with ErrMigrationLockLost.
+	var lost Migrator
+	lost.LockOwner, lost.LockTTL = "victim", 60*time.Millisecond
+	lost.Register("0002_stolen", func(ctx context.Context, p Project) error {
+		l.mu.Lock()
+		l.lockOwner = "thief"
+		l.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if _, err := lost.Up(ctx, proj, fstest.MapFS{}, "."); !errors.Is(err, ErrMigrationLockLost) {
+		t.Fatalf("stolen err=%v", err)
+	}
+	if _, ok := l.rows["0002_stolen"]; ok {
+		t.Fatal("stolen migration recorded")
+	}
+}
@@ -69,11 +69,16 @@ type SQLRequest struct {
 }
 
 // SQLResponse supports both DDL/DML and SELECT shapes.
+// Numeric row values decode as json.Number so large integers keep their
+// precision. Columns lists result columns in select order.
 type SQLResponse struct {
 	OK       bool                     `json:"ok"`
 	RowCount *int                     `json:"row_count,omitempty"`
+	Columns  []Column                 `json:"columns,omitempty"`
 	Rows     []map[string]interface{} `json:"rows,omitempty"`
 	Error    string                   `json:"error,omitempty"`
+
+	values [][]any // row values aligned with Columns
 }
 
 // ---- Tables and Status Models ----
@@ -83,15 +88,103 @@ type ListTablesResponse struct {
 }
 
 type BrowseRowsResponse struct {
-	Limit  int                      `json:"limit"`
-	Offset int                      `json:"offset"`
-	Table  string                   `json:"table"`
-	Rows   []map[string]interface{} `json:"rows"`
+	Limit   int                      `json:"limit"`
+	Offset  int                      `json:"offset"`
+	Table   string                   `json:"table"`
+	Columns []Column                 `json:"columns,omitempty"`
+	Rows    []map[string]interface{} `json:"rows"`
+
+	values [][]any // row values aligned with Columns
+}
+
+// TableSchema describes a table. Columns follow PRAGMA table_info order.
+// Keys the SDK does not model are kept in Extra; Raw returns the response
+// as decoded until the typed fields are changed.
+type TableSchema struct {
+	Name        string         `json:"name"`
+	SQL         string         `json:"sql,omitempty"`
+	Columns     []ColumnSchema `json:"columns"`
+	Indexes     []IndexSchema  `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKey   `json:"foreign_keys,omitempty"`
+	Triggers    []Trigger      `json:"triggers,omitempty"`
+	Extra       map[string]any `json:"-"`
+
+	raw *received
+}
+
+// ColumnSchema describes a table column. PK is the 1-based position of the
+// column in the primary key, or 0.
+type ColumnSchema struct {
+	CID     int    `json:"cid"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	NotNull bool   `json:"notnull"`
+	Default any    `json:"dflt_value"`
+	PK      int    `json:"pk"`
+}
+
+// IndexSchema describes an index. Origin is "c" for CREATE INDEX, "u" for
+// a UNIQUE constraint and "pk" for the primary key. SQL is the CREATE
+// INDEX statement when known; constraint indexes have none.
+type IndexSchema struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Origin  string   `json:"origin,omitempty"`
+	Partial bool     `json:"partial"`
+	Columns []string `json:"columns,omitempty"`
+	SQL     string   `json:"sql,omitempty"`
+}
+
+// Trigger is a trigger defined on a table, with its CREATE TRIGGER
+// statement.
+type Trigger struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
 }
 
-// TableSchema is intentionally open to allow backend evolution.
-type TableSchema = map[string]interface{}
+// ForeignKey describes a foreign key constraint. Columns and RefColumns
+// pair up by position.
+type ForeignKey struct {
+	ID         int      `json:"id"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"table"`
+	RefColumns []string `json:"ref_columns"`
+	OnUpdate   string   `json:"on_update,omitempty"`
+	OnDelete   string   `json:"on_delete,omitempty"`
+}
 
-// ProjectStatus and CommitResponse are intentionally open.
-type ProjectStatus = map[string]interface{}
-type CommitResponse = map[string]interface{}
+// ProjectStatus reports the state of a project. Keys the SDK does not
+// model are kept in Extra.
+type ProjectStatus struct {
+	OK             bool           `json:"ok"`
+	ProjectID      string         `json:"project_id"`
+	DBID           string         `json:"db_id"`
+	HolderID       string         `json:"holder_id,omitempty"`
+	ProjectName    string         `json:"project_name,omitempty"`
+	Tables         int            `json:"tables"`
+	PendingChanges int            `json:"pending_changes"`
+	Commits        int            `json:"commits"`
+	LastCommitAt   string         `json:"last_commit_at,omitempty"`
+	LastTxDigest   string         `json:"last_tx_digest,omitempty"`
+	LastBlobID     string         `json:"last_blob_id,omitempty"`
+	Epoch          int            `json:"epoch"`
+	EpochSet       int            `json:"epoch_set"`
+	CycleEnd       int            `json:"cycle_end"`
+	Extra          map[string]any `json:"-"`
+
+	raw *received
+}
+
+// CommitResponse reports a commit to chain-backed storage. Keys the SDK
+// does not model are kept in Extra.
+type CommitResponse struct {
+	Committed   bool           `json:"committed"`
+	ProjectID   string         `json:"project_id"`
+	TxDigest    string         `json:"tx_digest"`
+	BlobID      string         `json:"blob_id"`
+	Writes      int            `json:"writes"`
+	CommittedAt string         `json:"committed_at,omitempty"`
+	Extra       map[string]any `json:"-"`
+
+	raw *received
+}
@@ -24,12 +24,47 @@ func WithBackoff(init, max time.Duration) Option {
 }
 func WithLogger(l Logger) Option { return func(c *Client) { c.Logger = l } }
 
+// WithRetryPolicy replaces the retry behaviour configured by WithRetries
+// and WithBackoff.
+func WithRetryPolicy(p RetryPolicy) Option { return func(c *Client) { c.RetryPolicy = p } }
+
+// WithRateLimit paces requests to rps per second with bursts of up to
+// burst, adapting to 429 responses. All requests of the client share it.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		if rps > 0 {
+			c.Limiter = NewRateLimiter(rps, burst)
+		}
+	}
+}
+
+// WithMaxInFlight caps the number of requests in flight at once. A
+// streamed response counts until its body is closed.
+func WithMaxInFlight(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.inflight = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithCircuitBreaker installs a circuit breaker; see CircuitBreaker.
+func WithCircuitBreaker(b *CircuitBreaker) Option { return func(c *Client) { c.Breaker = b } }
+
+// WithHedging enables hedged read-only requests; see Hedging.
+func WithHedging(h *Hedging) Option { return func(c *Client) { c.Hedge = h } }
+
+// WithIdempotency sets how SQL writes without an idempotency key are
+// protected against duplicate application on retry.
+func WithIdempotency(m IdempotencyMode) Option { return func(c *Client) { c.Idempotency = m } }
+
 // CallOption customizes a single API call (for example, idempotency keys).
 type CallOption func(*callOptions)
 
 type callOptions struct {
-	headers http.Header
-	label   string
+	headers   http.Header
+	label     string
+	maxParams int
 }
 
 // WithIdempotencyKey attaches an idempotency key for write operations.
@@ -10,6 +10,7 @@ type Pager struct {
 	Limit   int
 	Offset  int
 	Done    bool
+	Options []CallOption // applied to every Browse call
 }
 
 // Next returns the next batch of rows, or nil when iteration finishes.
@@ -17,7 +18,7 @@ func (p *Pager) Next(ctx context.Context) ([]map[string]any, error) {
 	if p.Done {
 		return nil, nil
 	}
-	resp, err := p.Project.Browse(ctx, p.Table, p.Limit, p.Offset)
+	resp, err := p.Project.Browse(ctx, p.Table, p.Limit, p.Offset, p.Options...)
 	if err != nil {
 		return nil, err
 	}
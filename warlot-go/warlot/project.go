@@ -28,7 +28,7 @@ func (p Project) Browse(ctx context.Context, table string, limit, offset int, op
 }
 
 // Schema returns a table schema from the bound project.
-func (p Project) Schema(ctx context.Context, table string, opts ...CallOption) (TableSchema, error) {
+func (p Project) Schema(ctx context.Context, table string, opts ...CallOption) (*TableSchema, error) {
 	return p.Client.GetTableSchema(ctx, p.ID, table, opts...)
 }
 
@@ -38,11 +38,11 @@ func (p Project) Count(ctx context.Context, opts ...CallOption) (*TableCountResp
 }
 
 // Status returns the project status map.
-func (p Project) Status(ctx context.Context, opts ...CallOption) (ProjectStatus, error) {
+func (p Project) Status(ctx context.Context, opts ...CallOption) (*ProjectStatus, error) {
 	return p.Client.GetProjectStatus(ctx, p.ID, opts...)
 }
 
 // Commit triggers a commit for the bound project.
-func (p Project) Commit(ctx context.Context, opts ...CallOption) (CommitResponse, error) {
+func (p Project) Commit(ctx context.Context, opts ...CallOption) (*CommitResponse, error) {
 	return p.Client.CommitProject(ctx, p.ID, opts...)
 }
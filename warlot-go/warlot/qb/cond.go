@@ -0,0 +1,200 @@
+package qb
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Cond is a boolean condition for WHERE, HAVING and JOIN clauses.
+type Cond interface {
+	writeCond(w *writer)
+}
+
+// Raw is a SQL fragment with its own positional parameters. It is written
+// verbatim, so it must not contain untrusted text. Raw is accepted as a
+// condition, as a value in Insert and Update, and as a column via
+// SelectBuilder.ColumnExpr.
+type Raw struct {
+	SQL  string
+	Args []any
+}
+
+// Expr returns a Raw fragment.
+func Expr(sql string, args ...any) Raw { return Raw{SQL: sql, Args: args} }
+
+func (r Raw) writeCond(w *writer) {
+	w.str("(")
+	w.raw(r)
+	w.str(")")
+}
+
+// Col is a column reference used as a value, as in a join condition
+// Eq{"s.product_id": Col("p.id")}. It is quoted like any other identifier
+// instead of being bound as a parameter.
+type Col string
+
+// Eq matches columns equal to values. A nil value or nil pointer becomes
+// IS NULL and a slice becomes IN (...). Keys are written in sorted order.
+type Eq map[string]any
+
+// NotEq is the negation of Eq: <>, IS NOT NULL and NOT IN.
+type NotEq map[string]any
+
+// Lt, Lte, Gt and Gte compare columns with <, <=, > and >=.
+type (
+	Lt  map[string]any
+	Lte map[string]any
+	Gt  map[string]any
+	Gte map[string]any
+)
+
+// Like matches columns with LIKE patterns.
+type Like map[string]any
+
+func (c Eq) writeCond(w *writer)    { writeEq(w, c, false) }
+func (c NotEq) writeCond(w *writer) { writeEq(w, c, true) }
+func (c Lt) writeCond(w *writer)    { writeCmp(w, c, " < ") }
+func (c Lte) writeCond(w *writer)   { writeCmp(w, c, " <= ") }
+func (c Gt) writeCond(w *writer)    { writeCmp(w, c, " > ") }
+func (c Gte) writeCond(w *writer)   { writeCmp(w, c, " >= ") }
+func (c Like) writeCond(w *writer)  { writeCmp(w, c, " LIKE ") }
+
+// And joins conditions with AND. An empty And is true.
+type And []Cond
+
+// Or joins conditions with OR. An empty Or is false.
+type Or []Cond
+
+func (c And) writeCond(w *writer) { writeJoined(w, c, " AND ", "1=1") }
+func (c Or) writeCond(w *writer)  { writeJoined(w, c, " OR ", "1=0") }
+
+// Not negates a condition.
+func Not(c Cond) Cond { return notCond{c} }
+
+type notCond struct{ c Cond }
+
+func (c notCond) writeCond(w *writer) {
+	w.str("NOT ")
+	c.c.writeCond(w)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeEq(w *writer, m map[string]any, negate bool) {
+	if len(m) == 0 {
+		w.str("1=1")
+		return
+	}
+	w.str("(")
+	for i, k := range sortedKeys(m) {
+		if i > 0 {
+			w.str(" AND ")
+		}
+		w.ident(k)
+		v := m[k]
+		switch {
+		case isNil(v) && negate:
+			w.str(" IS NOT NULL")
+		case isNil(v):
+			w.str(" IS NULL")
+		case isList(v):
+			writeIn(w, reflect.ValueOf(v), negate)
+		case negate:
+			w.str(" <> ")
+			w.value(v)
+		default:
+			w.str(" = ")
+			w.value(v)
+		}
+	}
+	w.str(")")
+}
+
+// isNil reports whether v is nil or a nil pointer.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// isList reports whether v is a slice or array other than []byte.
+func isList(v any) bool {
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	k := reflect.TypeOf(v).Kind()
+	return k == reflect.Slice || k == reflect.Array
+}
+
+func writeIn(w *writer, rv reflect.Value, negate bool) {
+	if negate {
+		w.str(" NOT")
+	}
+	if rv.Len() == 0 {
+		// An empty list is not valid SQL; use an empty subquery instead.
+		w.str(" IN (SELECT 1 WHERE 0)")
+		return
+	}
+	w.str(" IN (")
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			w.str(", ")
+		}
+		w.value(rv.Index(i).Interface())
+	}
+	w.str(")")
+}
+
+func writeCmp(w *writer, m map[string]any, op string) {
+	if len(m) == 0 {
+		w.str("1=1")
+		return
+	}
+	w.str("(")
+	for i, k := range sortedKeys(m) {
+		if i > 0 {
+			w.str(" AND ")
+		}
+		w.ident(k)
+		w.str(op)
+		w.value(m[k])
+	}
+	w.str(")")
+}
+
+func writeJoined(w *writer, cs []Cond, sep, empty string) {
+	if len(cs) == 0 {
+		w.str(empty)
+		return
+	}
+	w.str("(")
+	for i, c := range cs {
+		if i > 0 {
+			w.str(sep)
+		}
+		c.writeCond(w)
+	}
+	w.str(")")
+}
+
+// writeWhere writes a WHERE or HAVING clause for conds joined with AND.
+func writeWhere(w *writer, kw string, conds []Cond) {
+	if len(conds) == 0 {
+		return
+	}
+	w.str(" " + kw + " ")
+	if len(conds) == 1 {
+		conds[0].writeCond(w)
+		return
+	}
+	And(conds).writeCond(w)
+}
@@ -0,0 +1,184 @@
+// Package qb builds parameterized SQL for the Warlot API. Builders quote
+// identifiers, collect positional parameters in placeholder order and
+// produce a warlot.SQLRequest:
+//
+//	req, err := qb.Select("id", "name").
+//		From("products").
+//		Where(qb.Eq{"sku": sku}).
+//		OrderBy("id DESC").
+//		Limit(10).
+//		Build()
+//
+// The result can be passed to Project.SQL, Client.ExecSQLStream or
+// warlot.Query, or executed with the Exec, Stream and Query helpers.
+package qb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Builder is implemented by every statement builder.
+type Builder interface {
+	Build() (warlot.SQLRequest, error)
+}
+
+// Exec builds b and executes it in p.
+func Exec(ctx context.Context, p warlot.Project, b Builder, opts ...warlot.CallOption) (*warlot.SQLResponse, error) {
+	req, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.ExecSQL(ctx, p.ID, req, opts...)
+}
+
+// Stream builds b and streams its rows from p.
+func Stream(ctx context.Context, p warlot.Project, b Builder, opts ...warlot.CallOption) (*warlot.RowScanner, error) {
+	req, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return p.Client.ExecSQLStream(ctx, p.ID, req, opts...)
+}
+
+// Query builds b and maps its rows into T with warlot.Query.
+func Query[T any](ctx context.Context, p warlot.Project, b Builder, opts ...warlot.CallOption) ([]T, error) {
+	req, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return warlot.Query[T](ctx, p, req.SQL, req.Params, opts...)
+}
+
+// ErrEmptyIdent is returned when a table or column name is empty.
+var ErrEmptyIdent = errors.New("qb: empty identifier")
+
+// QuoteIdent quotes a possibly dotted identifier such as "main.products"
+// for use in SQL text. Embedded double quotes are doubled, so any input
+// yields a single identifier and never alters the statement.
+func QuoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteName quotes an identifier, reporting empty names and empty dotted
+// parts as errors.
+func quoteName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrEmptyIdent
+	}
+	for _, p := range strings.Split(name, ".") {
+		if p == "" {
+			return "", fmt.Errorf("qb: invalid identifier %q", name)
+		}
+	}
+	return QuoteIdent(name), nil
+}
+
+// quoteAliased quotes "name", "name alias" or "name AS alias".
+func quoteAliased(s string) (string, error) {
+	name, alias := splitAlias(s)
+	q, err := quoteName(name)
+	if err != nil || alias == "" {
+		return q, err
+	}
+	a, err := quoteName(alias)
+	if err != nil {
+		return "", err
+	}
+	return q + " AS " + a, nil
+}
+
+func splitAlias(s string) (name, alias string) {
+	f := strings.Fields(s)
+	switch {
+	case len(f) == 3 && strings.EqualFold(f[1], "AS"):
+		return f[0], f[2]
+	case len(f) == 2:
+		return f[0], f[1]
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// quoteColumn quotes a result column: "*", "t.*" or an optionally aliased
+// identifier.
+func quoteColumn(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return s, nil
+	}
+	if t, ok := strings.CutSuffix(s, ".*"); ok {
+		q, err := quoteName(t)
+		return q + ".*", err
+	}
+	return quoteAliased(s)
+}
+
+// quoteColumns quotes a list of column names joined by ", ".
+func quoteColumns(cols []string) (string, error) {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		q, err := quoteName(c)
+		if err != nil {
+			return "", err
+		}
+		out[i] = q
+	}
+	return strings.Join(out, ", "), nil
+}
+
+// writer accumulates SQL text and parameters.
+type writer struct {
+	sb   strings.Builder
+	args []any
+	err  error
+}
+
+func (w *writer) str(s string) { w.sb.WriteString(s) }
+
+func (w *writer) ident(name string) {
+	q, err := quoteName(name)
+	w.fail(err)
+	w.str(q)
+}
+
+// value writes a placeholder for v, the SQL of a Raw expression or a
+// quoted Col.
+func (w *writer) value(v any) {
+	switch v := v.(type) {
+	case Raw:
+		w.raw(v)
+		return
+	case Col:
+		w.ident(string(v))
+		return
+	}
+	w.str("?")
+	w.args = append(w.args, v)
+}
+
+func (w *writer) raw(r Raw) {
+	w.str(r.SQL)
+	w.args = append(w.args, r.Args...)
+}
+
+func (w *writer) fail(err error) {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *writer) request() (warlot.SQLRequest, error) {
+	if w.err != nil {
+		return warlot.SQLRequest{}, w.err
+	}
+	return warlot.SQLRequest{SQL: w.sb.String(), Params: w.args}, nil
+}
@@ -0,0 +1,194 @@
+package qb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// SelectBuilder builds a SELECT statement. Methods modify the builder and
+// return it for chaining.
+type SelectBuilder struct {
+	distinct bool
+	columns  []column
+	from     string
+	joins    []join
+	where    []Cond
+	groupBy  []string
+	having   []Cond
+	orderBy  []string
+	limit    int
+	offset   int
+}
+
+type column struct {
+	name string
+	expr *Raw
+}
+
+type join struct {
+	kind, table string
+	on          Cond
+}
+
+// Select starts a SELECT of the given columns. Each column is "*", "t.*"
+// or an identifier optionally followed by an alias ("name AS n"). Use
+// ColumnExpr for expressions. No columns means "*".
+func Select(columns ...string) *SelectBuilder {
+	b := &SelectBuilder{limit: -1}
+	for _, c := range columns {
+		b.columns = append(b.columns, column{name: c})
+	}
+	return b
+}
+
+// Distinct makes the statement SELECT DISTINCT.
+func (b *SelectBuilder) Distinct() *SelectBuilder { b.distinct = true; return b }
+
+// Columns appends columns as in Select.
+func (b *SelectBuilder) Columns(columns ...string) *SelectBuilder {
+	for _, c := range columns {
+		b.columns = append(b.columns, column{name: c})
+	}
+	return b
+}
+
+// ColumnExpr appends an expression column such as Expr("COUNT(*) AS n").
+func (b *SelectBuilder) ColumnExpr(e Raw) *SelectBuilder {
+	b.columns = append(b.columns, column{expr: &e})
+	return b
+}
+
+// From sets the table, optionally with an alias ("products p").
+func (b *SelectBuilder) From(table string) *SelectBuilder { b.from = table; return b }
+
+// Join adds an INNER JOIN. Compare columns with Col, as in
+// Join("stock s", Eq{"s.product_id": Col("p.id")}); a plain string is bound
+// as a parameter.
+func (b *SelectBuilder) Join(table string, on Cond) *SelectBuilder {
+	b.joins = append(b.joins, join{"JOIN", table, on})
+	return b
+}
+
+// LeftJoin adds a LEFT JOIN.
+func (b *SelectBuilder) LeftJoin(table string, on Cond) *SelectBuilder {
+	b.joins = append(b.joins, join{"LEFT JOIN", table, on})
+	return b
+}
+
+// Where adds conditions. Conditions from all calls are joined with AND.
+func (b *SelectBuilder) Where(conds ...Cond) *SelectBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// GroupBy adds GROUP BY columns.
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, columns...)
+	return b
+}
+
+// Having adds HAVING conditions, joined with AND.
+func (b *SelectBuilder) Having(conds ...Cond) *SelectBuilder {
+	b.having = append(b.having, conds...)
+	return b
+}
+
+// OrderBy adds ORDER BY terms: a column optionally followed by ASC or
+// DESC, for example "id DESC".
+func (b *SelectBuilder) OrderBy(terms ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, terms...)
+	return b
+}
+
+// Limit sets LIMIT; a negative value removes it.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder { b.limit = n; return b }
+
+// Offset sets OFFSET.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder { b.offset = n; return b }
+
+// Build renders the statement.
+func (b *SelectBuilder) Build() (warlot.SQLRequest, error) {
+	w := &writer{}
+	w.str("SELECT ")
+	if b.distinct {
+		w.str("DISTINCT ")
+	}
+	if len(b.columns) == 0 {
+		w.str("*")
+	}
+	for i, c := range b.columns {
+		if i > 0 {
+			w.str(", ")
+		}
+		if c.expr != nil {
+			w.raw(*c.expr)
+			continue
+		}
+		q, err := quoteColumn(c.name)
+		w.fail(err)
+		w.str(q)
+	}
+	if b.from != "" {
+		q, err := quoteAliased(b.from)
+		w.fail(err)
+		w.str(" FROM " + q)
+	} else if len(b.joins) > 0 {
+		w.fail(errors.New("qb: join without FROM"))
+	}
+	for _, j := range b.joins {
+		q, err := quoteAliased(j.table)
+		w.fail(err)
+		w.str(" " + j.kind + " " + q)
+		if j.on != nil {
+			w.str(" ON ")
+			j.on.writeCond(w)
+		}
+	}
+	writeWhere(w, "WHERE", b.where)
+	if len(b.groupBy) > 0 {
+		cols, err := quoteColumns(b.groupBy)
+		w.fail(err)
+		w.str(" GROUP BY " + cols)
+	}
+	writeWhere(w, "HAVING", b.having)
+	if len(b.orderBy) > 0 {
+		w.str(" ORDER BY ")
+		for i, t := range b.orderBy {
+			if i > 0 {
+				w.str(", ")
+			}
+			term, err := orderTerm(t)
+			w.fail(err)
+			w.str(term)
+		}
+	}
+	if b.limit >= 0 {
+		w.str(" LIMIT " + strconv.Itoa(b.limit))
+	}
+	if b.offset > 0 {
+		if b.limit < 0 {
+			w.str(" LIMIT -1")
+		}
+		w.str(" OFFSET " + strconv.Itoa(b.offset))
+	}
+	return w.request()
+}
+
+// orderTerm quotes "col", "col ASC" or "col DESC".
+func orderTerm(t string) (string, error) {
+	f := strings.Fields(t)
+	dir := ""
+	if n := len(f); n == 2 && (strings.EqualFold(f[1], "ASC") || strings.EqualFold(f[1], "DESC")) {
+		dir = " " + strings.ToUpper(f[1])
+		f = f[:1]
+	}
+	if len(f) != 1 {
+		return "", fmt.Errorf("qb: invalid ORDER BY term %q", t)
+	}
+	q, err := quoteName(f[0])
+	return q + dir, err
+}
@@ -0,0 +1,117 @@
+package qb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/warlottest"
+)
+
+func TestSelect_Build(t *testing.T) {
+	cases := []struct {
+		name string
+		b    Builder
+		sql  string
+		args []any
+	}{
+		{
+			"basic",
+			Select("id", "name").From("products").Where(Eq{"sku": "A-1"}).OrderBy("id DESC").Limit(10),
+			`SELECT "id", "name" FROM "products" WHERE ("sku" = ?) ORDER BY "id" DESC LIMIT 10`,
+			[]any{"A-1"},
+		},
+		{
+			"conditions",
+			Select().From("t").Where(
+				Eq{"b": nil, "a": []int{1, 2}},
+				Or{Gt{"n": 5}, Like{"name": "x%"}},
+				Not(NotEq{"c": 3}),
+			),
+			`SELECT * FROM "t" WHERE (("a" IN (?, ?) AND "b" IS NULL) AND (("n" > ?) OR ("name" LIKE ?)) AND NOT ("c" <> ?))`,
+			[]any{1, 2, 5, "x%", 3},
+		},
+		{
+			"aggregate",
+			Select("p.category AS c").ColumnExpr(Expr("COUNT(*) AS n")).From("products p").
+				Join("stock s", Expr(`s.product_id = p.id`)).
+				GroupBy("p.category").Having(Expr("COUNT(*) > ?", 1)).Offset(20),
+			`SELECT "p"."category" AS "c", COUNT(*) AS n FROM "products" AS "p" JOIN "stock" AS "s" ON (s.product_id = p.id) GROUP BY "p"."category" HAVING (COUNT(*) > ?) LIMIT -1 OFFSET 20`,
+			[]any{1},
+		},
+		{
+			"join columns",
+			Select("p.id").From("products p").
+				LeftJoin("stock s", Eq{"s.product_id": Col("p.id"), "s.deleted_at": (*string)(nil)}).
+				Where(NotEq{"s.note": (*string)(nil)}, Gt{"s.qty": Col("p.min_qty")}),
+			`SELECT "p"."id" FROM "products" AS "p" LEFT JOIN "stock" AS "s" ON ("s"."deleted_at" IS NULL AND "s"."product_id" = "p"."id") WHERE (("s"."note" IS NOT NULL) AND ("s"."qty" > "p"."min_qty"))`,
+			nil,
+		},
+		{
+			"hostile identifiers",
+			Select(`x"; DROP TABLE t; --`).From(`t"`).Where(Eq{"in": []string{}}),
+			`SELECT "x""; DROP TABLE t; --" FROM "t""" WHERE ("in" IN (SELECT 1 WHERE 0))`,
+			nil,
+		},
+	}
+	for _, c := range cases {
+		req, err := c.b.Build()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if req.SQL != c.sql {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, req.SQL, c.sql)
+		}
+		if !reflect.DeepEqual(req.Params, c.args) {
+			t.Errorf("%s: params = %v, want %v", c.name, req.Params, c.args)
+		}
+	}
+
+	for _, b := range []Builder{
+		Select("").From("t"),
+		Select().From("t").OrderBy("id; DROP"),
+		Select().From("a..b"),
+	} {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("expected error for %+v", b)
+		}
+	}
+}
+
+func TestSelect_Exec(t *testing.T) {
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	proj := srv.Client().Project("p1")
+
+	if _, err := proj.SQL(ctx, `CREATE TABLE products (id INTEGER PRIMARY KEY, sku TEXT, price REAL)`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Exec(ctx, proj, Insert("products").Columns("sku", "price").Values("A-1", 3.5).Values("B-2", 7)); err != nil {
+		t.Fatal(err)
+	}
+
+	type product struct {
+		ID  int    `json:"id"`
+		SKU string `json:"sku"`
+	}
+	got, err := Query[product](ctx, proj, Select("id", "sku").From("products").Where(Gte{"price": 5}))
+	if err != nil || len(got) != 1 || got[0].SKU != "B-2" {
+		t.Fatalf("query: %+v err=%v", got, err)
+	}
+
+	sc, err := Stream(ctx, proj, Select().From("products").OrderBy("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	var n int
+	var row map[string]any
+	for sc.Next(&row) {
+		n++
+	}
+	if sc.Err() != nil || n != 2 {
+		t.Fatalf("stream: n=%d err=%v", n, sc.Err())
+	}
+}
@@ -0,0 +1,248 @@
+package qb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// InsertBuilder builds an INSERT statement, optionally with several rows,
+// an upsert clause and RETURNING.
+type InsertBuilder struct {
+	or        string
+	table     string
+	columns   []string
+	rows      [][]any
+	conflict  []string
+	doNothing bool
+	update    []string
+	returning []string
+}
+
+// Insert starts an INSERT into table.
+func Insert(table string) *InsertBuilder { return &InsertBuilder{table: table} }
+
+// OrReplace makes the statement INSERT OR REPLACE.
+func (b *InsertBuilder) OrReplace() *InsertBuilder { b.or = "REPLACE"; return b }
+
+// OrIgnore makes the statement INSERT OR IGNORE.
+func (b *InsertBuilder) OrIgnore() *InsertBuilder { b.or = "IGNORE"; return b }
+
+// Columns sets the inserted columns.
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// Values appends a row. Values may be Raw expressions.
+func (b *InsertBuilder) Values(values ...any) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// SetMap sets columns and a single row from m, in sorted column order.
+func (b *InsertBuilder) SetMap(m map[string]any) *InsertBuilder {
+	keys := sortedKeys(m)
+	row := make([]any, len(keys))
+	for i, k := range keys {
+		row[i] = m[k]
+	}
+	b.columns = keys
+	b.rows = [][]any{row}
+	return b
+}
+
+// OnConflict names the conflict target columns for DoNothing or
+// DoUpdate.
+func (b *InsertBuilder) OnConflict(columns ...string) *InsertBuilder {
+	b.conflict = columns
+	return b
+}
+
+// DoNothing adds ON CONFLICT ... DO NOTHING.
+func (b *InsertBuilder) DoNothing() *InsertBuilder { b.doNothing = true; return b }
+
+// DoUpdate adds ON CONFLICT ... DO UPDATE SET col = excluded.col for each
+// column.
+func (b *InsertBuilder) DoUpdate(columns ...string) *InsertBuilder {
+	b.update = append(b.update, columns...)
+	return b
+}
+
+// Returning adds a RETURNING clause.
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build renders the statement.
+func (b *InsertBuilder) Build() (warlot.SQLRequest, error) {
+	if len(b.rows) == 0 {
+		return warlot.SQLRequest{}, errors.New("qb: insert has no values")
+	}
+	w := &writer{}
+	w.str("INSERT ")
+	if b.or != "" {
+		w.str("OR " + b.or + " ")
+	}
+	w.str("INTO ")
+	w.ident(b.table)
+	if len(b.columns) > 0 {
+		cols, err := quoteColumns(b.columns)
+		w.fail(err)
+		w.str(" (" + cols + ")")
+	}
+	w.str(" VALUES ")
+	for i, row := range b.rows {
+		if len(b.columns) > 0 && len(row) != len(b.columns) {
+			w.fail(fmt.Errorf("qb: row %d has %d values for %d columns", i, len(row), len(b.columns)))
+		}
+		if i > 0 {
+			w.str(", ")
+		}
+		w.str("(")
+		for j, v := range row {
+			if j > 0 {
+				w.str(", ")
+			}
+			w.value(v)
+		}
+		w.str(")")
+	}
+	if b.doNothing || len(b.update) > 0 {
+		w.str(" ON CONFLICT")
+		if len(b.conflict) > 0 {
+			cols, err := quoteColumns(b.conflict)
+			w.fail(err)
+			w.str(" (" + cols + ")")
+		}
+		if b.doNothing {
+			w.str(" DO NOTHING")
+		} else {
+			w.str(" DO UPDATE SET ")
+			for i, c := range b.update {
+				if i > 0 {
+					w.str(", ")
+				}
+				q, err := quoteName(c)
+				w.fail(err)
+				w.str(q + " = excluded." + q)
+			}
+		}
+	}
+	writeReturning(w, b.returning)
+	return w.request()
+}
+
+// UpdateBuilder builds an UPDATE statement.
+type UpdateBuilder struct {
+	table     string
+	set       []assignment
+	where     []Cond
+	returning []string
+}
+
+type assignment struct {
+	column string
+	value  any
+}
+
+// Update starts an UPDATE of table.
+func Update(table string) *UpdateBuilder { return &UpdateBuilder{table: table} }
+
+// Set assigns a value, which may be a Raw expression such as
+// Expr("n + ?", 1).
+func (b *UpdateBuilder) Set(column string, value any) *UpdateBuilder {
+	b.set = append(b.set, assignment{column, value})
+	return b
+}
+
+// SetMap assigns every entry of m, in sorted column order.
+func (b *UpdateBuilder) SetMap(m map[string]any) *UpdateBuilder {
+	for _, k := range sortedKeys(m) {
+		b.Set(k, m[k])
+	}
+	return b
+}
+
+// Where adds conditions, joined with AND.
+func (b *UpdateBuilder) Where(conds ...Cond) *UpdateBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Returning adds a RETURNING clause.
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build renders the statement.
+func (b *UpdateBuilder) Build() (warlot.SQLRequest, error) {
+	if len(b.set) == 0 {
+		return warlot.SQLRequest{}, errors.New("qb: update has no assignments")
+	}
+	w := &writer{}
+	w.str("UPDATE ")
+	w.ident(b.table)
+	w.str(" SET ")
+	for i, a := range b.set {
+		if i > 0 {
+			w.str(", ")
+		}
+		w.ident(a.column)
+		w.str(" = ")
+		w.value(a.value)
+	}
+	writeWhere(w, "WHERE", b.where)
+	writeReturning(w, b.returning)
+	return w.request()
+}
+
+// DeleteBuilder builds a DELETE statement.
+type DeleteBuilder struct {
+	table     string
+	where     []Cond
+	returning []string
+}
+
+// Delete starts a DELETE from table.
+func Delete(table string) *DeleteBuilder { return &DeleteBuilder{table: table} }
+
+// Where adds conditions, joined with AND.
+func (b *DeleteBuilder) Where(conds ...Cond) *DeleteBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Returning adds a RETURNING clause.
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build renders the statement.
+func (b *DeleteBuilder) Build() (warlot.SQLRequest, error) {
+	w := &writer{}
+	w.str("DELETE FROM ")
+	w.ident(b.table)
+	writeWhere(w, "WHERE", b.where)
+	writeReturning(w, b.returning)
+	return w.request()
+}
+
+func writeReturning(w *writer, columns []string) {
+	if len(columns) == 0 {
+		return
+	}
+	w.str(" RETURNING ")
+	for i, c := range columns {
+		if i > 0 {
+			w.str(", ")
+		}
+		q, err := quoteColumn(c)
+		w.fail(err)
+		w.str(q)
+	}
+}
@@ -0,0 +1,69 @@
+package qb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrite_Build(t *testing.T) {
+	cases := []struct {
+		name string
+		b    Builder
+		sql  string
+		args []any
+	}{
+		{
+			"insert rows",
+			Insert("t").Columns("a", "b").Values(1, "x").Values(2, Expr("upper(?)", "y")),
+			`INSERT INTO "t" ("a", "b") VALUES (?, ?), (?, upper(?))`,
+			[]any{1, "x", 2, "y"},
+		},
+		{
+			"upsert",
+			Insert("t").SetMap(map[string]any{"id": 1, "n": 2}).OnConflict("id").DoUpdate("n").Returning("id"),
+			`INSERT INTO "t" ("id", "n") VALUES (?, ?) ON CONFLICT ("id") DO UPDATE SET "n" = excluded."n" RETURNING "id"`,
+			[]any{1, 2},
+		},
+		{
+			"insert or ignore",
+			Insert("t").OrIgnore().Columns("id").Values(1),
+			`INSERT OR IGNORE INTO "t" ("id") VALUES (?)`,
+			[]any{1},
+		},
+		{
+			"update",
+			Update("t").Set("n", Expr(`"n" + ?`, 1)).Set("name", "z").Where(Eq{"id": 7}),
+			`UPDATE "t" SET "n" = "n" + ?, "name" = ? WHERE ("id" = ?)`,
+			[]any{1, "z", 7},
+		},
+		{
+			"delete",
+			Delete("t").Where(Lt{"id": 3}).Returning("*"),
+			`DELETE FROM "t" WHERE ("id" < ?) RETURNING *`,
+			[]any{3},
+		},
+	}
+	for _, c := range cases {
+		req, err := c.b.Build()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if req.SQL != c.sql {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, req.SQL, c.sql)
+		}
+		if !reflect.DeepEqual(req.Params, c.args) {
+			t.Errorf("%s: params = %v, want %v", c.name, req.Params, c.args)
+		}
+	}
+
+	for _, b := range []Builder{
+		Insert("t").Columns("a"),
+		Insert("t").Columns("a", "b").Values(1),
+		Update("t"),
+		Delete(""),
+	} {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("expected error for %+v", b)
+		}
+	}
+}
@@ -8,7 +8,8 @@ import (
 
 // Query maps a SELECT result set into a typed slice using JSON round-trip.
 // For precise mapping, struct fields should be tagged with column names,
-// for example: `json:"created_at"`.
+// for example: `json:"created_at"`. Numbers are carried as json.Number, so
+// integer fields receive exact values.
 func Query[T any](ctx context.Context, p Project, sql string, params []any, opts ...CallOption) ([]T, error) {
 	res, err := p.SQL(ctx, sql, params, opts...)
 	if err != nil {
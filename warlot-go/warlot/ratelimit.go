@@ -0,0 +1,146 @@
+package warlot
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every request of a Client. When
+// the server answers 429 or sends Retry-After, the rate is halved and
+// requests pause for the hinted delay; afterwards the rate climbs back by
+// a tenth of the configured rate per second.
+//
+// Each attempt takes a token, so retries are paced as well.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  float64 // configured requests per second
+	rate   float64 // current requests per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	paused time.Time // no tokens are handed out before this
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rps requests per second with
+// bursts of up to burst requests. A burst below 1 is treated as 1; a rate
+// of zero or less does not limit.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{limit: rps, rate: rps, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// Rate returns the current rate in requests per second.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.now())
+	return l.rate
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.limit <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := l.now()
+	l.advance(now)
+	l.tokens--
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if now.Before(l.paused) {
+		d += l.paused.Sub(now)
+	}
+	l.mu.Unlock()
+
+	if err := sleepCtx(ctx, d); err != nil {
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Throttle reports a rate-limit response. The rate is halved, down to a
+// twentieth of the configured rate, and no request is let through for
+// retryAfter.
+func (l *RateLimiter) Throttle(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.advance(now)
+	l.rate /= 2
+	if min := l.limit / 20; l.rate < min {
+		l.rate = min
+	}
+	if l.tokens > 0 {
+		l.tokens = 0
+	}
+	if until := now.Add(retryAfter); until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// advance refills tokens and recovers the rate up to now. The caller holds mu.
+func (l *RateLimiter) advance(now time.Time) {
+	if l.last.IsZero() {
+		l.last = now
+		return
+	}
+	from := l.last
+	if from.Before(l.paused) {
+		from = l.paused
+	}
+	if elapsed := now.Sub(from).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.rate += elapsed * l.limit / 10
+		if l.rate > l.limit {
+			l.rate = l.limit
+		}
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+}
+
+// acquire waits for the rate limiter and a free in-flight slot. The
+// returned func releases the slot.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.inflight == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-c.inflight }) }, nil
+}
+
+// releaseBody frees an in-flight slot when a streamed response is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
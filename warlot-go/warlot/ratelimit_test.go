@@ -0,0 +1,147 @@
+package warlot
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_ThrottleAndRecover(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewRateLimiter(10, 2)
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// The burst passes immediately.
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		if err := l.Wait(ctx); err != nil || time.Since(start) > 50*time.Millisecond {
+			t.Fatalf("burst request %d delayed or failed: %v", i, err)
+		}
+	}
+
+	l.Throttle(0)
+	if r := l.Rate(); r != 5 {
+		t.Fatalf("rate after throttle = %v, want 5", r)
+	}
+	for i := 0; i < 10; i++ {
+		l.Throttle(0)
+	}
+	if r := l.Rate(); r != 0.5 {
+		t.Fatalf("rate floor = %v, want 0.5", r)
+	}
+
+	now = now.Add(5 * time.Second)
+	if r := l.Rate(); r != 5.5 {
+		t.Fatalf("rate after 5s = %v, want 5.5", r)
+	}
+	now = now.Add(time.Minute)
+	if r := l.Rate(); r != 10 {
+		t.Fatalf("rate not restored: %v", r)
+	}
+
+	// Retry-After pauses recovery until it has passed.
+	l.Throttle(3 * time.Second)
+	now = now.Add(2 * time.Second)
+	if r := l.Rate(); r != 5 {
+		t.Fatalf("rate recovered during pause: %v", r)
+	}
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	l.Throttle(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestClient_RateLimitPacesRequests(t *testing.T) {
+	srv, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tables":[]}`))
+	})
+	defer srv.Close()
+	cl := New(WithBaseURL(srv.URL), WithRateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := cl.ListTables(context.Background(), "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if el := time.Since(start); el < 140*time.Millisecond {
+		t.Fatalf("4 requests at 20/s with burst 1 took %v", el)
+	}
+}
+
+func TestClient_MaxInFlight(t *testing.T) {
+	var cur, peak int32
+	srv, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+		w.Write([]byte(`{"rows":[]}`))
+	})
+	defer srv.Close()
+	cl := New(WithBaseURL(srv.URL), WithMaxInFlight(2))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cl.ExecSQL(ctx, "x", SQLRequest{SQL: "SELECT 1"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if p := atomic.LoadInt32(&peak); p != 2 {
+		t.Fatalf("peak in flight = %d, want 2", p)
+	}
+
+	// A streamed response holds its slot until closed.
+	sc, err := cl.ExecSQLStream(ctx, "x", SQLRequest{SQL: "SELECT 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.inflight) != 1 {
+		t.Fatalf("in flight while streaming = %d", len(cl.inflight))
+	}
+	sc.Close()
+	if len(cl.inflight) != 0 {
+		t.Fatalf("slot not released on close")
+	}
+}
+
+func TestClient_ThrottleOn429(t *testing.T) {
+	var calls int32
+	srv, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"tables":[]}`))
+	})
+	defer srv.Close()
+	cl := New(WithBaseURL(srv.URL), WithRateLimit(100, 10), WithBackoff(time.Millisecond, time.Millisecond))
+
+	if _, err := cl.ListTables(context.Background(), "x"); err != nil {
+		t.Fatal(err)
+	}
+	if r := cl.Limiter.Rate(); r >= 100 {
+		t.Fatalf("rate not lowered after 429: %v", r)
+	}
+}
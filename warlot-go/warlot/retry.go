@@ -0,0 +1,266 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryAttempt describes a failed attempt of one logical call, passed to a
+// RetryPolicy to decide whether and when to try again.
+type RetryAttempt struct {
+	Method     string
+	URL        string
+	Attempt    int           // index of the failed attempt; 0 is the first
+	StatusCode int           // 0 when no response was received
+	Err        error         // transport error, or *APIError for a response
+	RetryAfter time.Duration // parsed Retry-After header, if any
+	PrevDelay  time.Duration // delay slept before this attempt; 0 for the first
+
+	// IdempotencyKey reports whether the request carries an
+	// x-idempotency-key header.
+	IdempotencyKey bool
+	// Idempotent reports whether repeating the request cannot apply it
+	// twice: a GET or HEAD, read-only SQL, or a request with an
+	// idempotency key.
+	Idempotent bool
+}
+
+// RetryPolicy decides, after each failed attempt, whether to retry and how
+// long to wait first. Policies are shared by concurrent calls and must be
+// safe for concurrent use.
+type RetryPolicy interface {
+	Next(a RetryAttempt) (delay time.Duration, retry bool)
+}
+
+// RequestCounter is implemented by policies that track traffic, such as
+// RetryBudget. The client calls CountRequest once per logical call.
+type RequestCounter interface {
+	CountRequest()
+}
+
+// RetryClassifier reports whether a failed attempt may be retried at all;
+// the policy then decides when.
+type RetryClassifier interface {
+	Retryable(a RetryAttempt) bool
+}
+
+// RetryClassifierFunc adapts a function to RetryClassifier.
+type RetryClassifierFunc func(a RetryAttempt) bool
+
+func (f RetryClassifierFunc) Retryable(a RetryAttempt) bool { return f(a) }
+
+// DefaultClassifier retries 429 and 5xx responses. Transport errors are
+// retried only for idempotent requests, since a write may have been
+// applied before the connection failed. Cancellation is never retried.
+var DefaultClassifier RetryClassifier = RetryClassifierFunc(func(a RetryAttempt) bool {
+	if errors.Is(a.Err, context.Canceled) || errors.Is(a.Err, context.DeadlineExceeded) {
+		return false
+	}
+	if a.StatusCode == 0 {
+		return a.Idempotent
+	}
+	return a.StatusCode == http.StatusTooManyRequests || a.StatusCode/100 == 5
+})
+
+func classify(c RetryClassifier, a RetryAttempt) bool {
+	if c == nil {
+		c = DefaultClassifier
+	}
+	return c.Retryable(a)
+}
+
+// Jitter selects how ExponentialBackoff randomizes delays.
+type Jitter int
+
+const (
+	// EqualJitter waits between half and all of the exponential delay.
+	EqualJitter Jitter = iota
+	// FullJitter waits between zero and the exponential delay.
+	FullJitter
+	// DecorrelatedJitter waits between Initial and three times the
+	// previous delay, so concurrent clients drift apart.
+	DecorrelatedJitter
+	// NoJitter waits exactly the exponential delay.
+	NoJitter
+)
+
+// ExponentialBackoff retries up to MaxRetries times, doubling the delay
+// from Initial up to Max. A Retry-After header raises the delay, still
+// capped at Max.
+type ExponentialBackoff struct {
+	Initial    time.Duration // default 200ms
+	Max        time.Duration // default 2s
+	MaxRetries int
+	Jitter     Jitter
+	Classifier RetryClassifier // default DefaultClassifier
+}
+
+func (p *ExponentialBackoff) Next(a RetryAttempt) (time.Duration, bool) {
+	if a.Attempt >= p.MaxRetries || !classify(p.Classifier, a) {
+		return 0, false
+	}
+	initial, max := normalizeBackoff(p.Initial, p.Max)
+	base := initial
+	for i := 0; i < a.Attempt && base < max; i++ {
+		base *= 2
+	}
+	if base > max {
+		base = max
+	}
+	var d time.Duration
+	switch p.Jitter {
+	case FullJitter:
+		d = time.Duration(float64(base) * randFloat64())
+	case DecorrelatedJitter:
+		prev := a.PrevDelay
+		if prev < initial {
+			prev = initial
+		}
+		d = initial + time.Duration(float64(3*prev-initial)*randFloat64())
+	case NoJitter:
+		d = base
+	default:
+		d = time.Duration(float64(base) * (0.5 + 0.5*randFloat64()))
+	}
+	if a.RetryAfter > d {
+		d = a.RetryAfter
+	}
+	if d > max {
+		d = max
+	}
+	return d, true
+}
+
+// FixedBackoff retries up to MaxRetries times with a constant delay, or
+// the Retry-After delay when that is longer.
+type FixedBackoff struct {
+	Delay      time.Duration
+	MaxRetries int
+	Classifier RetryClassifier // default DefaultClassifier
+}
+
+func (p *FixedBackoff) Next(a RetryAttempt) (time.Duration, bool) {
+	if a.Attempt >= p.MaxRetries || !classify(p.Classifier, a) {
+		return 0, false
+	}
+	if a.RetryAfter > p.Delay {
+		return a.RetryAfter, true
+	}
+	return p.Delay, true
+}
+
+// NoRetry never retries.
+var NoRetry RetryPolicy = noRetry{}
+
+type noRetry struct{}
+
+func (noRetry) Next(RetryAttempt) (time.Duration, bool) { return 0, false }
+
+// RetryBudget caps retries at a share of recent traffic, so a struggling
+// backend is not hit with a multiple of its normal load. Within the
+// budget, Policy decides. Use one budget per Client; it is safe for
+// concurrent use.
+type RetryBudget struct {
+	Policy     RetryPolicy
+	Ratio      float64       // retries allowed per request, e.g. 0.1
+	MinRetries int           // retries per Window allowed regardless of traffic
+	Window     time.Duration // default 10s
+
+	mu      sync.Mutex
+	slots   [10]budgetSlot
+	nowFunc func() time.Time
+}
+
+type budgetSlot struct {
+	index    int64
+	requests int
+	retries  int
+}
+
+// NewRetryBudget wraps p with a budget of ratio retries per request over
+// a ten second window, plus minRetries per window.
+func NewRetryBudget(p RetryPolicy, ratio float64, minRetries int) *RetryBudget {
+	return &RetryBudget{Policy: p, Ratio: ratio, MinRetries: minRetries}
+}
+
+// CountRequest records one logical call.
+func (b *RetryBudget) CountRequest() {
+	b.mu.Lock()
+	b.slot().requests++
+	b.mu.Unlock()
+	if rc, ok := b.Policy.(RequestCounter); ok {
+		rc.CountRequest()
+	}
+}
+
+func (b *RetryBudget) Next(a RetryAttempt) (time.Duration, bool) {
+	d, ok := b.Policy.Next(a)
+	if !ok {
+		return 0, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cur := b.slot()
+	var requests, retries int
+	for _, s := range b.slots {
+		if s.index > cur.index-int64(len(b.slots)) {
+			requests += s.requests
+			retries += s.retries
+		}
+	}
+	if float64(retries+1) > float64(b.MinRetries)+b.Ratio*float64(requests) {
+		return 0, false
+	}
+	cur.retries++
+	return d, true
+}
+
+// slot returns the slot for the current time, clearing it when it last
+// held an older period. The caller holds mu.
+func (b *RetryBudget) slot() *budgetSlot {
+	window := b.Window
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	now := time.Now
+	if b.nowFunc != nil {
+		now = b.nowFunc
+	}
+	idx := now().UnixNano() / int64(window/time.Duration(len(b.slots)))
+	s := &b.slots[idx%int64(len(b.slots))]
+	if s.index != idx {
+		*s = budgetSlot{index: idx}
+	}
+	return s
+}
+
+// retryPolicy returns the configured policy, or exponential backoff built
+// from MaxRetries, InitialBackoff and MaxBackoff.
+func (c *Client) retryPolicy() RetryPolicy {
+	if c.RetryPolicy != nil {
+		return c.RetryPolicy
+	}
+	return &ExponentialBackoff{
+		Initial:    c.InitialBackoff,
+		Max:        c.MaxBackoff,
+		MaxRetries: normalizeRetries(c.MaxRetries),
+	}
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
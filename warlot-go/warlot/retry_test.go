@@ -0,0 +1,169 @@
+package warlot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dropServer closes the connection on the first request, then succeeds.
+func dropServer(calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"ok":true,"row_count":1}`))
+	}
+}
+
+func TestRetry_TransportErrorOnlyWhenIdempotent(t *testing.T) {
+	ctx := context.Background()
+	req := SQLRequest{SQL: "INSERT INTO t VALUES (1)"}
+
+	var calls int32
+	srv, cl := newTestServer(dropServer(&calls))
+	defer srv.Close()
+	cl.Idempotency = IdempotencyOff
+	if _, err := cl.ExecSQL(ctx, "x", req); err == nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("unkeyed write: calls=%d err=%v", calls, err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if _, err := cl.ExecSQL(ctx, "x", req, WithIdempotencyKey("k")); err != nil || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("keyed write: calls=%d err=%v", calls, err)
+	}
+}
+
+func TestRetry_Policies(t *testing.T) {
+	var calls int32
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, `{"message":"down"}`, http.StatusServiceUnavailable)
+	})
+	defer srv.Close()
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		policy RetryPolicy
+		calls  int32
+	}{
+		{"none", NoRetry, 1},
+		{"fixed", &FixedBackoff{Delay: time.Millisecond, MaxRetries: 4}, 5},
+		{"exponential", &ExponentialBackoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, MaxRetries: 2, Jitter: FullJitter}, 3},
+		{"classifier", &FixedBackoff{MaxRetries: 4, Classifier: RetryClassifierFunc(func(a RetryAttempt) bool {
+			return a.StatusCode != http.StatusServiceUnavailable
+		})}, 1},
+	}
+	for _, tc := range cases {
+		atomic.StoreInt32(&calls, 0)
+		cl.RetryPolicy = tc.policy
+		_, err := cl.GetProjectStatus(ctx, "x")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("%s: err=%v", tc.name, err)
+		}
+		if got := atomic.LoadInt32(&calls); got != tc.calls {
+			t.Fatalf("%s: calls=%d, want %d", tc.name, got, tc.calls)
+		}
+	}
+}
+
+func TestRetry_ContextCancelledDuringBackoff(t *testing.T) {
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"busy"}`, http.StatusTooManyRequests)
+	})
+	defer srv.Close()
+	cl.RetryPolicy = &FixedBackoff{Delay: time.Minute, MaxRetries: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := cl.GetProjectStatus(ctx, "x"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestExponentialBackoff_Delays(t *testing.T) {
+	p := &ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, MaxRetries: 10, Jitter: NoJitter}
+	a := RetryAttempt{StatusCode: 503}
+	for i, want := range []time.Duration{100, 200, 400, 800, 1000} {
+		a.Attempt = i
+		if d, ok := p.Next(a); !ok || d != want*time.Millisecond {
+			t.Fatalf("attempt %d: %v %v", i, d, ok)
+		}
+	}
+
+	a.Attempt, a.RetryAfter = 0, 700*time.Millisecond
+	if d, _ := p.Next(a); d != 700*time.Millisecond {
+		t.Fatalf("retry-after: %v", d)
+	}
+	a.RetryAfter = time.Hour
+	if d, _ := p.Next(a); d != time.Second {
+		t.Fatalf("retry-after capped: %v", d)
+	}
+
+	p.Jitter = DecorrelatedJitter
+	a.RetryAfter = 0
+	for i := 0; i < 50; i++ {
+		a.PrevDelay = 200 * time.Millisecond
+		if d, _ := p.Next(a); d < 100*time.Millisecond || d > 600*time.Millisecond {
+			t.Fatalf("decorrelated out of range: %v", d)
+		}
+	}
+
+	a.Attempt = 10
+	if _, ok := p.Next(a); ok {
+		t.Fatal("retried past MaxRetries")
+	}
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	netErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+	cases := []struct {
+		a    RetryAttempt
+		want bool
+	}{
+		{RetryAttempt{StatusCode: 429}, true},
+		{RetryAttempt{StatusCode: 502}, true},
+		{RetryAttempt{StatusCode: 409}, false},
+		{RetryAttempt{Err: netErr}, false},
+		{RetryAttempt{Err: netErr, Idempotent: true}, true},
+		{RetryAttempt{Err: context.Canceled, Idempotent: true}, false},
+	}
+	for i, tc := range cases {
+		if got := DefaultClassifier.Retryable(tc.a); got != tc.want {
+			t.Fatalf("case %d: got %v", i, got)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewRetryBudget(&FixedBackoff{MaxRetries: 5}, 0.1, 1)
+	b.nowFunc = func() time.Time { return now }
+	a := RetryAttempt{StatusCode: 503}
+
+	for i := 0; i < 20; i++ {
+		b.CountRequest()
+	}
+	// 1 + 0.1*20 = 3 retries allowed in the window.
+	for i := 0; i < 3; i++ {
+		if _, ok := b.Next(a); !ok {
+			t.Fatalf("retry %d refused", i)
+		}
+	}
+	if _, ok := b.Next(a); ok {
+		t.Fatal("budget exceeded")
+	}
+
+	now = now.Add(11 * time.Second)
+	if _, ok := b.Next(a); !ok {
+		t.Fatal("budget not replenished after the window")
+	}
+}
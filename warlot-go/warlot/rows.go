@@ -0,0 +1,283 @@
+package warlot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Column describes a result column in select order. Type is the declared
+// column type when the API reports it and empty otherwise.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// UnmarshalJSON accepts either a bare column name or an object with
+// name and type fields.
+func (c *Column) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*c = Column{Name: name}
+		return nil
+	}
+	type plain Column
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*c = Column(p)
+	return nil
+}
+
+// UnmarshalJSON decodes numbers as json.Number and records column order.
+// When the response carries no columns field, columns are derived from the
+// key order of the row objects.
+func (r *SQLResponse) UnmarshalJSON(b []byte) error {
+	var w struct {
+		OK       bool            `json:"ok"`
+		RowCount *int            `json:"row_count"`
+		Rows     json.RawMessage `json:"rows"`
+		Columns  []Column        `json:"columns"`
+		Error    string          `json:"error"`
+	}
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	rs, err := decodeRowSet(w.Rows, w.Columns)
+	if err != nil {
+		return err
+	}
+	*r = SQLResponse{OK: w.OK, RowCount: w.RowCount, Error: w.Error}
+	r.Columns, r.Rows, r.values = rs.columns, rs.maps, rs.values
+	return nil
+}
+
+// Cursor returns a Rows cursor over the response rows.
+func (r *SQLResponse) Cursor() *Rows { return newRows(r.Columns, r.Rows, r.values) }
+
+// UnmarshalJSON decodes numbers as json.Number and records column order.
+func (r *BrowseRowsResponse) UnmarshalJSON(b []byte) error {
+	var w struct {
+		Limit   int             `json:"limit"`
+		Offset  int             `json:"offset"`
+		Table   string          `json:"table"`
+		Rows    json.RawMessage `json:"rows"`
+		Columns []Column        `json:"columns"`
+	}
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	rs, err := decodeRowSet(w.Rows, w.Columns)
+	if err != nil {
+		return err
+	}
+	*r = BrowseRowsResponse{Limit: w.Limit, Offset: w.Offset, Table: w.Table}
+	r.Columns, r.Rows, r.values = rs.columns, rs.maps, rs.values
+	return nil
+}
+
+// Cursor returns a Rows cursor over the page rows.
+func (r *BrowseRowsResponse) Cursor() *Rows { return newRows(r.Columns, r.Rows, r.values) }
+
+// Rows is a cursor over a decoded result set, modeled on database/sql.Rows.
+//
+//	rows := res.Cursor()
+//	for rows.Next() {
+//		var id int64
+//		var name string
+//		if err := rows.Scan(&id, &name); err != nil { ... }
+//	}
+type Rows struct {
+	columns []Column
+	maps    []map[string]any
+	values  [][]any
+	pos     int
+	err     error
+	closed  bool
+}
+
+func newRows(cols []Column, maps []map[string]any, values [][]any) *Rows {
+	if values == nil && len(maps) > 0 {
+		// Built by hand rather than decoded; align values by column name.
+		values = make([][]any, len(maps))
+		for i, m := range maps {
+			values[i] = make([]any, len(cols))
+			for j, c := range cols {
+				values[i][j] = m[c.Name]
+			}
+		}
+	}
+	return &Rows{columns: cols, maps: maps, values: values}
+}
+
+// Columns returns the result columns in select order.
+func (r *Rows) Columns() []Column { return r.columns }
+
+// ColumnNames returns the result column names in select order.
+func (r *Rows) ColumnNames() []string {
+	names := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Next advances to the next row. It returns false when rows are exhausted
+// or the cursor is closed.
+func (r *Rows) Next() bool {
+	if r.closed || r.pos >= len(r.values) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+// Scan copies the current row's columns into dest, in column order.
+// Supported destinations include pointers to string, []byte, bool, integer
+// and float kinds, json.Number, time.Time, any, pointers to those (for NULL),
+// and types implementing database/sql.Scanner. Other destinations receive
+// the value through a JSON round-trip.
+func (r *Rows) Scan(dest ...any) error {
+	if r.closed {
+		return errors.New("warlot: Scan on closed Rows")
+	}
+	if r.pos == 0 {
+		return errors.New("warlot: Scan called without calling Next")
+	}
+	if len(dest) != len(r.columns) {
+		return fmt.Errorf("warlot: expected %d destination arguments in Scan, not %d", len(r.columns), len(dest))
+	}
+	row := r.values[r.pos-1]
+	for i, d := range dest {
+		var v any
+		if i < len(row) {
+			v = row[i]
+		}
+		if err := convertAssign(d, v); err != nil {
+			err = fmt.Errorf("warlot: Scan column %d (%q): %w", i, r.columns[i].Name, err)
+			r.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Map returns the current row as a map keyed by column name.
+func (r *Rows) Map() map[string]any {
+	if r.pos == 0 || r.pos > len(r.maps) {
+		return nil
+	}
+	return r.maps[r.pos-1]
+}
+
+// Err returns the first Scan error encountered, if any.
+func (r *Rows) Err() error { return r.err }
+
+// Close marks the cursor as closed. It exists for parity with database/sql.
+func (r *Rows) Close() error {
+	r.closed = true
+	return nil
+}
+
+// rowSet is the decoded form of a rows array.
+type rowSet struct {
+	columns []Column
+	maps    []map[string]any
+	values  [][]any
+}
+
+// decodeRowSet decodes a JSON array of row objects preserving key order and
+// exact numbers. Declared columns come first; keys not covered by them are
+// appended in first-seen order.
+func decodeRowSet(raw json.RawMessage, declared []Column) (rowSet, error) {
+	rs := rowSet{columns: append([]Column(nil), declared...)}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return rs, nil
+	}
+	index := make(map[string]int, len(declared))
+	for i, c := range declared {
+		index[c.Name] = i
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := expectDelim(dec, '['); err != nil {
+		return rs, fmt.Errorf("rows: %w", err)
+	}
+	rs.maps = []map[string]any{}
+	for dec.More() {
+		keys, m, err := decodeOrderedObject(dec)
+		if err != nil {
+			return rs, fmt.Errorf("rows: %w", err)
+		}
+		rs.columns = addColumns(rs.columns, index, keys)
+		vals := make([]any, len(rs.columns))
+		for k, v := range m {
+			vals[index[k]] = v
+		}
+		rs.maps = append(rs.maps, m)
+		rs.values = append(rs.values, vals)
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return rs, fmt.Errorf("rows: %w", err)
+	}
+	return rs, nil
+}
+
+// addColumns appends the names not yet in index to cols, recording their
+// positions in index.
+func addColumns(cols []Column, index map[string]int, names []string) []Column {
+	for _, n := range names {
+		if _, ok := index[n]; !ok {
+			index[n] = len(cols)
+			cols = append(cols, Column{Name: n})
+		}
+	}
+	return cols
+}
+
+// decodeOrderedObject reads one JSON object from dec and returns its keys
+// in document order alongside the decoded map.
+func decodeOrderedObject(dec *json.Decoder) ([]string, map[string]any, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	m := map[string]any{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected object key %v", tok)
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		if _, dup := m[key]; !dup {
+			keys = append(keys, key)
+		}
+		m[key] = v
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, nil, err
+	}
+	return keys, m, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package warlot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLResponse_OrderedColumns_ExactNumbers_Scan(t *testing.T) {
+	body := `{"ok":true,"rows":[
+		{"zeta":9007199254740993,"alpha":"A","price":9.5,"created_at":"2024-05-01 10:00:00","meta":{"k":1},"note":null},
+		{"zeta":2,"alpha":"B","price":1,"created_at":"2024-05-02T10:00:00Z","meta":null,"note":"n"}
+	]}`
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	res, err := proj.SQL(ctx, `SELECT * FROM t`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range res.Columns {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "zeta,alpha,price,created_at,meta,note" {
+		t.Fatalf("columns=%v", names)
+	}
+	if n, ok := res.Rows[0]["zeta"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Fatalf("zeta=%#v", res.Rows[0]["zeta"])
+	}
+
+	rows := res.Cursor()
+	defer rows.Close()
+	type rec struct {
+		ID      int64
+		Name    string
+		Price   float64
+		Created time.Time
+		Meta    map[string]int
+		Note    sql.NullString
+	}
+	var got []rec
+	for rows.Next() {
+		var r rec
+		if err := rows.Scan(&r.ID, &r.Name, &r.Price, &r.Created, &r.Meta, &r.Note); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 9007199254740993 || got[0].Name != "A" || got[0].Meta["k"] != 1 ||
+		got[0].Note.Valid || got[1].Note.String != "n" || got[1].Created.Day() != 2 || got[0].Created.Hour() != 10 {
+		t.Fatalf("got=%+v", got)
+	}
+
+	// Typed Query keeps integer precision.
+	type T struct {
+		Zeta int64 `json:"zeta"`
+	}
+	ts, err := Query[T](ctx, proj, `SELECT * FROM t`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts[0].Zeta != 9007199254740993 {
+		t.Fatalf("zeta=%d", ts[0].Zeta)
+	}
+
+	// Scan errors: NULL into a non-pointer and argument count mismatch.
+	rows = res.Cursor()
+	rows.Next()
+	var s string
+	var a, b, c, d, e any
+	if err := rows.Scan(&a, &b, &c, &d, &e, &s); err == nil {
+		t.Fatal("expected NULL conversion error")
+	}
+	if err := rows.Scan(&a); err == nil {
+		t.Fatal("expected argument count error")
+	}
+	if a != int64(9007199254740993) {
+		t.Fatalf("any dest=%#v", a)
+	}
+}
+
+func TestSQLResponse_DeclaredColumns_Browse(t *testing.T) {
+	srv, cl := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/rows") {
+			fmt.Fprint(w, `{"limit":2,"offset":0,"table":"t","rows":[{"b":2,"a":1}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"columns":[{"name":"id","type":"INTEGER"},"name"],"rows":[{"name":"x","id":7}]}`)
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := cl.Project("p")
+
+	res, err := proj.SQL(ctx, `SELECT id, name FROM t`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Columns) != 2 || res.Columns[0] != (Column{Name: "id", Type: "INTEGER"}) || res.Columns[1].Name != "name" {
+		t.Fatalf("columns=%+v", res.Columns)
+	}
+	rows := res.Cursor()
+	var id int
+	var name string
+	if !rows.Next() || rows.Scan(&id, &name) != nil || id != 7 || name != "x" {
+		t.Fatalf("scan id=%d name=%q", id, name)
+	}
+	if rows.Next() {
+		t.Fatal("expected end of rows")
+	}
+
+	page, err := proj.Browse(ctx, "t", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := page.Cursor().ColumnNames(); strings.Join(names, ",") != "b,a" {
+		t.Fatalf("browse columns=%v", names)
+	}
+}
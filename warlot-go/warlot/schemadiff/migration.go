@@ -0,0 +1,348 @@
+package schemadiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Migration renders d as an up script and the down script that reverts
+// it, suitable for a NNN_name.up.sql / NNN_name.down.sql pair. Neither
+// script contains transaction statements, since Migrator runs each file
+// in its own batch. Reverting restores structure only: rows of dropped
+// tables and values of dropped columns are not brought back.
+func (d *Diff) Migration() (up, down string) {
+	return d.SQL(), d.Reverse().SQL()
+}
+
+// SQL renders the statements that apply d, each terminated by a
+// semicolon. New tables are created with their indexes and triggers
+// first, then changed tables are altered, then removed tables are
+// dropped.
+//
+// Column additions and removals use ALTER TABLE where SQLite allows it.
+// Any other change, including a changed constraint, rebuilds the table: the new definition is created
+// under a temporary name, shared columns are copied, the new table
+// replaces the old one, and its indexes and triggers are created again.
+// A script that rebuilds or drops tables opens with
+// PRAGMA foreign_keys = OFF, which Migrator sends outside the batch's
+// transaction, and ends by failing if any foreign key is left dangling.
+// It also sets legacy_alter_table while it runs, so renaming a rebuilt
+// table does not trip over triggers elsewhere that name it.
+func (d *Diff) SQL() string {
+	var stmts []string
+	for _, t := range d.Added {
+		stmts = append(stmts, createTable(t, t.Name))
+		stmts = append(stmts, objects(explicitIndexes(t), t.Triggers)...)
+	}
+	rebuilt := false
+	for _, td := range d.Changed {
+		if len(td.Changed)+len(td.AddedConstraints)+len(td.RemovedConstraints) > 0 || !canAlter(td) {
+			stmts = append(stmts, rebuild(td)...)
+			rebuilt = true
+		} else {
+			stmts = append(stmts, alterTable(td)...)
+		}
+	}
+	for _, t := range d.Removed {
+		stmts = append(stmts, "DROP TABLE "+quoteIdent(t.Name))
+	}
+	if len(stmts) == 0 {
+		return ""
+	}
+	if rebuilt || len(d.Removed) > 0 {
+		stmts = append([]string{"PRAGMA foreign_keys = OFF", "PRAGMA legacy_alter_table = ON"}, stmts...)
+		stmts = append(stmts, "PRAGMA legacy_alter_table = OFF")
+		stmts = append(stmts, foreignKeyCheck...)
+	}
+	return strings.Join(stmts, ";\n") + ";\n"
+}
+
+// foreignKeyCheck fails the script when PRAGMA foreign_key_check reports
+// a violation: the CHECK constraint rejects a non-zero count.
+var foreignKeyCheck = []string{
+	`CREATE TEMP TABLE "_schemadiff_fk_check" ("violations" INTEGER CHECK ("violations" = 0))`,
+	`INSERT INTO "_schemadiff_fk_check" SELECT count(*) FROM pragma_foreign_key_check`,
+	`DROP TABLE "_schemadiff_fk_check"`,
+}
+
+// alterTable applies td in place. Indexes and triggers that go away are
+// dropped before the columns they may name.
+func alterTable(td TableDiff) []string {
+	table := quoteIdent(td.From.Name)
+	var out []string
+	for _, ix := range td.RemovedIndexes {
+		out = append(out, "DROP INDEX "+quoteIdent(ix.Name))
+	}
+	for _, tr := range td.RemovedTriggers {
+		out = append(out, "DROP TRIGGER "+quoteIdent(tr.Name))
+	}
+	for _, c := range td.Added {
+		out = append(out, "ALTER TABLE "+table+" ADD COLUMN "+columnDef(c, false))
+	}
+	for _, c := range td.Removed {
+		out = append(out, "ALTER TABLE "+table+" DROP COLUMN "+quoteIdent(c.Name))
+	}
+	return append(out, objects(td.AddedIndexes, td.AddedTriggers)...)
+}
+
+// canAlter reports whether ALTER TABLE can apply the column changes of
+// td. SQLite cannot add a key or UNIQUE column, a NOT NULL column without
+// a default, or a column whose default is not constant. It cannot drop a
+// key, UNIQUE or indexed column or one a foreign key or CHECK constraint
+// uses, and dropping a column a trigger names breaks the trigger.
+func canAlter(td TableDiff) bool {
+	to := parseTable(td.To)
+	for _, c := range td.Added {
+		if c.PK > 0 || (c.NotNull && defaultText(c.Default) == "") || !constantDefault(defaultText(c.Default)) {
+			return false
+		}
+		if (to != nil && unique(to, c.Name)) || constraintIndexed(td.To, c.Name) {
+			return false
+		}
+	}
+	if len(td.Removed) == 0 {
+		return true
+	}
+	from := parseTable(td.From)
+	if from == nil || len(from.Checks) > 0 || len(td.Removed) >= len(td.From.Columns) {
+		return false
+	}
+	for _, cd := range from.Columns {
+		if cd.Check != nil {
+			return false
+		}
+	}
+	kept := &warlot.TableSchema{}
+	for _, ix := range td.From.Indexes {
+		if findIndex(td.RemovedIndexes, ix.Name) < 0 {
+			kept.Indexes = append(kept.Indexes, ix)
+		}
+	}
+	for _, c := range td.Removed {
+		if c.PK > 0 || unique(from, c.Name) || indexed(kept, c.Name) {
+			return false
+		}
+		for _, fk := range td.From.ForeignKeys {
+			if containsFold(fk.Columns, c.Name) {
+				return false
+			}
+		}
+		for _, tr := range td.From.Triggers {
+			if !hasTrigger(td.RemovedTriggers, tr.Name) && mentions(tr.SQL, c.Name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseTable parses the CREATE TABLE statement of t, or returns nil when
+// there is none to inspect.
+func parseTable(t *warlot.TableSchema) *sqlparse.CreateTable {
+	st, err := sqlparse.Parse(t.SQL)
+	if err != nil {
+		return nil
+	}
+	ct, _ := st.(*sqlparse.CreateTable)
+	return ct
+}
+
+// unique reports whether a UNIQUE or PRIMARY KEY constraint covers col.
+func unique(ct *sqlparse.CreateTable, col string) bool {
+	for _, cd := range ct.Columns {
+		if strings.EqualFold(cd.Name, col) && (cd.Unique || cd.PrimaryKey) {
+			return true
+		}
+	}
+	if containsFold(ct.PrimaryKey, col) {
+		return true
+	}
+	for _, u := range ct.Unique {
+		if containsFold(u, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexed reports whether any index of t covers col or names it in its
+// statement, as expression and partial indexes may.
+func indexed(t *warlot.TableSchema, col string) bool {
+	for _, ix := range t.Indexes {
+		if containsFold(ix.Columns, col) || mentions(ix.SQL, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// constraintIndexed reports whether a UNIQUE or PRIMARY KEY index of t
+// covers col.
+func constraintIndexed(t *warlot.TableSchema, col string) bool {
+	for _, ix := range t.Indexes {
+		if ix.Origin != "c" && containsFold(ix.Columns, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether sql contains col as an identifier.
+func mentions(sql, col string) bool {
+	toks, err := sqlparse.Tokenize(sql)
+	if err != nil {
+		return strings.Contains(strings.ToLower(sql), strings.ToLower(col))
+	}
+	for _, t := range toks {
+		if (t.Kind == sqlparse.TokIdent || t.Kind == sqlparse.TokQuotedIdent) && strings.EqualFold(t.Text, col) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// constantDefault reports whether def is a default ALTER TABLE ADD COLUMN
+// accepts: none, a literal, or NULL, TRUE or FALSE.
+func constantDefault(def string) bool {
+	if def == "" || strings.HasPrefix(def, "'") {
+		return true
+	}
+	if _, err := strconv.ParseFloat(strings.TrimLeft(def, "+-"), 64); err == nil {
+		return true
+	}
+	switch strings.ToUpper(def) {
+	case "NULL", "TRUE", "FALSE":
+		return true
+	}
+	return false
+}
+
+// rebuild replaces the table with td.To's definition, keeping the values
+// of shared columns, and recreates td.To's indexes and triggers, which
+// SQLite dropped with the old table.
+func rebuild(td TableDiff) []string {
+	tmp := td.To.Name + "__new"
+	var shared []string
+	for _, c := range td.To.Columns {
+		if _, ok := td.From.Column(c.Name); ok {
+			shared = append(shared, quoteIdent(c.Name))
+		}
+	}
+	stmts := []string{createTable(td.To, tmp)}
+	if len(shared) > 0 {
+		cols := strings.Join(shared, ", ")
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quoteIdent(tmp), cols, cols, quoteIdent(td.From.Name)))
+	}
+	stmts = append(stmts,
+		"DROP TABLE "+quoteIdent(td.From.Name),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(tmp), quoteIdent(td.To.Name)),
+	)
+	return append(stmts, objects(explicitIndexes(td.To), td.To.Triggers)...)
+}
+
+// objects renders the statements that create indexes and triggers.
+func objects(indexes []warlot.IndexSchema, triggers []warlot.Trigger) []string {
+	var out []string
+	for _, ix := range indexes {
+		out = append(out, trimStatement(ix.SQL))
+	}
+	for _, tr := range triggers {
+		out = append(out, trimStatement(tr.SQL))
+	}
+	return out
+}
+
+func trimStatement(sql string) string {
+	return strings.TrimRight(strings.TrimSpace(sql), ";")
+}
+
+// createTable renders CREATE TABLE for t under name. The original
+// statement is reused when the schema carries one, which keeps
+// constraints the column list cannot express.
+func createTable(t *warlot.TableSchema, name string) string {
+	ddl := trimStatement(t.SQL)
+	if i := strings.Index(ddl, "("); i >= 0 && strings.HasPrefix(strings.ToUpper(ddl), "CREATE TABLE") {
+		return "CREATE TABLE " + quoteIdent(name) + " " + ddl[i:]
+	}
+	pk := t.PrimaryKey()
+	var defs []string
+	for _, c := range t.Columns {
+		defs = append(defs, columnDef(c, len(pk) == 1 && c.PK == 1))
+	}
+	if len(pk) > 1 {
+		defs = append(defs, "PRIMARY KEY ("+quoteList(pk)+")")
+	}
+	for _, fk := range t.ForeignKeys {
+		def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", quoteList(fk.Columns), quoteIdent(fk.RefTable))
+		if len(fk.RefColumns) > 0 {
+			def += " (" + quoteList(fk.RefColumns) + ")"
+		}
+		if fk.OnUpdate != "" && !strings.EqualFold(fk.OnUpdate, "NO ACTION") {
+			def += " ON UPDATE " + fk.OnUpdate
+		}
+		if fk.OnDelete != "" && !strings.EqualFold(fk.OnDelete, "NO ACTION") {
+			def += " ON DELETE " + fk.OnDelete
+		}
+		defs = append(defs, def)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", quoteIdent(name), strings.Join(defs, ",\n  "))
+}
+
+func columnDef(c warlot.ColumnSchema, primaryKey bool) string {
+	s := quoteIdent(c.Name)
+	if c.Type != "" {
+		s += " " + c.Type
+	}
+	if primaryKey {
+		s += " PRIMARY KEY"
+	}
+	if c.NotNull {
+		s += " NOT NULL"
+	}
+	if def := defaultText(c.Default); def != "" {
+		s += " DEFAULT " + defaultExpr(def)
+	}
+	return s
+}
+
+// defaultExpr parenthesizes a default unless it is a literal, a keyword
+// such as CURRENT_TIMESTAMP, or already parenthesized.
+func defaultExpr(s string) string {
+	if strings.HasPrefix(s, "(") || strings.HasPrefix(s, "'") {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return "(" + s + ")"
+		}
+	}
+	return s
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteList(names []string) string {
+	q := make([]string, len(names))
+	for i, n := range names {
+		q[i] = quoteIdent(n)
+	}
+	return strings.Join(q, ", ")
+}
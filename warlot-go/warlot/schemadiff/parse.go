@@ -0,0 +1,460 @@
+package schemadiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// ddlTable is a table as a DDL script has defined it so far.
+type ddlTable struct {
+	ct       *sqlparse.CreateTable
+	sql      string
+	indexes  []warlot.IndexSchema
+	triggers []warlot.Trigger
+}
+
+// ParseSQL builds a schema from a DDL script. Statements apply in order,
+// so ALTER and DROP statements later in the script are reflected. Data
+// statements, views and temporary objects are skipped.
+func ParseSQL(src string) (Schema, error) {
+	stmts, err := sqlparse.Split(src)
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string]*ddlTable{}
+	for _, stmt := range stmts {
+		if err := applyDDL(tables, stmt); err != nil {
+			return nil, err
+		}
+	}
+	s := Schema{}
+	for _, t := range tables {
+		s.add(t.schema())
+	}
+	return s, nil
+}
+
+func applyDDL(tables map[string]*ddlTable, stmt string) error {
+	toks, err := sqlparse.Tokenize(stmt)
+	if err != nil {
+		return err
+	}
+	words := leadingWords(toks, 4)
+	switch {
+	case len(words) < 2:
+		return nil
+	case words[0] == "CREATE" && (words[1] == "TEMP" || words[1] == "TEMPORARY" || words[1] == "VIEW" || words[1] == "VIRTUAL"):
+		return nil
+	case words[0] == "CREATE" && words[1] == "TRIGGER":
+		name, table := triggerTarget(toks)
+		t := tables[strings.ToLower(table)]
+		if t == nil {
+			return fmt.Errorf("trigger %s: no such table: %s", name, table)
+		}
+		t.triggers = append(t.triggers, warlot.Trigger{Name: name, SQL: stmt})
+		return nil
+	case words[0] == "DROP" && words[1] == "TRIGGER":
+		name := objectName(toks[2:])
+		for _, t := range tables {
+			for i, tr := range t.triggers {
+				if strings.EqualFold(tr.Name, name) {
+					t.triggers = append(t.triggers[:i], t.triggers[i+1:]...)
+					return nil
+				}
+			}
+		}
+		return nil
+	case words[0] == "DROP" && words[1] == "VIEW":
+		return nil
+	case words[0] != "CREATE" && words[0] != "ALTER" && words[0] != "DROP":
+		return nil
+	}
+
+	st, err := sqlparse.Parse(stmt)
+	if err != nil {
+		return err
+	}
+	switch x := st.(type) {
+	case *sqlparse.CreateTable:
+		key := strings.ToLower(x.Name)
+		if tables[key] != nil {
+			if x.IfNotExists {
+				return nil
+			}
+			return fmt.Errorf("table %s already exists", x.Name)
+		}
+		tables[key] = &ddlTable{ct: x, sql: x.Raw}
+	case *sqlparse.CreateIndex:
+		t := tables[strings.ToLower(x.Table)]
+		if t == nil {
+			return fmt.Errorf("index %s: no such table: %s", x.Name, x.Table)
+		}
+		t.indexes = append(t.indexes, indexFromDDL(x))
+	case *sqlparse.DropIndex:
+		for _, t := range tables {
+			for i, ix := range t.indexes {
+				if strings.EqualFold(ix.Name, x.Name) {
+					t.indexes = append(t.indexes[:i], t.indexes[i+1:]...)
+					return nil
+				}
+			}
+		}
+		if !x.IfExists {
+			return fmt.Errorf("no such index: %s", x.Name)
+		}
+	case *sqlparse.DropTable:
+		key := strings.ToLower(x.Name)
+		if tables[key] == nil && !x.IfExists {
+			return fmt.Errorf("no such table: %s", x.Name)
+		}
+		delete(tables, key)
+	case *sqlparse.AlterTable:
+		t := tables[strings.ToLower(x.Name)]
+		if t == nil {
+			return fmt.Errorf("no such table: %s", x.Name)
+		}
+		return t.alter(tables, x)
+	}
+	return nil
+}
+
+// alter applies ALTER TABLE to t, editing the stored CREATE TABLE text the
+// way SQLite does.
+func (t *ddlTable) alter(tables map[string]*ddlTable, at *sqlparse.AlterTable) error {
+	switch {
+	case at.AddColumn != nil:
+		if t.column(at.AddColumn.Name) >= 0 {
+			return fmt.Errorf("duplicate column name: %s", at.AddColumn.Name)
+		}
+		t.ct.Columns = append(t.ct.Columns, *at.AddColumn)
+		if i := strings.LastIndex(t.sql, ")"); i >= 0 {
+			t.sql = t.sql[:i] + ", " + at.AddColumn.Raw + t.sql[i:]
+		}
+	case at.DropColumn != "":
+		i := t.column(at.DropColumn)
+		if i < 0 {
+			return fmt.Errorf("no such column: %s", at.DropColumn)
+		}
+		t.sql = cutDefinition(t.sql, t.ct.Columns[i].Raw)
+		t.ct.Columns = append(t.ct.Columns[:i], t.ct.Columns[i+1:]...)
+	case at.RenameTo != "":
+		delete(tables, strings.ToLower(t.ct.Name))
+		t.ct.Name = at.RenameTo
+		tables[strings.ToLower(at.RenameTo)] = t
+	default:
+		i := t.column(at.RenameColumn[0])
+		if i < 0 {
+			return fmt.Errorf("no such column: %s", at.RenameColumn[0])
+		}
+		cd := &t.ct.Columns[i]
+		toks, err := sqlparse.Tokenize(cd.Raw)
+		if err != nil {
+			return err
+		}
+		raw := quoteIdent(at.RenameColumn[1]) + cd.Raw[toks[0].End:]
+		t.sql = strings.Replace(t.sql, cd.Raw, raw, 1)
+		cd.Name, cd.Raw = at.RenameColumn[1], raw
+	}
+	return nil
+}
+
+func (t *ddlTable) column(name string) int {
+	for i, c := range t.ct.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// schema converts t to the form Load returns, with PRAGMA table_info
+// conventions for defaults and key positions.
+func (t *ddlTable) schema() *warlot.TableSchema {
+	ct := t.ct
+	ts := &warlot.TableSchema{Name: ct.Name, SQL: t.sql, Indexes: t.indexes, Triggers: t.triggers}
+	pk := map[string]int{}
+	for i, c := range ct.PrimaryKey {
+		pk[strings.ToLower(c)] = i + 1
+	}
+	for i, cd := range ct.Columns {
+		c := warlot.ColumnSchema{CID: i, Name: cd.Name, Type: cd.Type, NotNull: cd.NotNull, PK: pk[strings.ToLower(cd.Name)]}
+		if cd.PrimaryKey {
+			c.PK = 1
+		}
+		if def := strings.TrimSpace(cd.DefaultText); def != "" {
+			if strings.HasPrefix(def, "(") && strings.HasSuffix(def, ")") {
+				def = strings.TrimSpace(def[1 : len(def)-1])
+			}
+			c.Default = def
+		}
+		ts.Columns = append(ts.Columns, c)
+		if fk := cd.References; fk != nil {
+			ts.ForeignKeys = append(ts.ForeignKeys, warlot.ForeignKey{
+				ID: len(ts.ForeignKeys), Columns: []string{cd.Name}, RefTable: fk.Table, RefColumns: fk.RefColumns,
+			})
+		}
+	}
+	for _, fk := range ct.ForeignKeys {
+		ts.ForeignKeys = append(ts.ForeignKeys, warlot.ForeignKey{
+			ID: len(ts.ForeignKeys), Columns: fk.Columns, RefTable: fk.Table, RefColumns: fk.RefColumns,
+		})
+	}
+	return ts
+}
+
+// indexFromDDL describes a CREATE INDEX statement.
+func indexFromDDL(ci *sqlparse.CreateIndex) warlot.IndexSchema {
+	return warlot.IndexSchema{
+		Name: ci.Name, Unique: ci.Unique, Origin: "c", Partial: ci.Where != nil,
+		Columns: ci.Columns, SQL: ci.Raw,
+	}
+}
+
+// parseIndex describes an index from its CREATE INDEX text. Statements the
+// parser does not understand, such as expression indexes, keep only their
+// name and text.
+func parseIndex(name, sql string) warlot.IndexSchema {
+	if st, err := sqlparse.Parse(sql); err == nil {
+		if ci, ok := st.(*sqlparse.CreateIndex); ok {
+			return indexFromDDL(ci)
+		}
+	}
+	return warlot.IndexSchema{Name: name, Origin: "c", SQL: sql}
+}
+
+// leadingWords returns up to n leading identifier tokens, upper-cased,
+// skipping IF NOT EXISTS and UNIQUE.
+func leadingWords(toks []sqlparse.Token, n int) []string {
+	var out []string
+	for _, t := range toks {
+		if t.Kind != sqlparse.TokIdent || len(out) == n {
+			break
+		}
+		w := strings.ToUpper(t.Text)
+		if w != "UNIQUE" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// triggerTarget returns the name and table of a CREATE TRIGGER statement.
+func triggerTarget(toks []sqlparse.Token) (name, table string) {
+	i := 2 // after CREATE TRIGGER
+	if i+2 < len(toks) && strings.EqualFold(toks[i].Text, "IF") {
+		i += 3
+	}
+	name = objectName(toks[i:])
+	for j := i; j+1 < len(toks); j++ {
+		if toks[j].Kind == sqlparse.TokIdent && strings.EqualFold(toks[j].Text, "ON") {
+			table = objectName(toks[j+1:])
+			break
+		}
+	}
+	return name, table
+}
+
+// objectName reads a possibly schema-qualified name, skipping IF EXISTS,
+// and returns its last part.
+func objectName(toks []sqlparse.Token) string {
+	if len(toks) > 2 && toks[0].Kind == sqlparse.TokIdent && strings.EqualFold(toks[0].Text, "IF") {
+		toks = toks[2:]
+	}
+	if len(toks) == 0 {
+		return ""
+	}
+	name := toks[0].Text
+	if len(toks) > 2 && toks[1].Text == "." {
+		name = toks[2].Text
+	}
+	return name
+}
+
+// cutDefinition removes def and the comma that separates it from its
+// neighbour from a CREATE TABLE statement.
+func cutDefinition(sql, def string) string {
+	i := strings.Index(sql, def)
+	if i < 0 {
+		return sql
+	}
+	before := strings.TrimRight(sql[:i], " \t\r\n")
+	after := sql[i+len(def):]
+	if strings.HasSuffix(before, ",") {
+		return strings.TrimRight(before[:len(before)-1], " \t\r\n") + after
+	}
+	trimmed := strings.TrimLeft(after, " \t\r\n")
+	if strings.HasPrefix(trimmed, ",") {
+		return sql[:i] + strings.TrimLeft(trimmed[1:], " \t\r\n")
+	}
+	return sql[:i] + after
+}
+
+// sqlKey normalizes a CREATE statement for comparison: keywords and
+// identifiers compare case-insensitively, quoting and layout are
+// ignored, and IF NOT EXISTS is dropped as SQLite does when storing it.
+func sqlKey(sql string) string {
+	toks, err := sqlparse.Tokenize(sql)
+	if err != nil {
+		return strings.Join(strings.Fields(sql), " ")
+	}
+	return tokensKey(toks)
+}
+
+// tokensKey is sqlKey for a statement already tokenized.
+func tokensKey(toks []sqlparse.Token) string {
+	var parts []string
+	for i := 0; i < len(toks) && toks[i].Kind != sqlparse.TokEOF; i++ {
+		t := toks[i]
+		if t.Kind == sqlparse.TokIdent && strings.EqualFold(t.Text, "IF") && i+2 < len(toks) &&
+			strings.EqualFold(toks[i+1].Text, "NOT") && strings.EqualFold(toks[i+2].Text, "EXISTS") {
+			i += 2
+			continue
+		}
+		switch t.Kind {
+		case sqlparse.TokIdent, sqlparse.TokQuotedIdent:
+			parts = append(parts, strings.ToUpper(t.Text))
+		case sqlparse.TokString:
+			parts = append(parts, "'"+t.Text+"'")
+		default:
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// constraint is a CHECK, UNIQUE or foreign key clause of a CREATE TABLE
+// statement in sqlKey form. Column is the column that declares it, or ""
+// for a table constraint. Column constraints are keyed in table form, so
+// "a INT UNIQUE" and "UNIQUE (a)" compare equal.
+type constraint struct {
+	Column string
+	Key    string
+}
+
+// tableConstraints extracts the constraints of a CREATE TABLE statement.
+// Primary keys are left out; column PK positions already cover them. ok
+// is false when sql is not a CREATE TABLE statement with a column list.
+func tableConstraints(sql string) (out []constraint, ok bool) {
+	toks, err := sqlparse.Tokenize(sql)
+	if err != nil {
+		return nil, false
+	}
+	if w := leadingWords(toks, 2); len(w) < 2 || w[0] != "CREATE" || w[1] != "TABLE" {
+		return nil, false
+	}
+	start := -1
+	for i, t := range toks {
+		if isOp(t, "(") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+	depth, item := 0, start+1
+	for i := start; i < len(toks); i++ {
+		switch {
+		case isOp(toks[i], "("):
+			depth++
+		case isOp(toks[i], ")"):
+			depth--
+			if depth == 0 {
+				return append(out, itemConstraints(toks[item:i])...), true
+			}
+		case depth == 1 && isOp(toks[i], ","):
+			out = append(out, itemConstraints(toks[item:i])...)
+			item = i + 1
+		}
+	}
+	return nil, false
+}
+
+// itemConstraints returns the constraints of one column definition or
+// table constraint.
+func itemConstraints(toks []sqlparse.Token) []constraint {
+	if len(toks) == 0 {
+		return nil
+	}
+	if keyword(toks[0]) == "CONSTRAINT" && len(toks) > 2 {
+		toks = toks[2:]
+	}
+	switch keyword(toks[0]) {
+	case "CHECK", "UNIQUE", "FOREIGN":
+		return []constraint{{Key: tokensKey(toks)}}
+	case "PRIMARY":
+		return nil
+	}
+	col := toks[0].Text
+	var out []constraint
+	for i := 1; i < len(toks); {
+		switch keyword(toks[i]) {
+		case "CHECK":
+			end := groupEnd(toks, i+1)
+			out = append(out, constraint{Column: col, Key: tokensKey(toks[i:end])})
+			i = end
+		case "UNIQUE":
+			out = append(out, constraint{Column: col, Key: "UNIQUE ( " + strings.ToUpper(col) + " )"})
+			i++
+		case "REFERENCES":
+			end := i + 1
+			for end < len(toks) && !columnClause(toks, end) {
+				end = groupEnd(toks, end)
+			}
+			out = append(out, constraint{Column: col, Key: "FOREIGN KEY ( " + strings.ToUpper(col) + " ) " + tokensKey(toks[i:end])})
+			i = end
+		default:
+			i = groupEnd(toks, i)
+		}
+	}
+	return out
+}
+
+// columnClause reports whether toks[i] starts another clause of a column
+// definition, which ends a REFERENCES clause. NOT DEFERRABLE belongs to
+// the REFERENCES clause itself.
+func columnClause(toks []sqlparse.Token, i int) bool {
+	switch keyword(toks[i]) {
+	case "CONSTRAINT", "CHECK", "UNIQUE", "NULL", "DEFAULT", "COLLATE", "PRIMARY", "GENERATED", "AS", "REFERENCES":
+		return true
+	case "NOT":
+		return i+1 >= len(toks) || keyword(toks[i+1]) != "DEFERRABLE"
+	}
+	return false
+}
+
+// groupEnd returns the index after toks[i], or after the parenthesized
+// group toks[i] opens.
+func groupEnd(toks []sqlparse.Token, i int) int {
+	if i >= len(toks) || !isOp(toks[i], "(") {
+		return i + 1
+	}
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch {
+		case isOp(toks[i], "("):
+			depth++
+		case isOp(toks[i], ")"):
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// keyword returns t upper-cased if it is an unquoted identifier, or "".
+func keyword(t sqlparse.Token) string {
+	if t.Kind != sqlparse.TokIdent {
+		return ""
+	}
+	return strings.ToUpper(t.Text)
+}
+
+func isOp(t sqlparse.Token, op string) bool {
+	return t.Kind == sqlparse.TokOp && t.Text == op
+}
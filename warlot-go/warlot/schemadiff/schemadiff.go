@@ -0,0 +1,471 @@
+// Package schemadiff compares table schemas and generates the SQL that
+// migrates one to the other.
+//
+// Schemas are loaded from a project (Load) or from a DDL script (ParseSQL,
+// LoadFile). Compare reports added, removed and changed tables, columns
+// and table constraints, and CREATE INDEX and CREATE TRIGGER statements;
+// Diff.Migration
+// renders up and down scripts that Migrator.Up accepts. Views are not
+// compared.
+//
+//	prod, _ := schemadiff.Load(ctx, prodProject)
+//	staging, _ := schemadiff.Load(ctx, stagingProject)
+//	d := schemadiff.Compare(prod, staging)
+//	if !d.Empty() {
+//		fmt.Print(d)
+//		up, down := d.Migration()
+//		...
+//	}
+package schemadiff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Schema is a set of tables keyed by lower-case table name.
+type Schema map[string]*warlot.TableSchema
+
+// Tables returns the tables sorted by name.
+func (s Schema) Tables() []*warlot.TableSchema {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*warlot.TableSchema, len(keys))
+	for i, k := range keys {
+		out[i] = s[k]
+	}
+	return out
+}
+
+func (s Schema) add(t *warlot.TableSchema) {
+	if !internalTable(t.Name) {
+		s[strings.ToLower(t.Name)] = t
+	}
+}
+
+// Load reads the schema of every user table in p, with the CREATE INDEX
+// and CREATE TRIGGER statements recorded in sqlite_master. SQLite's own
+// tables and the migration ledger are skipped.
+func Load(ctx context.Context, p warlot.Project, opts ...warlot.CallOption) (Schema, error) {
+	lt, err := p.Tables(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := Schema{}
+	for _, name := range lt.Tables {
+		if internalTable(name) {
+			continue
+		}
+		t, err := p.Schema(ctx, name, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		if t.Name == "" {
+			t.Name = name
+		}
+		s.add(t)
+	}
+	if err := loadObjects(ctx, p, s, opts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadObjects attaches the explicit indexes and triggers in sqlite_master
+// to their tables. Constraint indexes have no SQL and are left as the
+// schema endpoint reported them.
+func loadObjects(ctx context.Context, p warlot.Project, s Schema, opts []warlot.CallOption) error {
+	type object struct {
+		Type  string `json:"type"`
+		Name  string `json:"name"`
+		Table string `json:"tbl_name"`
+		SQL   string `json:"sql"`
+	}
+	objs, err := warlot.Query[object](ctx, p, `SELECT type, name, tbl_name, sql FROM sqlite_master
+WHERE type IN ('index', 'trigger') AND sql IS NOT NULL ORDER BY name`, nil, opts...)
+	if err != nil {
+		return fmt.Errorf("load indexes and triggers: %w", err)
+	}
+	for _, o := range objs {
+		t := s[strings.ToLower(o.Table)]
+		if t == nil {
+			continue
+		}
+		if o.Type == "trigger" {
+			if !hasTrigger(t.Triggers, o.Name) {
+				t.Triggers = append(t.Triggers, warlot.Trigger{Name: o.Name, SQL: o.SQL})
+			}
+			continue
+		}
+		ix := parseIndex(o.Name, o.SQL)
+		if i := findIndex(t.Indexes, o.Name); i >= 0 {
+			t.Indexes[i].SQL = o.SQL
+		} else {
+			t.Indexes = append(t.Indexes, ix)
+		}
+	}
+	return nil
+}
+
+// LoadFile reads a DDL script with ParseSQL.
+func LoadFile(path string) (Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSQL(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Diff describes the changes that turn one schema into another.
+type Diff struct {
+	Added   []*warlot.TableSchema `json:"added"`
+	Removed []*warlot.TableSchema `json:"removed"`
+	Changed []TableDiff           `json:"changed"`
+}
+
+// TableDiff describes the column, constraint, index and trigger changes
+// of a table present in both schemas. An index, trigger or constraint
+// whose text changed is listed as removed and added. Constraints are the
+// CHECK, UNIQUE and foreign key clauses of the CREATE TABLE statement in
+// normalized form; those declared by an added or removed column are not
+// listed separately.
+type TableDiff struct {
+	Name    string                `json:"name"`
+	From    *warlot.TableSchema   `json:"-"`
+	To      *warlot.TableSchema   `json:"-"`
+	Added   []warlot.ColumnSchema `json:"added_columns"`
+	Removed []warlot.ColumnSchema `json:"removed_columns"`
+	Changed []ColumnDiff          `json:"changed_columns"`
+
+	AddedConstraints   []string             `json:"added_constraints,omitempty"`
+	RemovedConstraints []string             `json:"removed_constraints,omitempty"`
+	AddedIndexes       []warlot.IndexSchema `json:"added_indexes,omitempty"`
+	RemovedIndexes     []warlot.IndexSchema `json:"removed_indexes,omitempty"`
+	AddedTriggers      []warlot.Trigger     `json:"added_triggers,omitempty"`
+	RemovedTriggers    []warlot.Trigger     `json:"removed_triggers,omitempty"`
+}
+
+// ColumnDiff describes a column whose definition changed. Changes lists
+// the differences in readable form, such as "type INT -> TEXT".
+type ColumnDiff struct {
+	Name    string              `json:"name"`
+	From    warlot.ColumnSchema `json:"from"`
+	To      warlot.ColumnSchema `json:"to"`
+	Changes []string            `json:"changes"`
+}
+
+// Compare reports the changes that turn from into to. Table and column
+// names match case-insensitively, and declared types compare
+// case-insensitively with whitespace collapsed. Only indexes created with
+// CREATE INDEX are compared; their statements, like those of triggers and
+// table constraints, compare ignoring case, quoting, layout and IF NOT
+// EXISTS. When either table has no CREATE TABLE text, only their foreign
+// keys' columns and referenced tables are compared.
+func Compare(from, to Schema) *Diff {
+	d := &Diff{}
+	for _, t := range to.Tables() {
+		if from[strings.ToLower(t.Name)] == nil {
+			d.Added = append(d.Added, t)
+		}
+	}
+	for _, t := range from.Tables() {
+		other := to[strings.ToLower(t.Name)]
+		if other == nil {
+			d.Removed = append(d.Removed, t)
+			continue
+		}
+		if td := compareTable(t, other); td != nil {
+			d.Changed = append(d.Changed, *td)
+		}
+	}
+	return d
+}
+
+func compareTable(from, to *warlot.TableSchema) *TableDiff {
+	td := &TableDiff{Name: to.Name, From: from, To: to}
+	for _, c := range to.Columns {
+		if _, ok := from.Column(c.Name); !ok {
+			td.Added = append(td.Added, c)
+		}
+	}
+	for _, c := range from.Columns {
+		other, ok := to.Column(c.Name)
+		if !ok {
+			td.Removed = append(td.Removed, c)
+			continue
+		}
+		if changes := compareColumn(c, other); len(changes) > 0 {
+			td.Changed = append(td.Changed, ColumnDiff{Name: other.Name, From: c, To: other, Changes: changes})
+		}
+	}
+	td.AddedConstraints, td.RemovedConstraints = compareConstraints(td)
+	for _, ix := range explicitIndexes(to) {
+		if !sameIndex(from, ix) {
+			td.AddedIndexes = append(td.AddedIndexes, ix)
+		}
+	}
+	for _, ix := range explicitIndexes(from) {
+		if !sameIndex(to, ix) {
+			td.RemovedIndexes = append(td.RemovedIndexes, ix)
+		}
+	}
+	for _, tr := range to.Triggers {
+		if !sameTrigger(from, tr) {
+			td.AddedTriggers = append(td.AddedTriggers, tr)
+		}
+	}
+	for _, tr := range from.Triggers {
+		if !sameTrigger(to, tr) {
+			td.RemovedTriggers = append(td.RemovedTriggers, tr)
+		}
+	}
+	if td.empty() {
+		return nil
+	}
+	return td
+}
+
+func (td *TableDiff) empty() bool {
+	return len(td.Added)+len(td.Removed)+len(td.Changed)+len(td.AddedConstraints)+len(td.RemovedConstraints)+
+		len(td.AddedIndexes)+len(td.RemovedIndexes)+len(td.AddedTriggers)+len(td.RemovedTriggers) == 0
+}
+
+// compareConstraints returns the constraints of td.To missing from
+// td.From and those of td.From missing from td.To, leaving out the ones
+// declared by added or removed columns.
+func compareConstraints(td *TableDiff) (added, removed []string) {
+	from, okFrom := tableConstraints(td.From.SQL)
+	to, okTo := tableConstraints(td.To.SQL)
+	if !okFrom || !okTo {
+		from, to = foreignKeyConstraints(td.From), foreignKeyConstraints(td.To)
+	}
+	own := func(c constraint, cols []warlot.ColumnSchema) bool {
+		for _, col := range cols {
+			if strings.EqualFold(col.Name, c.Column) {
+				return true
+			}
+		}
+		return false
+	}
+	count := map[string]int{}
+	for _, c := range from {
+		count[c.Key]++
+	}
+	for _, c := range to {
+		if count[c.Key] > 0 {
+			count[c.Key]--
+		} else if !own(c, td.Added) {
+			added = append(added, c.Key)
+		}
+	}
+	for _, c := range from {
+		if count[c.Key] > 0 {
+			count[c.Key]--
+			if !own(c, td.Removed) {
+				removed = append(removed, c.Key)
+			}
+		}
+	}
+	return added, removed
+}
+
+// foreignKeyConstraints keys the foreign keys of t by their columns and
+// referenced table and columns. Actions are left out, since a schema
+// parsed from DDL does not record them.
+func foreignKeyConstraints(t *warlot.TableSchema) []constraint {
+	var out []constraint
+	for _, fk := range t.ForeignKeys {
+		def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", quoteList(fk.Columns), quoteIdent(fk.RefTable))
+		if len(fk.RefColumns) > 0 {
+			def += " (" + quoteList(fk.RefColumns) + ")"
+		}
+		c := constraint{Key: sqlKey(def)}
+		if len(fk.Columns) == 1 {
+			c.Column = fk.Columns[0]
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// explicitIndexes returns the indexes of t created with CREATE INDEX.
+func explicitIndexes(t *warlot.TableSchema) []warlot.IndexSchema {
+	var out []warlot.IndexSchema
+	for _, ix := range t.Indexes {
+		if ix.SQL != "" {
+			out = append(out, ix)
+		}
+	}
+	return out
+}
+
+func sameIndex(t *warlot.TableSchema, ix warlot.IndexSchema) bool {
+	i := findIndex(t.Indexes, ix.Name)
+	return i >= 0 && sqlKey(t.Indexes[i].SQL) == sqlKey(ix.SQL)
+}
+
+func sameTrigger(t *warlot.TableSchema, tr warlot.Trigger) bool {
+	for _, o := range t.Triggers {
+		if strings.EqualFold(o.Name, tr.Name) {
+			return sqlKey(o.SQL) == sqlKey(tr.SQL)
+		}
+	}
+	return false
+}
+
+func findIndex(list []warlot.IndexSchema, name string) int {
+	for i, ix := range list {
+		if strings.EqualFold(ix.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasTrigger(list []warlot.Trigger, name string) bool {
+	for _, tr := range list {
+		if strings.EqualFold(tr.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareColumn(a, b warlot.ColumnSchema) []string {
+	var out []string
+	if normType(a.Type) != normType(b.Type) {
+		out = append(out, fmt.Sprintf("type %s -> %s", orNone(a.Type), orNone(b.Type)))
+	}
+	if a.NotNull != b.NotNull {
+		out = append(out, nullability(a.NotNull)+" -> "+nullability(b.NotNull))
+	}
+	if da, db := defaultText(a.Default), defaultText(b.Default); da != db {
+		out = append(out, fmt.Sprintf("default %s -> %s", orNone(da), orNone(db)))
+	}
+	if a.PK != b.PK {
+		out = append(out, fmt.Sprintf("primary key position %d -> %d", a.PK, b.PK))
+	}
+	return out
+}
+
+// Empty reports whether the schemas are the same.
+func (d *Diff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Changed) == 0
+}
+
+// Reverse returns the diff that undoes d.
+func (d *Diff) Reverse() *Diff {
+	r := &Diff{Added: d.Removed, Removed: d.Added}
+	for _, td := range d.Changed {
+		rt := TableDiff{
+			Name: td.From.Name, From: td.To, To: td.From, Added: td.Removed, Removed: td.Added,
+			AddedConstraints: td.RemovedConstraints, RemovedConstraints: td.AddedConstraints,
+			AddedIndexes: td.RemovedIndexes, RemovedIndexes: td.AddedIndexes,
+			AddedTriggers: td.RemovedTriggers, RemovedTriggers: td.AddedTriggers,
+		}
+		for _, cd := range td.Changed {
+			rc := compareColumn(cd.To, cd.From)
+			rt.Changed = append(rt.Changed, ColumnDiff{Name: cd.From.Name, From: cd.To, To: cd.From, Changes: rc})
+		}
+		r.Changed = append(r.Changed, rt)
+	}
+	return r
+}
+
+// String renders the diff as one line per table, column, constraint,
+// index or trigger change: "+" added, "-" removed, "~" changed.
+func (d *Diff) String() string {
+	var b strings.Builder
+	for _, t := range d.Added {
+		fmt.Fprintf(&b, "+ table %s\n", t.Name)
+	}
+	for _, t := range d.Removed {
+		fmt.Fprintf(&b, "- table %s\n", t.Name)
+	}
+	for _, td := range d.Changed {
+		fmt.Fprintf(&b, "~ table %s\n", td.Name)
+		for _, c := range td.Added {
+			fmt.Fprintf(&b, "    + column %s\n", columnSummary(c))
+		}
+		for _, c := range td.Removed {
+			fmt.Fprintf(&b, "    - column %s\n", columnSummary(c))
+		}
+		for _, cd := range td.Changed {
+			fmt.Fprintf(&b, "    ~ column %s: %s\n", cd.Name, strings.Join(cd.Changes, ", "))
+		}
+		for _, c := range td.RemovedConstraints {
+			fmt.Fprintf(&b, "    - constraint %s\n", c)
+		}
+		for _, c := range td.AddedConstraints {
+			fmt.Fprintf(&b, "    + constraint %s\n", c)
+		}
+		for _, ix := range td.RemovedIndexes {
+			fmt.Fprintf(&b, "    - index %s\n", ix.Name)
+		}
+		for _, ix := range td.AddedIndexes {
+			fmt.Fprintf(&b, "    + index %s\n", ix.Name)
+		}
+		for _, tr := range td.RemovedTriggers {
+			fmt.Fprintf(&b, "    - trigger %s\n", tr.Name)
+		}
+		for _, tr := range td.AddedTriggers {
+			fmt.Fprintf(&b, "    + trigger %s\n", tr.Name)
+		}
+	}
+	return b.String()
+}
+
+func columnSummary(c warlot.ColumnSchema) string {
+	s := c.Name
+	if c.Type != "" {
+		s += " " + c.Type
+	}
+	if c.NotNull {
+		s += " NOT NULL"
+	}
+	return s
+}
+
+// internalTable reports tables owned by SQLite or the migrator.
+func internalTable(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "sqlite_") || name == "_migrations" || name == "_migrations_lock"
+}
+
+func normType(t string) string { return strings.ToUpper(strings.Join(strings.Fields(t), " ")) }
+
+func nullability(notNull bool) string {
+	if notNull {
+		return "not null"
+	}
+	return "nullable"
+}
+
+// defaultText renders a column default as SQL text; "" means none.
+func defaultText(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	}
+	return fmt.Sprint(v)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,281 @@
+package schemadiff
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/warlottest"
+)
+
+const prodDDL = `
+CREATE TABLE products (id INTEGER PRIMARY KEY, sku TEXT NOT NULL, price INT, legacy TEXT);
+CREATE INDEX products_sku ON products (sku);
+CREATE TABLE audit (id INTEGER PRIMARY KEY, msg TEXT);
+CREATE TABLE tags (name TEXT);
+CREATE TRIGGER products_tag AFTER UPDATE OF price ON products BEGIN
+  INSERT INTO tags (name) VALUES (NEW.sku);
+END;
+CREATE TABLE reviews (id INTEGER PRIMARY KEY, product_id INTEGER REFERENCES products(id), body TEXT UNIQUE, stars INT);
+INSERT INTO products (sku, price, legacy) VALUES ('a', 10, 'x'), ('b', 20, 'y');
+INSERT INTO reviews (product_id, body, stars) VALUES (2, 'good', 5);
+`
+
+const stagingDDL = `
+CREATE TABLE products (id INTEGER PRIMARY KEY, sku TEXT NOT NULL, price REAL NOT NULL DEFAULT 0);
+create index if not exists "products_sku" on products(sku);
+CREATE TRIGGER products_tag AFTER UPDATE OF price ON products BEGIN
+  INSERT INTO tags (name) VALUES (NEW.sku);
+END;
+CREATE TABLE orders (id INTEGER PRIMARY KEY, product_id INTEGER REFERENCES products(id), qty INT DEFAULT 1);
+CREATE UNIQUE INDEX orders_product ON orders (product_id);
+CREATE TABLE tags (name TEXT);
+ALTER TABLE tags ADD COLUMN color TEXT DEFAULT 'red';
+CREATE INDEX tags_color ON tags (color);
+CREATE TABLE reviews (id INTEGER PRIMARY KEY, product_id INTEGER REFERENCES products(id), stars INT);
+`
+
+func TestParseSQL(t *testing.T) {
+	s, err := ParseSQL(stagingDDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := s["tags"]
+	if tags == nil || len(tags.Columns) != 2 || tags.Columns[1].Name != "color" || tags.Columns[1].Default != "'red'" {
+		t.Fatalf("tags: %+v", tags)
+	}
+	if !strings.Contains(tags.SQL, `color TEXT DEFAULT 'red'`) || len(tags.Indexes) != 1 || tags.Indexes[0].Columns[0] != "color" {
+		t.Fatalf("tags: %+v", tags)
+	}
+	if tr := s["products"].Triggers; len(tr) != 1 || tr[0].Name != "products_tag" {
+		t.Fatalf("products triggers: %+v", tr)
+	}
+	if fk := s["orders"].ForeignKeys; len(fk) != 1 || fk[0].RefTable != "products" {
+		t.Fatalf("orders foreign keys: %+v", fk)
+	}
+	if pk := s["products"].PrimaryKey(); len(pk) != 1 || pk[0] != "id" {
+		t.Fatalf("products pk: %v", pk)
+	}
+	if _, err := ParseSQL("CREATE TABLE"); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestCompare_Report(t *testing.T) {
+	from, _ := ParseSQL(prodDDL)
+	to, _ := ParseSQL(stagingDDL)
+	d := Compare(from, to)
+	want := "+ table orders\n" +
+		"- table audit\n" +
+		"~ table products\n" +
+		"    - column legacy TEXT\n" +
+		"    ~ column price: type INT -> REAL, nullable -> not null, default (none) -> 0\n" +
+		"~ table reviews\n" +
+		"    - column body TEXT\n" +
+		"~ table tags\n" +
+		"    + column color TEXT\n" +
+		"    + index tags_color\n"
+	if got := d.String(); got != want {
+		t.Fatalf("report:\n%s\nwant:\n%s", got, want)
+	}
+	if !Compare(to, to).Empty() || d.Empty() {
+		t.Fatal("Empty")
+	}
+
+	up, down := d.Migration()
+	for _, s := range []string{
+		"PRAGMA foreign_keys = OFF;\n",
+		`CREATE TABLE "orders" (`,
+		`CREATE UNIQUE INDEX orders_product ON orders (product_id)`,
+		`CREATE TABLE "products__new" (`,
+		`INSERT INTO "products__new" ("id", "sku", "price") SELECT "id", "sku", "price" FROM "products"`,
+		`ALTER TABLE "products__new" RENAME TO "products"`,
+		`create index if not exists "products_sku" on products(sku)`,
+		`CREATE TRIGGER products_tag AFTER UPDATE OF price ON products BEGIN`,
+		`CREATE TABLE "reviews__new" (`,
+		`ALTER TABLE "tags" ADD COLUMN "color" TEXT DEFAULT 'red'`,
+		`CREATE INDEX tags_color ON tags (color)`,
+		`DROP TABLE "audit";`,
+		`SELECT count(*) FROM pragma_foreign_key_check`,
+	} {
+		if !strings.Contains(up, s) {
+			t.Errorf("up missing %q:\n%s", s, up)
+		}
+	}
+	if !strings.Contains(down, `ALTER TABLE "tags" DROP COLUMN "color"`) || !strings.Contains(down, `DROP TABLE "orders"`) ||
+		!strings.Contains(down, `DROP INDEX "tags_color"`) {
+		t.Errorf("down:\n%s", down)
+	}
+}
+
+func TestCompare_Constraints(t *testing.T) {
+	from, _ := ParseSQL(`CREATE TABLE parent (id INTEGER PRIMARY KEY);
+CREATE TABLE t (id INTEGER PRIMARY KEY, a INT CHECK (a > 0), b TEXT UNIQUE,
+  p INT REFERENCES parent(id), old INT CHECK (old < 9), UNIQUE (a, b))`)
+	same, _ := ParseSQL(`CREATE TABLE parent (id INTEGER PRIMARY KEY);
+create table "t" ("id" integer primary key, "a" int, b text, p int, old int check (old < 9),
+  check (a > 0), unique (b), foreign key (p) references parent (id), constraint ab unique (a, b))`)
+	if d := Compare(from, same); !d.Empty() {
+		t.Fatalf("equivalent constraints differ:\n%s", d)
+	}
+
+	to, _ := ParseSQL(`CREATE TABLE parent (id INTEGER PRIMARY KEY);
+CREATE TABLE t (id INTEGER PRIMARY KEY, a INT CHECK (a >= 0), b TEXT UNIQUE,
+  p INT REFERENCES parent(id) ON DELETE CASCADE)`)
+	d := Compare(from, to)
+	want := "~ table t\n" +
+		"    - column old INT\n" +
+		"    - constraint CHECK ( A > 0 )\n" +
+		"    - constraint FOREIGN KEY ( P ) REFERENCES PARENT ( ID )\n" +
+		"    - constraint UNIQUE ( A , B )\n" +
+		"    + constraint CHECK ( A >= 0 )\n" +
+		"    + constraint FOREIGN KEY ( P ) REFERENCES PARENT ( ID ) ON DELETE CASCADE\n"
+	if got := d.String(); got != want {
+		t.Fatalf("report:\n%s\nwant:\n%s", got, want)
+	}
+	if up := d.SQL(); !strings.Contains(up, `CREATE TABLE "t__new"`) {
+		t.Fatalf("constraint change not rebuilt:\n%s", up)
+	}
+
+	// Without CREATE TABLE text, foreign keys are still compared.
+	bare := func(ref string) Schema {
+		return Schema{"t": &warlot.TableSchema{
+			Name:        "t",
+			Columns:     []warlot.ColumnSchema{{Name: "p", Type: "INT"}},
+			ForeignKeys: []warlot.ForeignKey{{Columns: []string{"p"}, RefTable: ref}},
+		}}
+	}
+	if !Compare(bare("parent"), bare("parent")).Empty() || Compare(bare("parent"), bare("other")).Empty() {
+		t.Fatal("foreign keys without table text")
+	}
+}
+
+func TestCanAlter(t *testing.T) {
+	from, _ := ParseSQL(`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, b INT, c INT, d INT);
+CREATE INDEX t_b ON t (b);
+CREATE INDEX t_c ON t (id) WHERE c > 0;`)
+	cases := []struct {
+		to    string
+		alter bool
+	}{
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, b INT, c INT, d INT, e INT DEFAULT 0)`, true},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, b INT, c INT, d INT, e INT UNIQUE)`, false},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, b INT, c INT, d INT, e TEXT DEFAULT CURRENT_TIMESTAMP)`, false},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, b INT, c INT)`, true},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, b INT, c INT, d INT)`, false},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, c INT, d INT)`, false},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT UNIQUE, b INT, d INT)`, false},
+	}
+	for _, tc := range cases {
+		to, err := ParseSQL(tc.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		to["t"].Indexes = from["t"].Indexes
+		td := compareTable(from["t"], to["t"])
+		if got := canAlter(*td); got != tc.alter {
+			t.Errorf("%s: canAlter = %v", tc.to, got)
+		}
+	}
+}
+
+func TestMigration_RoundTrip(t *testing.T) {
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := srv.CreateProject("0xholder", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj := srv.Client().Project(id)
+	if _, err := proj.SQL(ctx, "PRAGMA foreign_keys = ON;\n"+prodDDL, nil); err != nil {
+		t.Fatal(err)
+	}
+	before, err := Load(ctx, proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := ParseSQL(stagingDDL)
+
+	up, down := Compare(before, target).Migration()
+	fsys := fstest.MapFS{
+		"m/001_promote.up.sql":   {Data: []byte(up)},
+		"m/001_promote.down.sql": {Data: []byte(down)},
+	}
+	if _, err := warlot.Migrate.Up(ctx, proj, fsys, "m"); err != nil {
+		t.Fatalf("up: %v\n%s", err, up)
+	}
+	after, err := Load(ctx, proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Compare(after, target); !d.Empty() {
+		t.Fatalf("after up:\n%s", d)
+	}
+	rows, err := warlot.Query[struct {
+		SKU   string  `json:"sku"`
+		Price float64 `json:"price"`
+	}](ctx, proj, `SELECT sku, price FROM products ORDER BY id`, nil)
+	if err != nil || len(rows) != 2 || rows[1].Price != 20 {
+		t.Fatalf("rows kept: %+v err=%v", rows, err)
+	}
+	if _, err := proj.SQL(ctx, `UPDATE products SET price = 21 WHERE sku = 'b'`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := warlot.Query[struct {
+		Name string `json:"name"`
+	}](ctx, proj, `SELECT name FROM tags`, nil); err != nil || len(tags) != 1 || tags[0].Name != "b" {
+		t.Fatalf("trigger not recreated: %+v err=%v", tags, err)
+	}
+
+	if _, err := warlot.Migrate.Down(ctx, proj, fsys, "m"); err != nil {
+		t.Fatalf("down: %v\n%s", err, down)
+	}
+	reverted, err := Load(ctx, proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Compare(reverted, before); !d.Empty() {
+		t.Fatalf("after down:\n%s", d)
+	}
+	fk, err := warlot.Query[struct {
+		On int `json:"foreign_keys"`
+	}](ctx, proj, `PRAGMA foreign_keys`, nil)
+	if err != nil || len(fk) != 1 || fk[0].On != 1 {
+		t.Fatalf("foreign_keys not restored: %+v err=%v", fk, err)
+	}
+}
+
+func TestMigration_DanglingForeignKeyFails(t *testing.T) {
+	warlottest.SkipWithoutSQLite(t)
+	srv := warlottest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	id, _ := srv.CreateProject("0xholder", "p")
+	proj := srv.Client().Project(id)
+	if _, err := proj.SQL(ctx, `CREATE TABLE a (id INTEGER PRIMARY KEY);
+CREATE TABLE b (id INTEGER PRIMARY KEY, a_id INTEGER REFERENCES a(id));
+INSERT INTO a (id) VALUES (1);
+INSERT INTO b (a_id) VALUES (1);`, nil); err != nil {
+		t.Fatal(err)
+	}
+	from, err := Load(ctx, proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := Schema{"b": from["b"]}
+	up := Compare(from, to).SQL()
+	fsys := fstest.MapFS{"m/001_drop_a.up.sql": {Data: []byte(up)}}
+	if _, err := warlot.Migrate.Up(ctx, proj, fsys, "m"); err == nil || !strings.Contains(err.Error(), "CHECK") {
+		t.Fatalf("up = %v\n%s", err, up)
+	}
+	if tables, _ := proj.Tables(ctx); len(tables.Tables) < 2 {
+		t.Fatalf("drop not rolled back: %v", tables.Tables)
+	}
+}
@@ -9,12 +9,15 @@ import (
 )
 
 // ExecSQL executes a parameterized SQL statement within a project.
-// Both DDL/DML and SELECT responses are supported.
+// Both DDL/DML and SELECT responses are supported. Unless the client's
+// IdempotencyMode says otherwise, a mutating statement sent without
+// WithIdempotencyKey gets a generated key shared by all its attempts.
 func (c *Client) ExecSQL(ctx context.Context, projectID string, req SQLRequest, opts ...CallOption) (*SQLResponse, error) {
 	path := fmt.Sprintf("/warlotSql/projects/%s/sql", url.PathEscape(projectID))
 	var out SQLResponse
 	h := c.authHeaders()
 	mergeHeaders(h, buildHeaders(nil, opts...))
+	ctx = c.prepareSQL(ctx, req.SQL, h)
 	if err := c.doJSON(ctx, http.MethodPost, path, h, req, &out); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,222 @@
+// Package sqldriver registers a database/sql driver named "warlot" that
+// forwards statements to a Warlot project through the SDK client.
+//
+// Usage:
+//
+//	import _ "github.com/steven3002/warlot-golang-sdk/warlot-go/warlot/sqldriver"
+//
+//	db, err := sql.Open("warlot", "https://KEY@warlot-api.onrender.com/PROJECT_ID?holder=0xH&pname=myproj")
+//
+// Exec and Query are backed by Client.ExecSQL and Client.ExecSQLStream.
+// The API executes each statement independently, so transactions are not
+// supported by this driver.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// DriverName is the name under which the driver is registered.
+const DriverName = "warlot"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+// ErrTxUnsupported is returned by Begin because statements are executed
+// independently by the API.
+var ErrTxUnsupported = errors.New("warlot: transactions are not supported by the database/sql driver")
+
+// Driver implements driver.Driver and driver.DriverContext.
+type Driver struct{}
+
+// Open parses the DSN and returns a new connection.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+// OpenConnector parses the DSN once so the pool can reuse the configuration.
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{client: warlot.New(cfg.Options()...), projectID: cfg.ProjectID, drv: d}, nil
+}
+
+// NewConnector returns a connector bound to an existing client, for use with
+// sql.OpenDB when the client is configured programmatically.
+func NewConnector(cl *warlot.Client, projectID string) driver.Connector {
+	return &connector{client: cl, projectID: projectID, drv: &Driver{}}
+}
+
+type connector struct {
+	client    *warlot.Client
+	projectID string
+	drv       *Driver
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{client: c.client, projectID: c.projectID}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.drv }
+
+// conn is stateless; every statement is a separate API call.
+type conn struct {
+	client    *warlot.Client
+	projectID string
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) { return nil, ErrTxUnsupported }
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.client.ExecSQL(ctx, c.projectID, warlot.SQLRequest{SQL: "SELECT 1"})
+	return err
+}
+
+// CheckNamedValue accepts the value types the JSON request body can carry.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nv.Name != "" {
+		return fmt.Errorf("warlot: named parameter %q is not supported; use ? placeholders", nv.Name)
+	}
+	switch v := nv.Value.(type) {
+	case nil, int64, float64, bool, string, []byte:
+		return nil
+	case time.Time:
+		nv.Value = v.UTC().Format(time.RFC3339Nano)
+		return nil
+	default:
+		val, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return err
+		}
+		nv.Value = val
+		if t, ok := val.(time.Time); ok {
+			nv.Value = t.UTC().Format(time.RFC3339Nano)
+		}
+		return nil
+	}
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.client.ExecSQL(ctx, c.projectID, warlot.SQLRequest{SQL: query, Params: params(args)})
+	if err != nil {
+		return nil, err
+	}
+	r := result{}
+	if res.RowCount != nil {
+		r.rowsAffected = int64(*res.RowCount)
+		r.hasCount = true
+	}
+	return r, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	sc, err := c.client.ExecSQLStream(ctx, c.projectID, warlot.SQLRequest{SQL: query, Params: params(args)})
+	if err != nil {
+		return nil, err
+	}
+	return newRows(sc)
+}
+
+// params converts driver arguments to the JSON params array. []byte values
+// are sent as strings because the API accepts JSON scalars only.
+func params(args []driver.NamedValue) []any {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]any, len(args))
+	for i, a := range args {
+		if b, ok := a.Value.([]byte); ok {
+			out[i] = string(b)
+			continue
+		}
+		out[i] = a.Value
+	}
+	return out
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return out
+}
+
+// result exposes SQLResponse.RowCount as RowsAffected.
+type result struct {
+	rowsAffected int64
+	hasCount     bool
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return 0, errors.New("warlot: LastInsertId is not supported; use RETURNING or a follow-up SELECT")
+}
+
+func (r result) RowsAffected() (int64, error) {
+	if !r.hasCount {
+		return 0, errors.New("warlot: row count not reported for this statement")
+	}
+	return r.rowsAffected, nil
+}
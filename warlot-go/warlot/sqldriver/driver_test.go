@@ -0,0 +1,193 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+func TestParseDSN(t *testing.T) {
+	cfg, err := ParseDSN("https://key-1@api.example.com/v1/proj-123?holder=0xH&pname=shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "https://api.example.com/v1" || cfg.ProjectID != "proj-123" ||
+		cfg.APIKey != "key-1" || cfg.HolderID != "0xH" || cfg.ProjectName != "shop" {
+		t.Fatalf("cfg=%+v", cfg)
+	}
+
+	cfg, err = ParseDSN("warlot://api.example.com/p?apikey=k&project_name=n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "https://api.example.com" || cfg.ProjectID != "p" || cfg.APIKey != "k" || cfg.ProjectName != "n" {
+		t.Fatalf("cfg=%+v", cfg)
+	}
+
+	for _, bad := range []string{"ftp://h/p", "https://h", "https:///p"} {
+		if _, err := ParseDSN(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestDriver_ExecQuery(t *testing.T) {
+	var gotKey, gotHolder string
+	var gotParams []any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/warlotSql/projects/proj-123/sql" {
+			http.NotFound(w, r)
+			return
+		}
+		gotKey = r.Header.Get("x-api-key")
+		gotHolder = r.Header.Get("x-holder-id")
+		var req warlot.SQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotParams = req.Params
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(strings.ToLower(req.SQL), "select") {
+			fmt.Fprint(w, `{"ok":true,"rows":[{"id":9007199254740993,"name":"A","price":9.5},{"id":2,"name":null,"price":1}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"row_count":3}`)
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open(DriverName, strings.Replace(srv.URL, "http://", "http://key-1@", 1)+"/proj-123?holder=0xH&pname=shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `UPDATE products SET price = ? WHERE sku = ?`, 9.5, "sku-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 3 {
+		t.Fatalf("rows affected=%d err=%v", n, err)
+	}
+	if gotKey != "key-1" || gotHolder != "0xH" {
+		t.Fatalf("headers: key=%q holder=%q", gotKey, gotHolder)
+	}
+	if len(gotParams) != 2 || gotParams[1] != "sku-1" {
+		t.Fatalf("params=%v", gotParams)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id, name, price FROM products`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	if strings.Join(cols, ",") != "id,name,price" {
+		t.Fatalf("columns=%v", cols)
+	}
+	type product struct {
+		ID    int64
+		Name  sql.NullString
+		Price float64
+	}
+	var got []product
+	for rows.Next() {
+		var p product
+		if err := rows.Scan(&p.ID, &p.Name, &p.Price); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 9007199254740993 || got[0].Name.String != "A" || got[1].Name.Valid || got[1].Price != 1 {
+		t.Fatalf("got=%+v", got)
+	}
+}
+
+func TestDriver_NewConnectorAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"rows":[]}`)
+	}))
+	defer srv.Close()
+
+	db := sql.OpenDB(NewConnector(warlot.New(warlot.WithBaseURL(srv.URL)), "p"))
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT * FROM empty`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.Next() {
+		t.Fatal("expected no rows")
+	}
+	rows.Close()
+
+	if _, err := db.ExecContext(ctx, `SELECT :x`, sql.Named("x", 1)); err == nil {
+		t.Fatal("expected named parameter error")
+	}
+	if _, err := db.BeginTx(ctx, nil); err != ErrTxUnsupported {
+		t.Fatalf("begin err=%v", err)
+	}
+}
+
+func TestDriver_ColumnsFromResponse(t *testing.T) {
+	body := `{"ok":true,"columns":[{"name":"id","type":"INTEGER"},"name"],"rows":[]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	db := sql.OpenDB(NewConnector(warlot.New(warlot.WithBaseURL(srv.URL)), "p"))
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT id, name FROM empty`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, _ := rows.Columns()
+	if strings.Join(cols, ",") != "id,name" || rows.Next() {
+		t.Fatalf("columns=%v", cols)
+	}
+	rows.Close()
+
+	// Declared order wins over row key order; missing keys scan as NULL.
+	body = `{"ok":true,"columns":["id","name"],"rows":[{"name":"A","id":1},{"id":2}]}`
+	rows, err = db.QueryContext(ctx, `SELECT id, name FROM t`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %t", id, name.String, name.Valid))
+	}
+	if fmt.Sprint(got) != "[1 A true 2  false]" {
+		t.Fatalf("got %v", got)
+	}
+}
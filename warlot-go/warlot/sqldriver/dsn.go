@@ -0,0 +1,83 @@
+package sqldriver
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// Config holds the connection settings carried by a DSN.
+type Config struct {
+	BaseURL     string
+	ProjectID   string
+	APIKey      string
+	HolderID    string
+	ProjectName string
+}
+
+// ParseDSN parses a DSN of the form
+//
+//	https://API_KEY@host[:port][/base]/PROJECT_ID?holder=HOLDER&pname=NAME
+//
+// The scheme "warlot" is an alias for https. The API key may also be passed
+// as the "apikey" query parameter, and "project_name" is accepted for "pname".
+func ParseDSN(dsn string) (*Config, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("warlot: invalid DSN: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "warlot":
+		u.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("warlot: unsupported DSN scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("warlot: DSN is missing a host")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	cfg := &Config{ProjectID: path[i+1:]}
+	if cfg.ProjectID == "" {
+		return nil, errors.New("warlot: DSN is missing a project ID")
+	}
+	base := ""
+	if i >= 0 {
+		base = "/" + path[:i]
+	}
+	cfg.BaseURL = u.Scheme + "://" + u.Host + base
+
+	q := u.Query()
+	if u.User != nil {
+		cfg.APIKey = u.User.Username()
+	}
+	if k := q.Get("apikey"); k != "" {
+		cfg.APIKey = k
+	}
+	cfg.HolderID = q.Get("holder")
+	cfg.ProjectName = q.Get("pname")
+	if cfg.ProjectName == "" {
+		cfg.ProjectName = q.Get("project_name")
+	}
+	return cfg, nil
+}
+
+// Options converts the configuration to client options.
+func (c *Config) Options() []warlot.Option {
+	opts := []warlot.Option{warlot.WithBaseURL(c.BaseURL)}
+	if c.APIKey != "" {
+		opts = append(opts, warlot.WithAPIKey(c.APIKey))
+	}
+	if c.HolderID != "" {
+		opts = append(opts, warlot.WithHolderID(c.HolderID))
+	}
+	if c.ProjectName != "" {
+		opts = append(opts, warlot.WithProjectName(c.ProjectName))
+	}
+	return opts
+}
@@ -0,0 +1,62 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"io"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// rows adapts a streaming RowScanner to driver.Rows. Columns are those the
+// response declares, falling back to the key order of the first row object.
+type rows struct {
+	sc      *warlot.RowScanner
+	columns []string
+	first   []any // buffered first row, consumed by the first Next
+	done    bool
+}
+
+func newRows(sc *warlot.RowScanner) (*rows, error) {
+	r := &rows{sc: sc}
+	vals, ok := sc.NextValues()
+	if !ok {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		r.done = true
+	}
+	for _, c := range sc.Columns() {
+		r.columns = append(r.columns, c.Name)
+	}
+	r.first = vals
+	return r, nil
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return r.sc.Close() }
+
+func (r *rows) Next(dest []driver.Value) error {
+	vals := r.first
+	r.first = nil
+	if vals == nil {
+		if r.done {
+			return io.EOF
+		}
+		var ok bool
+		if vals, ok = r.sc.NextValues(); !ok {
+			r.done = true
+			if err := r.sc.Err(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+	}
+	for i := range dest {
+		dest[i] = nil
+		if i < len(vals) {
+			dest[i] = vals[i]
+		}
+	}
+	return nil
+}
@@ -7,8 +7,8 @@ import (
 	"net/url"
 )
 
-// GetProjectStatus returns a project status map.
-func (c *Client) GetProjectStatus(ctx context.Context, projectID string, opts ...CallOption) (ProjectStatus, error) {
+// GetProjectStatus returns the status of a project.
+func (c *Client) GetProjectStatus(ctx context.Context, projectID string, opts ...CallOption) (*ProjectStatus, error) {
 	path := fmt.Sprintf("/warlotSql/projects/%s/status", url.PathEscape(projectID))
 	var out ProjectStatus
 	h := c.authHeaders()
@@ -16,11 +16,11 @@ func (c *Client) GetProjectStatus(ctx context.Context, projectID string, opts ..
 	if err := c.doJSON(ctx, http.MethodGet, path, h, nil, &out); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return &out, nil
 }
 
 // CommitProject persists recent changes of a project to the blockchain.
-func (c *Client) CommitProject(ctx context.Context, projectID string, opts ...CallOption) (CommitResponse, error) {
+func (c *Client) CommitProject(ctx context.Context, projectID string, opts ...CallOption) (*CommitResponse, error) {
 	path := fmt.Sprintf("/warlotSql/projects/%s/commit", url.PathEscape(projectID))
 	var out CommitResponse
 	h := c.authHeaders()
@@ -28,5 +28,5 @@ func (c *Client) CommitProject(ctx context.Context, projectID string, opts ...Ca
 	if err := c.doJSON(ctx, http.MethodPost, path, h, struct{}{}, &out); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return &out, nil
 }
@@ -16,51 +16,136 @@ type RowScanner struct {
 	inRows  bool
 	done    bool
 	lastErr error
+	columns []Column
+	index   map[string]int
 }
 
 // Next decodes the next row into dst (map or struct pointer). Returns false
 // on end of stream or on error. After false, Err should be checked.
+// Numbers decoded into maps or interfaces are json.Number values.
 func (s *RowScanner) Next(dst any) bool {
-	if s.done {
+	if !s.seek() {
 		return false
 	}
-	// Seek to "rows": [
-	if !s.inRows {
-		for {
-			tok, err := s.dec.Token()
-			if err != nil {
-				s.lastErr = err
-				_ = s.Close()
-				return false
-			}
-			if key, ok := tok.(string); ok && key == "rows" {
-				tok, err = s.dec.Token()
-				if err != nil {
-					s.lastErr = err
-					_ = s.Close()
-					return false
-				}
-				if delim, ok := tok.(json.Delim); !ok || delim != '[' {
-					s.lastErr = fmt.Errorf("unexpected token after rows: %v", tok)
-					_ = s.Close()
-					return false
-				}
-				s.inRows = true
-				break
-			}
-		}
-	}
-	// Decode next element or finish array.
 	if s.dec.More() {
 		if err := s.dec.Decode(dst); err != nil {
-			s.lastErr = err
-			_ = s.Close()
-			return false
+			return s.fail(err)
 		}
 		return true
 	}
-	// Consume closing ']' and finish.
+	s.finish()
+	return false
+}
+
+// NextValues decodes the next row and returns its values in the order of
+// Columns, normalized as database/sql drivers report them: int64 or float64
+// for numbers and JSON text for arrays and objects. Keys not yet known are
+// appended to Columns. Returns false on end of stream or on error.
+func (s *RowScanner) NextValues() ([]any, bool) {
+	if !s.seek() {
+		return nil, false
+	}
+	if !s.dec.More() {
+		s.finish()
+		return nil, false
+	}
+	keys, m, err := decodeOrderedObject(s.dec)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	s.columns = addColumns(s.columns, s.index, keys)
+	vals := make([]any, len(s.columns))
+	for k, v := range m {
+		vals[s.index[k]] = driverValue(v)
+	}
+	return vals, true
+}
+
+// Columns returns the result columns known so far: those the response
+// declares ahead of its rows, then row keys in first-seen order. Once the
+// stream is exhausted it also includes columns declared after the rows.
+func (s *RowScanner) Columns() []Column { return s.columns }
+
+// seek advances to the first row, recording declared columns on the way.
+func (s *RowScanner) seek() bool {
+	if s.done {
+		return false
+	}
+	if s.inRows {
+		return true
+	}
+	for {
+		key, ok := s.member()
+		if !ok {
+			return false
+		}
+		if key == "rows" {
+			if err := expectDelim(s.dec, '['); err != nil {
+				return s.fail(fmt.Errorf("rows: %w", err))
+			}
+			s.inRows = true
+			return true
+		}
+	}
+}
+
+// finish consumes the rest of the response after the rows array.
+func (s *RowScanner) finish() {
 	_, _ = s.dec.Token()
+	for {
+		if _, ok := s.member(); !ok {
+			return
+		}
+	}
+}
+
+// member reads the next top-level key, decoding it when it is columns and
+// skipping other values except rows. It returns false at the end of the
+// object, which ends the stream, or on error. A response without rows is an
+// empty result.
+func (s *RowScanner) member() (string, bool) {
+	if !s.dec.More() {
+		_, _ = s.dec.Token()
+		s.done = true
+		_ = s.Close()
+		return "", false
+	}
+	tok, err := s.dec.Token()
+	if err != nil {
+		return "", s.fail(err)
+	}
+	key, _ := tok.(string)
+	switch key {
+	case "rows":
+		if !s.inRows {
+			return key, true
+		}
+	case "columns":
+		var cols []Column
+		if err := s.dec.Decode(&cols); err != nil {
+			return "", s.fail(fmt.Errorf("columns: %w", err))
+		}
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.Name
+		}
+		s.columns = addColumns(s.columns, s.index, names)
+		for _, c := range cols {
+			if c.Type != "" {
+				s.columns[s.index[c.Name]].Type = c.Type
+			}
+		}
+		return key, true
+	}
+	var skip json.RawMessage
+	if err := s.dec.Decode(&skip); err != nil {
+		return "", s.fail(err)
+	}
+	return key, true
+}
+
+func (s *RowScanner) fail(err error) bool {
+	s.lastErr = err
 	s.done = true
 	_ = s.Close()
 	return false
@@ -85,11 +170,13 @@ func (c *Client) ExecSQLStream(ctx context.Context, projectID string, req SQLReq
 	path := fmt.Sprintf("/warlotSql/projects/%s/sql", url.PathEscape(projectID))
 	h := c.authHeaders()
 	mergeHeaders(h, buildHeaders(nil, opts...))
+	ctx = c.prepareSQL(ctx, req.SQL, h)
 	res, err := c.doRequest(ctx, http.MethodPost, path, h, req)
 	if err != nil {
 		return nil, err
 	}
 	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
 	tok, err := dec.Token()
 	if err != nil {
 		_ = res.Body.Close()
@@ -99,5 +186,5 @@ func (c *Client) ExecSQLStream(ctx context.Context, projectID string, req SQLReq
 		_ = res.Body.Close()
 		return nil, fmt.Errorf("unexpected response start: %v", tok)
 	}
-	return &RowScanner{dec: dec, closer: res.Body}, nil
+	return &RowScanner{dec: dec, closer: res.Body, index: map[string]int{}}, nil
 }
@@ -2,6 +2,7 @@ package warlot
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -39,7 +40,11 @@ func TestExecSQLStream_RowScanner(t *testing.T) {
 		if !sc.Next(&m) {
 			break
 		}
-		got = append(got, int(m["i"].(float64)))
+		n, err := m["i"].(json.Number).Int64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, int(n))
 	}
 	if sc.Err() != nil {
 		t.Fatalf("scanner err: %v", sc.Err())
@@ -43,7 +43,7 @@ func (c *Client) BrowseRows(ctx context.Context, projectID, table string, limit,
 }
 
 // GetTableSchema returns the schema of a table.
-func (c *Client) GetTableSchema(ctx context.Context, projectID, table string, opts ...CallOption) (TableSchema, error) {
+func (c *Client) GetTableSchema(ctx context.Context, projectID, table string, opts ...CallOption) (*TableSchema, error) {
 	path := fmt.Sprintf("/warlotSql/projects/%s/tables/%s/schema", url.PathEscape(projectID), url.PathEscape(table))
 	var out TableSchema
 	h := c.authHeaders()
@@ -51,7 +51,7 @@ func (c *Client) GetTableSchema(ctx context.Context, projectID, table string, op
 	if err := c.doJSON(ctx, http.MethodGet, path, h, nil, &out); err != nil {
 		return nil, err
 	}
-	return out, nil
+	return &out, nil
 }
 
 // GetTableCount returns the number of tables in a project.
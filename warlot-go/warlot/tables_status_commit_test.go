@@ -3,6 +3,7 @@ package warlot
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -31,13 +32,13 @@ func TestTables_Status_Commit_Pager(t *testing.T) {
 				},
 			})
 		case strings.HasSuffix(r.URL.Path, "/schema"):
-			json.NewEncoder(w).Encode(map[string]any{"name": "products", "columns": []string{"id"}})
+			io.WriteString(w, `{"name":"products","columns":[{"cid":0,"name":"id","type":"INTEGER","notnull":0,"dflt_value":null,"pk":1},{"cid":1,"name":"sku","type":"TEXT","notnull":1,"dflt_value":"'x'","pk":0}],"foreign_keys":[{"id":0,"seq":0,"table":"a","from":"a1","to":"id"},{"id":0,"seq":1,"table":"a","from":"a2","to":"k"}],"owner":"bob"}`)
 		case strings.HasSuffix(r.URL.Path, "/count"):
 			json.NewEncoder(w).Encode(TableCountResponse{ProjectID: "proj-123", TableCount: 1})
 		case strings.HasSuffix(r.URL.Path, "/status"):
-			json.NewEncoder(w).Encode(map[string]any{"ok": true})
+			io.WriteString(w, `{"ok":true,"project_id":"proj-123","pending_writes":3,"last_commit_at":"2024-01-02T03:04:05Z","Epoch":7}`)
 		case strings.HasSuffix(r.URL.Path, "/commit"):
-			json.NewEncoder(w).Encode(map[string]any{"committed": true})
+			io.WriteString(w, `{"committed":true,"TxDigest":"0xabc","blob_id":"blob-1","gas":12}`)
 		default:
 			http.NotFound(w, r)
 		}
@@ -69,8 +70,20 @@ func TestTables_Status_Commit_Pager(t *testing.T) {
 
 	// schema
 	sc, err := proj.Schema(ctx, "products")
-	if err != nil || sc["name"] != "products" {
-		t.Fatalf("schema: %v err=%v", sc, err)
+	if err != nil || sc.Name != "products" || len(sc.Columns) != 2 || sc.Raw()["name"] != "products" {
+		t.Fatalf("schema: %+v err=%v", sc, err)
+	}
+	if c := sc.Columns[1]; c.Name != "sku" || !c.NotNull || c.Default != "'x'" || sc.Columns[0].PK != 1 {
+		t.Fatalf("columns: %+v", sc.Columns)
+	}
+	if pk := sc.PrimaryKey(); len(pk) != 1 || pk[0] != "id" {
+		t.Fatalf("pk: %v", pk)
+	}
+	if fk := sc.ForeignKeys; len(fk) != 1 || fk[0].RefTable != "a" || strings.Join(fk[0].Columns, ",") != "a1,a2" || strings.Join(fk[0].RefColumns, ",") != "id,k" {
+		t.Fatalf("foreign keys: %+v", fk)
+	}
+	if sc.Extra["owner"] != "bob" || len(sc.Extra) != 1 {
+		t.Fatalf("extra: %v", sc.Extra)
 	}
 
 	// count
@@ -81,13 +94,51 @@ func TestTables_Status_Commit_Pager(t *testing.T) {
 
 	// status
 	st, err := proj.Status(ctx)
-	if err != nil || st["ok"] != true {
+	if err != nil || !st.OK || st.PendingChanges != 3 || st.Epoch != 7 || st.LastCommitAt != "2024-01-02T03:04:05Z" || st.Raw()["ok"] != true {
 		t.Fatalf("status: %+v err=%v", st, err)
 	}
 
 	// commit
 	cm, err := proj.Commit(ctx)
-	if err != nil || cm["committed"] != true {
+	if err != nil || !cm.Committed || cm.TxDigest != "0xabc" || cm.BlobID != "blob-1" || cm.Extra["gas"] != float64(12) {
 		t.Fatalf("commit: %+v err=%v", cm, err)
 	}
+	b, err := json.Marshal(cm)
+	if err != nil || !strings.Contains(string(b), `"TxDigest":"0xabc"`) {
+		t.Fatalf("commit round trip: %s err=%v", b, err)
+	}
+}
+
+func TestResponses_MarshalReflectsEdits(t *testing.T) {
+	var sc TableSchema
+	if err := json.Unmarshal([]byte(`{"table":"t","columns":["a"],"owner":"bob"}`), &sc); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(sc); string(b) != `{"columns":["a"],"owner":"bob","table":"t"}` {
+		t.Fatalf("unchanged schema not written as received: %s", b)
+	}
+	sc.Name = "u"
+	sc.Columns = append(sc.Columns, ColumnSchema{CID: 1, Name: "b"})
+	b, _ := json.Marshal(sc)
+	if !strings.Contains(string(b), `"name":"u"`) || !strings.Contains(string(b), `"name":"b"`) ||
+		!strings.Contains(string(b), `"owner":"bob"`) || strings.Contains(string(b), `"table"`) {
+		t.Fatalf("edited schema: %s", b)
+	}
+	if sc.Raw()["name"] != "u" {
+		t.Fatalf("Raw is stale: %v", sc.Raw())
+	}
+
+	var st ProjectStatus
+	_ = json.Unmarshal([]byte(`{"ok":true,"pending_writes":2}`), &st)
+	st.PendingChanges = 0
+	if b, _ := json.Marshal(st); !strings.Contains(string(b), `"pending_changes":0`) || strings.Contains(string(b), "pending_writes") {
+		t.Fatalf("edited status: %s", b)
+	}
+
+	var cm CommitResponse
+	_ = json.Unmarshal([]byte(`{"committed":true,"TxDigest":"0xabc"}`), &cm)
+	cm.Extra = map[string]any{"note": "x"}
+	if b, _ := json.Marshal(cm); !strings.Contains(string(b), `"tx_digest":"0xabc"`) || !strings.Contains(string(b), `"note":"x"`) {
+		t.Fatalf("edited commit: %s", b)
+	}
 }
@@ -1,7 +1,8 @@
 package warlot
 
 import (
-	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -37,6 +38,20 @@ func buildHeaders(h http.Header, opts ...CallOption) http.Header {
 	return h
 }
 
+// hasIdempotencyKey reports whether the CallOptions set an idempotency key.
+func hasIdempotencyKey(opts []CallOption) bool {
+	return buildHeaders(nil, opts...).Get("x-idempotency-key") != ""
+}
+
+// newIdempotencyKey returns a random hex key for a single logical call.
+func newIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
 // mergeHeaders appends values from src into dst.
 func mergeHeaders(dst http.Header, src http.Header) {
 	if src == nil {
@@ -94,16 +109,20 @@ func parseRetryAfter(v string) time.Duration {
 	return 0
 }
 
-// redactHeaders masks sensitive header values for logging.
-func redactHeaders(h http.Header) http.Header {
+// redactHeaders masks sensitive header values. For logging it keeps a
+// short prefix and suffix of long values; with full set, as for cassettes,
+// which are committed, no part of the value is kept.
+func redactHeaders(h http.Header, full bool) http.Header {
 	if h == nil {
 		return h
 	}
 	cp := http.Header{}
 	for k, vs := range h {
 		for _, v := range vs {
-			if strings.EqualFold(k, "x-api-key") {
-				if len(v) > 8 {
+			if strings.EqualFold(k, "x-api-key") || strings.EqualFold(k, "Authorization") {
+				if full {
+					cp.Add(k, cassetteRedacted)
+				} else if len(v) > 8 {
 					cp.Add(k, v[:4]+"…"+v[len(v)-4:])
 				} else {
 					cp.Add(k, "********")
@@ -147,27 +166,3 @@ func normalizeRetries(r int) int {
 	}
 	return r
 }
-
-// jitterSleep sleeps for a randomized duration based on the current backoff.
-// Context cancellation is respected.
-func jitterSleep(ctx context.Context, backoff, maxBack time.Duration) {
-	jitter := time.Duration(float64(backoff) * (0.5 + 0.5*randFloat64()))
-	if jitter > maxBack {
-		jitter = maxBack
-	}
-	timer := time.NewTimer(jitter)
-	defer timer.Stop()
-	select {
-	case <-timer.C:
-	case <-ctx.Done():
-	}
-}
-
-// nextBackoff doubles backoff up to maxBack.
-func nextBackoff(backoff, maxBack time.Duration) time.Duration {
-	backoff *= 2
-	if backoff > maxBack {
-		backoff = maxBack
-	}
-	return backoff
-}
@@ -0,0 +1,53 @@
+package warlottest
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoTable is returned by Engine.Schema for a table that does not exist.
+var ErrNoTable = errors.New("warlottest: no such table")
+
+// Engine executes SQL for one fake project. NewSQLiteEngine returns the
+// default, a private in-memory SQLite database; NewSQLEngine adapts any
+// *sql.DB that speaks SQLite's dialect.
+type Engine interface {
+	// Exec runs a script of one or more statements. Positional parameters
+	// are bound across the whole script in order. The result is that of
+	// the last statement; an error leaves the database unchanged for the
+	// failed statement and rolls back any transaction the script opened.
+	Exec(ctx context.Context, query string, args []any) (*Result, error)
+
+	// Tables lists user tables in creation order.
+	Tables(ctx context.Context) ([]string, error)
+
+	// Schema describes a table, or returns ErrNoTable.
+	Schema(ctx context.Context, table string) (*TableInfo, error)
+}
+
+// Result is the outcome of Engine.Exec. Query is set when the statement
+// produced a result set (SELECT, PRAGMA or RETURNING); RowsAffected is set
+// otherwise.
+type Result struct {
+	Query        bool
+	Columns      []string
+	Rows         [][]any
+	RowsAffected int64
+}
+
+// TableInfo describes a table. SQL is its CREATE TABLE statement.
+type TableInfo struct {
+	Name    string       `json:"name"`
+	SQL     string       `json:"sql"`
+	Columns []ColumnInfo `json:"columns"`
+}
+
+// ColumnInfo mirrors a row of SQLite's PRAGMA table_info.
+type ColumnInfo struct {
+	CID     int    `json:"cid"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	NotNull bool   `json:"notnull"`
+	Default any    `json:"dflt_value"`
+	PK      int    `json:"pk"`
+}
@@ -0,0 +1,695 @@
+// Package warlottest provides an in-process fake of the Warlot API for
+// tests. A Server implements project resolve/init, key issuance, SQL,
+// table browsing, schema, count, status and commit on top of an embedded
+// SQL engine, and can inject failures, latency and Retry-After headers:
+//
+//	srv := warlottest.NewServer()
+//	defer srv.Close()
+//	cl := srv.Client()
+//	proj := cl.Project("p1") // unknown projects are created on first use
+//	_, err := proj.SQL(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)`, nil)
+package warlottest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+// DefaultBrowseLimit is the page size used by the rows endpoint when the
+// request does not set limit.
+const DefaultBrowseLimit = 100
+
+// Server is a fake Warlot API served over a local httptest server.
+type Server struct {
+	// URL is the base URL of the server, for warlot.WithBaseURL.
+	URL string
+
+	srv        *httptest.Server
+	newEngine  func(projectID string) (Engine, error)
+	requireKey bool
+	latency    time.Duration
+
+	mu       sync.Mutex
+	projects map[string]*project
+	faults   []*Fault
+	requests []Request
+
+	idemMu    sync.Mutex
+	idem      map[string]*replay
+	idemOrder []string // keys in insertion order, for eviction
+}
+
+// maxReplays bounds the stored idempotent responses; the oldest are
+// forgotten first, as keys expire on the real API.
+const maxReplays = 4096
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithEngine sets the engine factory called once per project. The default
+// is NewSQLiteEngine, which needs a registered SQLite driver; pass an
+// engine built with NewSQLEngine to use any other *sql.DB.
+func WithEngine(fn func(projectID string) (Engine, error)) Option {
+	return func(s *Server) { s.newEngine = fn }
+}
+
+// WithRequireAPIKey makes project endpoints require an x-api-key issued for
+// the project through /auth/issue. Unknown projects are then not created
+// on first use.
+func WithRequireAPIKey() Option { return func(s *Server) { s.requireKey = true } }
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) Option { return func(s *Server) { s.latency = d } }
+
+// Fault describes a failure injected into matching requests. Empty match
+// fields match everything.
+type Fault struct {
+	Method      string // HTTP method
+	Path        string // substring of the URL path
+	SQLContains string // substring of the request's SQL (case-insensitive)
+
+	Status     int           // response status; 0 only applies Latency
+	RetryAfter string        // Retry-After header value
+	Body       string        // response body; defaults to a JSON error
+	Latency    time.Duration // delay before responding or proceeding
+
+	// Times limits how many requests the fault affects; 0 means all.
+	Times int
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// SQL returns the statement of a SQL request, or "".
+func (r Request) SQL() string {
+	var body struct {
+		SQL string `json:"sql"`
+	}
+	_ = json.Unmarshal(r.Body, &body)
+	return body.SQL
+}
+
+type project struct {
+	id, dbID, holder, name, owner string
+
+	engine     Engine
+	keys       map[string]bool
+	writes     int64
+	commits    int
+	lastCommit string
+}
+
+// replay is a stored response for an idempotency key.
+type replay struct {
+	sum    [32]byte
+	status int
+	body   []byte
+}
+
+// NewServer starts a fake server. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		newEngine: defaultEngine,
+		projects:  map[string]*project{},
+		idem:      map[string]*replay{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /warlotSql/projects/resolve", s.handleResolve)
+	mux.HandleFunc("POST /warlotSql/projects/init", s.handleInit)
+	mux.HandleFunc("POST /auth/issue", s.handleIssue)
+	mux.HandleFunc("POST /warlotSql/projects/{id}/sql", s.handleSQL)
+	mux.HandleFunc("GET /warlotSql/projects/{id}/tables", s.handleTables)
+	mux.HandleFunc("GET /warlotSql/projects/{id}/tables/count", s.handleCount)
+	mux.HandleFunc("GET /warlotSql/projects/{id}/tables/{table}/rows", s.handleRows)
+	mux.HandleFunc("GET /warlotSql/projects/{id}/tables/{table}/schema", s.handleSchema)
+	mux.HandleFunc("GET /warlotSql/projects/{id}/status", s.handleStatus)
+	mux.HandleFunc("POST /warlotSql/projects/{id}/commit", s.handleCommit)
+
+	s.srv = httptest.NewServer(s.intercept(mux))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down and closes engines that have a Close method.
+func (s *Server) Close() {
+	s.srv.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.projects {
+		if c, ok := p.engine.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// Client returns a warlot.Client for the server with fast retry backoff.
+// Options are applied after the defaults.
+func (s *Server) Client(opts ...warlot.Option) *warlot.Client {
+	base := []warlot.Option{
+		warlot.WithBaseURL(s.URL),
+		warlot.WithRetries(3),
+		warlot.WithBackoff(time.Millisecond, 20*time.Millisecond),
+	}
+	return warlot.New(append(base, opts...)...)
+}
+
+// CreateProject registers a project without going through /init and
+// returns its ID.
+func (s *Server) CreateProject(holderID, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.createLocked(newID("proj"), holderID, name, "")
+	if err != nil {
+		return "", err
+	}
+	return p.id, nil
+}
+
+// Engine returns a project's engine for seeding data or making assertions,
+// creating the project if needed.
+func (s *Server) Engine(projectID string) (Engine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.projects[projectID]; p != nil {
+		return p.engine, nil
+	}
+	p, err := s.createLocked(projectID, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	return p.engine, nil
+}
+
+// Inject adds a fault. Faults are checked in the order they were added and
+// the first match applies.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far, including failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests clears the request log.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// intercept records requests and applies latency and faults before routing.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		req := Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: body}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		f := s.takeFaultLocked(req)
+		s.mu.Unlock()
+
+		delay := s.latency
+		if f != nil {
+			delay += f.Latency
+		}
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+		if f != nil && f.Status != 0 {
+			if f.RetryAfter != "" {
+				w.Header().Set("Retry-After", f.RetryAfter)
+			}
+			if f.Body != "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(f.Status)
+				io.WriteString(w, f.Body)
+				return
+			}
+			writeError(w, f.Status, "INJECTED", http.StatusText(f.Status))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) takeFaultLocked(req Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && !strings.EqualFold(f.Method, req.Method) {
+			continue
+		}
+		if f.Path != "" && !strings.Contains(req.Path, f.Path) {
+			continue
+		}
+		if f.SQLContains != "" && !strings.Contains(strings.ToLower(req.SQL()), strings.ToLower(f.SQLContains)) {
+			continue
+		}
+		out := *f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return &out
+	}
+	return nil
+}
+
+func (s *Server) createLocked(id, holder, name, owner string) (*project, error) {
+	eng, err := s.newEngine(id)
+	if err != nil {
+		return nil, err
+	}
+	p := &project{id: id, dbID: newID("db"), holder: holder, name: name, owner: owner, engine: eng, keys: map[string]bool{}}
+	s.projects[id] = p
+	return p, nil
+}
+
+func (s *Server) findByName(holder, name string) *project {
+	for _, p := range s.projects {
+		if p.holder == holder && p.name == name && name != "" {
+			return p
+		}
+	}
+	return nil
+}
+
+// project resolves the {id} path value and enforces API keys.
+func (s *Server) project(w http.ResponseWriter, r *http.Request) *project {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.projects[id]
+	if p == nil && !s.requireKey {
+		var err error
+		if p, err = s.createLocked(id, r.Header.Get("x-holder-id"), r.Header.Get("x-project-name"), ""); err != nil {
+			writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+			return nil
+		}
+	}
+	if p == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "project not found")
+		return nil
+	}
+	if s.requireKey && !p.keys[r.Header.Get("x-api-key")] {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid API key")
+		return nil
+	}
+	return p
+}
+
+// idempotent replays the stored response when the request carries an
+// x-idempotency-key already seen for the same scope. Reusing a key with a
+// different body is rejected with 422.
+func (s *Server) idempotent(w http.ResponseWriter, r *http.Request, scope string, fn http.HandlerFunc) {
+	key := r.Header.Get("x-idempotency-key")
+	if key == "" {
+		fn(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+
+	s.idemMu.Lock()
+	defer s.idemMu.Unlock()
+	k := scope + "\x00" + key
+	if rp := s.idem[k]; rp != nil {
+		if rp.sum != sum {
+			writeError(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_MISMATCH", "idempotency key reused with a different request")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(rp.status)
+		w.Write(rp.body)
+		return
+	}
+	rec := httptest.NewRecorder()
+	fn(rec, r)
+	if rec.Code < 500 {
+		s.idem[k] = &replay{sum: sum, status: rec.Code, body: rec.Body.Bytes()}
+		s.idemOrder = append(s.idemOrder, k)
+		if len(s.idemOrder) > maxReplays {
+			delete(s.idem, s.idemOrder[0])
+			s.idemOrder = s.idemOrder[1:]
+		}
+	}
+	for k, vs := range rec.Header() {
+		w.Header()[k] = vs
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+// ---- handlers ----
+
+func (s *Server) handleResolve(w http.ResponseWriter, r *http.Request) {
+	var req warlot.ResolveProjectRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	p := s.findByName(req.HolderID, req.ProjectName)
+	s.mu.Unlock()
+	if p == nil {
+		writeJSON(w, http.StatusOK, warlot.ResolveProjectResponse{Action: "init"})
+		return
+	}
+	writeJSON(w, http.StatusOK, warlot.ResolveProjectResponse{
+		ExistsMeta: true, ExistsChain: true, ProjectID: p.id, DBID: p.dbID, Action: "none",
+	})
+}
+
+func (s *Server) handleInit(w http.ResponseWriter, r *http.Request) {
+	s.idempotent(w, r, "init", func(w http.ResponseWriter, r *http.Request) {
+		var req warlot.InitProjectRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.HolderID == "" || req.ProjectName == "" || req.OwnerAddress == "" {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "holder_id, project_name and owner_address are required")
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.findByName(req.HolderID, req.ProjectName) != nil {
+			writeError(w, http.StatusConflict, "CONFLICT", "project already exists")
+			return
+		}
+		p, err := s.createLocked(newID("proj"), req.HolderID, req.ProjectName, req.OwnerAddress)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, warlot.InitProjectResponse{
+			ProjectID: p.id, DBID: p.dbID, WriterPassID: newID("pass"), BlobID: newID("blob"),
+			TxDigest: newID("tx"), CSVHashHex: hexID(), DigestHex: hexID(), SignatureHex: hexID(),
+		})
+	})
+}
+
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	var req warlot.IssueKeyRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.projects[req.ProjectID]
+	if p == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "project not found")
+		return
+	}
+	if (p.holder != "" && p.holder != req.ProjectHolder) || (p.name != "" && p.name != req.ProjectName) {
+		writeError(w, http.StatusForbidden, "FORBIDDEN", "holder or project name does not match")
+		return
+	}
+	key := "wk_" + hexID()
+	p.keys[key] = true
+	writeJSON(w, http.StatusOK, warlot.IssueKeyResponse{APIKey: key, URL: s.URL + "/warlotSql/projects/" + url.PathEscape(p.id)})
+}
+
+func (s *Server) handleSQL(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	s.idempotent(w, r, "sql:"+p.id, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			SQL    string `json:"sql"`
+			Params []any  `json:"params"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body")
+			return
+		}
+		if strings.TrimSpace(req.SQL) == "" {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "sql is required")
+			return
+		}
+		res, err := p.engine.Exec(r.Context(), req.SQL, req.Params)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "SQL_ERROR", err.Error())
+			return
+		}
+		if kw := firstKeyword(req.SQL); !(res.Query && (kw == "SELECT" || kw == "PRAGMA")) {
+			s.mu.Lock()
+			p.writes++
+			s.mu.Unlock()
+		}
+		if res.Query {
+			writeRows(w, `{"ok":true,`, res, "}")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "row_count": res.RowsAffected})
+	})
+}
+
+func (s *Server) handleTables(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	tables, err := p.engine.Tables(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+		return
+	}
+	if tables == nil {
+		tables = []string{}
+	}
+	writeJSON(w, http.StatusOK, warlot.ListTablesResponse{Tables: tables})
+}
+
+func (s *Server) handleCount(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	tables, err := p.engine.Tables(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, warlot.TableCountResponse{ProjectID: p.id, TableCount: len(tables)})
+}
+
+func (s *Server) handleRows(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	info, ok := schemaOr404(w, r, p)
+	if !ok {
+		return
+	}
+	limit, offset := DefaultBrowseLimit, 0
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && v > 0 {
+		offset = v
+	}
+	res, err := p.engine.Exec(r.Context(), `SELECT * FROM `+quoteIdent(info.Name)+` LIMIT ? OFFSET ?`, []any{limit, offset})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+		return
+	}
+	head, _ := json.Marshal(map[string]any{"limit": limit, "offset": offset, "table": info.Name})
+	writeRows(w, string(head[:len(head)-1])+",", res, "}")
+}
+
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	if info, ok := schemaOr404(w, r, p); ok {
+		writeJSON(w, http.StatusOK, info)
+	}
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	tables, err := p.engine.Tables(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var last any
+	if p.lastCommit != "" {
+		last = p.lastCommit
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok": true, "project_id": p.id, "db_id": p.dbID, "holder_id": p.holder, "project_name": p.name,
+		"tables": len(tables), "pending_writes": p.writes, "commits": p.commits, "last_commit_at": last,
+	})
+}
+
+func (s *Server) handleCommit(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	s.idempotent(w, r, "commit:"+p.id, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writes := p.writes
+		p.writes = 0
+		p.commits++
+		p.lastCommit = time.Now().UTC().Format(time.RFC3339)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"committed": true, "project_id": p.id, "tx_digest": hexID(), "blob_id": newID("blob"),
+			"writes": writes, "committed_at": p.lastCommit,
+		})
+	})
+}
+
+func schemaOr404(w http.ResponseWriter, r *http.Request, p *project) (*TableInfo, bool) {
+	info, err := p.engine.Schema(r.Context(), r.PathValue("table"))
+	if errors.Is(err, ErrNoTable) {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "no such table: "+r.PathValue("table"))
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ENGINE", err.Error())
+		return nil, false
+	}
+	return info, true
+}
+
+// ---- encoding ----
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]any{"ok": false, "error": msg, "code": code})
+}
+
+// writeRows writes prefix, the columns and rows members of a result set,
+// then suffix. Row objects have their keys in column order.
+func writeRows(w http.ResponseWriter, prefix string, res *Result, suffix string) {
+	var buf bytes.Buffer
+	buf.WriteString(prefix)
+	cols, _ := json.Marshal(res.Columns)
+	if res.Columns == nil {
+		cols = []byte("[]")
+	}
+	buf.WriteString(`"columns":`)
+	buf.Write(cols)
+	buf.WriteString(`,"rows":[`)
+	for i, r := range res.Rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for j, c := range res.Columns {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			k, _ := json.Marshal(c)
+			buf.Write(k)
+			buf.WriteByte(':')
+			buf.Write(jsonValue(r[j]))
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	buf.WriteString(suffix)
+	buf.WriteByte('\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func jsonValue(v any) []byte {
+	switch x := v.(type) {
+	case []byte:
+		v = string(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			v = nil
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte("null")
+	}
+	return b
+}
+
+func hexID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func newID(prefix string) string { return prefix + "_" + hexID()[:16] }
@@ -0,0 +1,254 @@
+package warlottest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/warlot"
+)
+
+func TestServer_ProjectLifecycle(t *testing.T) {
+	SkipWithoutSQLite(t)
+	srv := NewServer(WithRequireAPIKey())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cl := srv.Client()
+	res, err := cl.ResolveProject(ctx, warlot.ResolveProjectRequest{HolderID: "h1", ProjectName: "shop"})
+	if err != nil || res.Action != "init" {
+		t.Fatalf("resolve before init: %+v err=%v", res, err)
+	}
+	ini, err := cl.InitProject(ctx, warlot.InitProjectRequest{HolderID: "h1", ProjectName: "shop", OwnerAddress: "0xabc"})
+	if err != nil || ini.ProjectID == "" {
+		t.Fatalf("init: %+v err=%v", ini, err)
+	}
+	res, err = cl.ResolveProject(ctx, warlot.ResolveProjectRequest{HolderID: "h1", ProjectName: "shop"})
+	if err != nil || res.ProjectID != ini.ProjectID {
+		t.Fatalf("resolve after init: %+v err=%v", res, err)
+	}
+
+	proj := cl.Project(ini.ProjectID)
+	var apiErr *warlot.APIError
+	if _, err := proj.SQL(ctx, "SELECT 1", nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("sql without key: %v", err)
+	}
+
+	key, err := cl.IssueAPIKey(ctx, warlot.IssueKeyRequest{ProjectID: ini.ProjectID, ProjectHolder: "h1", ProjectName: "shop"})
+	if err != nil || key.APIKey == "" {
+		t.Fatalf("issue: %+v err=%v", key, err)
+	}
+	cl.APIKey = key.APIKey
+
+	if _, err := proj.SQL(ctx, `CREATE TABLE products (id INTEGER PRIMARY KEY, sku TEXT NOT NULL, price REAL)`, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	ins, err := proj.SQL(ctx, `INSERT INTO products (sku, price) VALUES (?, ?), (?, ?)`, []any{"A-1", 9.5, "B-2", 12})
+	if err != nil || ins.RowCount == nil || *ins.RowCount != 2 {
+		t.Fatalf("insert: %+v err=%v", ins, err)
+	}
+
+	sel, err := proj.SQL(ctx, `SELECT id, sku FROM products WHERE price > ? ORDER BY id DESC`, []any{5})
+	if err != nil || len(sel.Rows) != 2 {
+		t.Fatalf("select: %+v err=%v", sel, err)
+	}
+	if got := sel.Rows[0]["sku"]; got != "B-2" {
+		t.Fatalf("first sku = %v", got)
+	}
+	if names := sel.Cursor().ColumnNames(); len(names) != 2 || names[0] != "id" || names[1] != "sku" {
+		t.Fatalf("columns = %v", names)
+	}
+
+	lt, err := proj.Tables(ctx)
+	if err != nil || len(lt.Tables) != 1 || lt.Tables[0] != "products" {
+		t.Fatalf("tables: %+v err=%v", lt, err)
+	}
+	br, err := proj.Browse(ctx, "products", 1, 1)
+	if err != nil || len(br.Rows) != 1 || br.Rows[0]["sku"] != "B-2" {
+		t.Fatalf("browse: %+v err=%v", br, err)
+	}
+	if _, err := proj.Schema(ctx, "products"); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if _, err := proj.Schema(ctx, "missing"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("schema of missing table: %v", err)
+	}
+	cnt, err := proj.Count(ctx)
+	if err != nil || cnt.TableCount != 1 {
+		t.Fatalf("count: %+v err=%v", cnt, err)
+	}
+	if _, err := proj.Status(ctx); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if _, err := proj.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
+func TestServer_BatchRollsBack(t *testing.T) {
+	SkipWithoutSQLite(t)
+	srv := NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	proj := srv.Client().Project("p1")
+
+	if _, err := proj.SQL(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT UNIQUE)`, nil); err != nil {
+		t.Fatal(err)
+	}
+	err := proj.Tx(ctx, func(tx *warlot.Tx) error {
+		tx.Exec(`INSERT INTO t (name) VALUES (?)`, "a")
+		tx.Exec(`INSERT INTO t (name) VALUES (?)`, "a")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected unique violation")
+	}
+	rows, err := warlot.Query[struct {
+		N int `json:"n"`
+	}](ctx, proj, `SELECT COUNT(*) AS n FROM t`, nil)
+	if err != nil || len(rows) != 1 || rows[0].N != 0 {
+		t.Fatalf("count after rollback: %+v err=%v", rows, err)
+	}
+}
+
+func TestServer_FaultsAndIdempotency(t *testing.T) {
+	SkipWithoutSQLite(t)
+	srv := NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	proj := srv.Client().Project("p1")
+
+	if _, err := proj.SQL(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY)`, nil); err != nil {
+		t.Fatal(err)
+	}
+	srv.ResetRequests()
+	srv.Inject(Fault{Path: "/sql", SQLContains: "insert", Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 2})
+	if _, err := proj.SQL(ctx, `INSERT INTO t (id) VALUES (1)`, nil, warlot.WithIdempotencyKey("k1")); err != nil {
+		t.Fatalf("insert after faults: %v", err)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Fatalf("requests = %d, want 3", n)
+	}
+
+	// A replayed key must not apply the insert twice.
+	if _, err := proj.SQL(ctx, `INSERT INTO t (id) VALUES (1)`, nil, warlot.WithIdempotencyKey("k1")); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if _, err := proj.SQL(ctx, `INSERT INTO t (id) VALUES (2)`, nil, warlot.WithIdempotencyKey("k1")); err == nil {
+		t.Fatal("expected mismatch error for reused key")
+	}
+
+	srv.Inject(Fault{Status: http.StatusServiceUnavailable})
+	if _, err := proj.SQL(ctx, `SELECT 1`, nil); err == nil {
+		t.Fatal("expected persistent 503 to fail")
+	}
+	srv.ClearFaults()
+
+	srv.Inject(Fault{Latency: 200 * time.Millisecond, Times: 1})
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := proj.SQL(tctx, `SELECT 1`, nil); err == nil {
+		t.Fatal("expected timeout from injected latency")
+	}
+
+	// Stored replays are bounded, oldest first.
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	for i := 0; i < maxReplays+1; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		r.Header.Set("x-idempotency-key", fmt.Sprint("bulk-", i))
+		srv.idempotent(httptest.NewRecorder(), r, "test", ok)
+	}
+	if n := len(srv.idem); n != maxReplays {
+		t.Fatalf("stored replays = %d", n)
+	}
+	if srv.idem["test\x00bulk-0"] != nil || srv.idem["sql:p1\x00k1"] != nil {
+		t.Fatal("oldest replays not evicted")
+	}
+}
+
+func TestServer_Migrator(t *testing.T) {
+	SkipWithoutSQLite(t)
+	srv := NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	proj := srv.Client().Project("p1")
+
+	fsys := fstest.MapFS{
+		"migrations/0001_init.up.sql":   {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);`)},
+		"migrations/0001_init.down.sql": {Data: []byte(`DROP TABLE users;`)},
+		"migrations/0002_idx.up.sql":    {Data: []byte(`CREATE INDEX users_email ON users (email);`)},
+		"migrations/0002_idx.down.sql":  {Data: []byte(`DROP INDEX users_email;`)},
+	}
+	var m warlot.Migrator
+	applied, err := m.Up(ctx, proj, fsys, "migrations")
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("up: %v err=%v", applied, err)
+	}
+	st, err := m.Status(ctx, proj, fsys, "migrations")
+	if err != nil || len(st.Pending) != 0 {
+		t.Fatalf("status: %+v err=%v", st, err)
+	}
+	if _, err := m.Down(ctx, proj, fsys, "migrations"); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if applied, err = m.Up(ctx, proj, fsys, "migrations"); err != nil || len(applied) != 1 {
+		t.Fatalf("re-up: %v err=%v", applied, err)
+	}
+}
+
+func TestServer_KeysetPagerUnderWrites(t *testing.T) {
+	SkipWithoutSQLite(t)
+	srv := NewServer()
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proj := srv.Client().Project("p")
+
+	if _, err := proj.SQL(ctx, `CREATE TABLE ev (id INTEGER PRIMARY KEY, v TEXT)`, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 25; i++ {
+		if _, err := proj.SQL(ctx, `INSERT INTO ev (id, v) VALUES (?, ?)`, []any{i * 10, "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pg := &warlot.KeysetPager{Project: proj, Table: "ev", Limit: 10}
+	first, err := pg.Next(ctx)
+	if err != nil || len(first) != 10 {
+		t.Fatalf("first page: %d err=%v", len(first), err)
+	}
+	// Rows removed before the cursor and added after it must not shift
+	// the next page, as they would with limit/offset.
+	if _, err := proj.SQL(ctx, `DELETE FROM ev WHERE id <= 30; INSERT INTO ev (id, v) VALUES (255, 'new')`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resume in a fresh pager, as after a restart.
+	resumed := &warlot.KeysetPager{Project: proj, Table: "ev", Limit: 10, Cursor: pg.Cursor}
+	var ids []int64
+	for {
+		rows, err := resumed.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows == nil {
+			break
+		}
+		for _, r := range rows {
+			n, _ := r["id"].(json.Number).Int64()
+			ids = append(ids, n)
+		}
+	}
+	if len(ids) != 16 || ids[0] != 110 || ids[14] != 250 || ids[15] != 255 {
+		t.Fatalf("resumed ids = %v", ids)
+	}
+}
@@ -0,0 +1,196 @@
+package warlottest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/steven3002/warlot-golang-sdk/warlot-go/internal/sqlparse"
+)
+
+// NewSQLEngine adapts a database/sql handle, typically an SQLite database
+// opened with a driver of your choice, to Engine. Scripts run statement by
+// statement on one connection so BEGIN/COMMIT behave as on the real API.
+// Tables and Schema read sqlite_master and PRAGMA table_info, so the
+// database must speak SQLite's dialect. TEXT read back as []byte is
+// returned as string, and booleans and timestamps a driver derived from
+// the declared column type are turned back into 0/1 and text.
+func NewSQLEngine(db *sql.DB) Engine { return &sqlEngine{db: db} }
+
+type sqlEngine struct{ db *sql.DB }
+
+func (e *sqlEngine) Exec(ctx context.Context, query string, args []any) (*Result, error) {
+	stmts, err := sqlparse.Split(query)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	inTx := false
+	res := &Result{}
+	for _, st := range stmts {
+		n, err := sqlparse.CountParams(st)
+		if err != nil {
+			return nil, err
+		}
+		if n > len(args) {
+			err = errors.New("not enough parameters for statement")
+		} else {
+			res, err = e.run(ctx, conn, st, args[:n])
+			args = args[n:]
+		}
+		if err != nil {
+			if inTx {
+				_, _ = conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+			}
+			return nil, err
+		}
+		switch firstKeyword(st) {
+		case "BEGIN":
+			inTx = true
+		case "COMMIT", "END", "ROLLBACK":
+			inTx = false
+		}
+	}
+	if inTx {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return nil, errors.New("transaction left open: scripts must end with COMMIT or ROLLBACK")
+	}
+	return res, nil
+}
+
+func (e *sqlEngine) run(ctx context.Context, conn *sql.Conn, stmt string, args []any) (*Result, error) {
+	if !returnsRows(stmt) {
+		r, err := conn.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := r.RowsAffected()
+		return &Result{RowsAffected: n}, nil
+	}
+	rows, err := conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Query: true, Columns: cols}
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range vals {
+			vals[i] = sqliteValue(v)
+		}
+		res.Rows = append(res.Rows, vals)
+	}
+	return res, rows.Err()
+}
+
+func (e *sqlEngine) Tables(ctx context.Context) ([]string, error) {
+	rows, err := e.db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+func (e *sqlEngine) Schema(ctx context.Context, table string) (*TableInfo, error) {
+	info := &TableInfo{}
+	err := e.db.QueryRowContext(ctx, `SELECT name, sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).
+		Scan(&info.Name, &info.SQL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoTable
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := e.db.QueryContext(ctx, `PRAGMA table_info(`+quoteIdent(info.Name)+`)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ColumnInfo
+		if err := rows.Scan(&c.CID, &c.Name, &c.Type, &c.NotNull, &c.Default, &c.PK); err != nil {
+			return nil, err
+		}
+		c.Default = sqliteValue(c.Default)
+		info.Columns = append(info.Columns, c)
+	}
+	return info, rows.Err()
+}
+
+// sqliteValue undoes conversions some drivers apply based on the declared
+// column type, returning values as SQLite stores them: TEXT as string,
+// booleans as 0 or 1, and timestamps as text in SQLite's own layout.
+func sqliteValue(v any) any {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	case bool:
+		if x {
+			return int64(1)
+		}
+		return int64(0)
+	case time.Time:
+		if x.Nanosecond() != 0 {
+			return x.Format("2006-01-02 15:04:05.999999999")
+		}
+		return x.Format("2006-01-02 15:04:05")
+	}
+	return v
+}
+
+// returnsRows reports whether a statement yields a result set.
+func returnsRows(stmt string) bool {
+	switch firstKeyword(stmt) {
+	case "SELECT", "PRAGMA", "WITH", "VALUES", "EXPLAIN":
+		return true
+	}
+	toks, err := sqlparse.Tokenize(stmt)
+	if err != nil {
+		return false
+	}
+	for _, t := range toks {
+		if t.Kind == sqlparse.TokIdent && strings.EqualFold(t.Text, "RETURNING") {
+			return true
+		}
+	}
+	return false
+}
+
+func firstKeyword(stmt string) string {
+	toks, err := sqlparse.Tokenize(stmt)
+	if err != nil || toks[0].Kind != sqlparse.TokIdent {
+		return ""
+	}
+	return strings.ToUpper(toks[0].Text)
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
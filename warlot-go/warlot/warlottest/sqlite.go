@@ -0,0 +1,59 @@
+package warlottest
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// SQLiteDriver is the database/sql driver name NewSQLiteEngine opens.
+// warlottest registers no driver itself, so the SDK carries no cgo
+// dependency; import one in the test binary, for example
+//
+//	import _ "github.com/mattn/go-sqlite3"
+const SQLiteDriver = "sqlite3"
+
+// ErrNoSQLite is returned by NewSQLiteEngine when no driver named
+// SQLiteDriver is registered.
+var ErrNoSQLite = errors.New(`warlottest: no "sqlite3" database/sql driver is registered; import one such as github.com/mattn/go-sqlite3, or use WithEngine`)
+
+// SQLiteAvailable reports whether NewSQLiteEngine can open a database.
+func SQLiteAvailable() bool { return slices.Contains(sql.Drivers(), SQLiteDriver) }
+
+// SkipWithoutSQLite skips t when SQLiteAvailable is false, as in builds
+// with CGO_ENABLED=0.
+func SkipWithoutSQLite(t testing.TB) {
+	t.Helper()
+	if !SQLiteAvailable() {
+		t.Skip(ErrNoSQLite)
+	}
+}
+
+// NewSQLiteEngine returns an engine backed by a private in-memory SQLite
+// database opened through the registered SQLiteDriver. It is the default
+// engine of NewServer. Close releases the database.
+func NewSQLiteEngine() (Engine, error) {
+	if !SQLiteAvailable() {
+		return nil, ErrNoSQLite
+	}
+	db, err := sql.Open(SQLiteDriver, ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Every connection to :memory: is a separate database; keep one.
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteEngine{sqlEngine{db: db}}, nil
+}
+
+type sqliteEngine struct{ sqlEngine }
+
+func (e *sqliteEngine) Close() error { return e.db.Close() }
+
+func defaultEngine(string) (Engine, error) { return NewSQLiteEngine() }